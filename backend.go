@@ -0,0 +1,49 @@
+package hrd
+
+import (
+	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
+
+// backend executes the datastore operations issued by a Store.
+// It operates on the package's internal representation of kinds,
+// keys and queries; a Datastore is the public seam below it.
+type backend interface {
+
+	// Get loads the entities for the passed-in keys into dst.
+	Get(kind *types.Kind, keys []*types.Key, dst interface{}, opts *types.Opts, multi bool) ([]*types.Key, error)
+
+	// Put saves the passed-in entities and returns their keys.
+	Put(kind *types.Kind, src interface{}, opts *types.Opts) ([]*types.Key, error)
+
+	// Delete deletes the entities for the passed-in keys.
+	Delete(kind *types.Kind, keys ...*types.Key) error
+
+	// Count returns the number of results for the passed-in query on a kind.
+	Count(kind *types.Kind, qry *types.Query) (int, error)
+
+	// Query executes the passed-in query on a kind and loads its results
+	// into dsts. It returns the query's iterator for continuing from its cursor.
+	Query(kind *types.Kind, qry *types.Query, dsts interface{}, multi bool) (types.Iterator, []*types.Key, error)
+
+	// AllocateIDs allocates n numeric IDs for a kind and parent key,
+	// which may be nil, and returns them as complete keys.
+	AllocateIDs(kind *types.Kind, parent *types.Key, n int) ([]*types.Key, error)
+
+	// AllocateIDRange allocates the numeric IDs from start to end, inclusive,
+	// for a kind and parent key, which may be nil.
+	AllocateIDRange(kind *types.Kind, parent *types.Key, start, end int64) error
+
+	// Transact runs the passed-in function in a transaction.
+	Transact(ctx ae.Context, crossGroup bool, f func(ae.Context) error) error
+}
+
+// defaultBackend returns the default backend.
+// It uses the App Engine datastore.
+func defaultBackend() backend {
+	return internal.NewBackend(internal.AppEngine{})
+}
+
+var _ backend = (*internal.Backend)(nil)
@@ -0,0 +1,37 @@
+// Package cache provides global caches for datastore entities,
+// which are shared by all requests of an application.
+package cache
+
+import (
+	"time"
+
+	ae "appengine"
+)
+
+// Cache is a global cache of serialized entities.
+//
+// To prevent stale entries, a key is locked before its entity is written:
+// a locked key has no value and can not be set until its lock expires.
+type Cache interface {
+
+	// Get returns the values of the passed-in keys.
+	// Keys without a value, including locked keys, are omitted.
+	Get(ctx ae.Context, keys []string) (map[string][]byte, error)
+
+	// Set stores the passed-in values, except for keys
+	// which already have a value or are locked.
+	// An expiration of zero means no expiration.
+	Set(ctx ae.Context, values map[string][]byte, expiration time.Duration) error
+
+	// Replace stores the passed-in values in place of
+	// the current values and locks of their keys.
+	// An expiration of zero means no expiration.
+	Replace(ctx ae.Context, values map[string][]byte, expiration time.Duration) error
+
+	// Delete removes the values and locks of the passed-in keys.
+	Delete(ctx ae.Context, keys []string) error
+
+	// Lock removes the values of the passed-in keys and prevents
+	// them from being set until the lock expires.
+	Lock(ctx ae.Context, keys []string, expiration time.Duration) error
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	ae "appengine"
+)
+
+// LRU is a Cache that keeps values in the memory of the process.
+// If it is full, the least recently used value is evicted.
+//
+// Since each instance of an application has its own memory, an LRU does not
+// see the writes of other instances: it should only be used for entities
+// that are read-only or for applications that run on a single instance.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+// lruItem is a value or lock of an LRU.
+type lruItem struct {
+	key     string
+	value   []byte
+	locked  bool
+	expires time.Time
+}
+
+// NewLRU returns a Cache that keeps up to size values in memory.
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of values and locks in the cache.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Get returns the values of the passed-in keys.
+func (c *LRU) Get(_ ae.Context, keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if item := c.get(key); item != nil && !item.locked {
+			c.order.MoveToFront(c.items[key])
+			ret[key] = append([]byte(nil), item.value...)
+		}
+	}
+	return ret, nil
+}
+
+// Set stores the passed-in values, unless a key has a value or is locked.
+func (c *LRU) Set(_ ae.Context, values map[string][]byte, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range values {
+		if c.get(key) == nil {
+			c.put(&lruItem{key: key, value: append([]byte(nil), value...), expires: expiresAt(expiration)})
+		}
+	}
+	return nil
+}
+
+// Replace stores the passed-in values in place of
+// the current values and locks of their keys.
+func (c *LRU) Replace(_ ae.Context, values map[string][]byte, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range values {
+		c.remove(key)
+		c.put(&lruItem{key: key, value: append([]byte(nil), value...), expires: expiresAt(expiration)})
+	}
+	return nil
+}
+
+// Delete removes the values and locks of the passed-in keys.
+func (c *LRU) Delete(_ ae.Context, keys []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.remove(key)
+	}
+	return nil
+}
+
+// Lock replaces the values of the passed-in keys with locks.
+func (c *LRU) Lock(_ ae.Context, keys []string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.remove(key)
+		c.put(&lruItem{key: key, locked: true, expires: expiresAt(expiration)})
+	}
+	return nil
+}
+
+// get returns the unexpired item of a key, if any.
+func (c *LRU) get(key string) *lruItem {
+	elem, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+
+	item := elem.Value.(*lruItem)
+	if !item.expires.IsZero() && !time.Now().Before(item.expires) {
+		c.remove(key)
+		return nil
+	}
+	return item
+}
+
+func (c *LRU) put(item *lruItem) {
+	c.items[item.key] = c.order.PushFront(item)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back().Value.(*lruItem).key)
+	}
+}
+
+func (c *LRU) remove(key string) {
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+func expiresAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(expiration)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/101loops/bdd"
+)
+
+var _ = Describe("LRU", func() {
+
+	cacheTests(func() Cache {
+		return NewLRU(10)
+	})
+
+	It("should evict the least recently used value", func() {
+		lru := NewLRU(2)
+		lru.Set(ctx, map[string][]byte{"a": []byte("1")}, 0)
+		lru.Set(ctx, map[string][]byte{"b": []byte("2")}, 0)
+		lru.Get(ctx, []string{"a"})
+		lru.Set(ctx, map[string][]byte{"c": []byte("3")}, 0)
+
+		values, _ := lru.Get(ctx, []string{"a", "b", "c"})
+		Check(values, HasLen, 2)
+		Check(values["b"], IsNil)
+		Check(lru.Len(), EqualsNum, 2)
+	})
+
+	It("should expire values and locks", func() {
+		lru := NewLRU(10)
+		lru.Set(ctx, map[string][]byte{"a": []byte("1")}, time.Nanosecond)
+		lru.Lock(ctx, []string{"b"}, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		values, _ := lru.Get(ctx, []string{"a"})
+		Check(values, HasLen, 0)
+
+		lru.Set(ctx, map[string][]byte{"b": []byte("2")}, 0)
+		values, _ = lru.Get(ctx, []string{"b"})
+		Check(values["b"], Equals, []byte("2"))
+	})
+
+	It("should not share values with the caller", func() {
+		lru := NewLRU(10)
+		value := []byte("1")
+		lru.Set(ctx, map[string][]byte{"a": value}, 0)
+		value[0] = '2'
+
+		values, _ := lru.Get(ctx, []string{"a"})
+		Check(values["a"], Equals, []byte("1"))
+	})
+})
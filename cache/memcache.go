@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"time"
+
+	ae "appengine"
+	"appengine/memcache"
+)
+
+const (
+	// lockFlag marks a memcache item as lock.
+	lockFlag uint32 = 1 << 31
+)
+
+// Memcache is a Cache that uses App Engine's memcache.
+// Its locking follows the approach of nds (github.com/qedus/nds).
+type Memcache struct{}
+
+// NewMemcache returns a Cache that uses App Engine's memcache.
+func NewMemcache() Memcache {
+	return Memcache{}
+}
+
+// Get returns the values of the passed-in keys.
+func (Memcache) Get(ctx ae.Context, keys []string) (map[string][]byte, error) {
+	items, err := memcache.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]byte, len(items))
+	for key, item := range items {
+		if item.Flags&lockFlag == 0 {
+			ret[key] = item.Value
+		}
+	}
+	return ret, nil
+}
+
+// Set stores the passed-in values, unless a key has a value or is locked.
+func (Memcache) Set(ctx ae.Context, values map[string][]byte, expiration time.Duration) error {
+	items := make([]*memcache.Item, 0, len(values))
+	for key, value := range values {
+		items = append(items, &memcache.Item{Key: key, Value: value, Expiration: expiration})
+	}
+	return ignoreErrors(memcache.AddMulti(ctx, items), memcache.ErrNotStored)
+}
+
+// Replace stores the passed-in values in place of
+// the current values and locks of their keys.
+func (Memcache) Replace(ctx ae.Context, values map[string][]byte, expiration time.Duration) error {
+	items := make([]*memcache.Item, 0, len(values))
+	for key, value := range values {
+		items = append(items, &memcache.Item{Key: key, Value: value, Expiration: expiration})
+	}
+	return memcache.SetMulti(ctx, items)
+}
+
+// Delete removes the values and locks of the passed-in keys.
+func (Memcache) Delete(ctx ae.Context, keys []string) error {
+	return ignoreErrors(memcache.DeleteMulti(ctx, keys), memcache.ErrCacheMiss)
+}
+
+// Lock replaces the values of the passed-in keys with locks.
+func (Memcache) Lock(ctx ae.Context, keys []string, expiration time.Duration) error {
+	items := make([]*memcache.Item, len(keys))
+	for i, key := range keys {
+		items[i] = &memcache.Item{Key: key, Value: []byte{}, Flags: lockFlag, Expiration: expiration}
+	}
+	return memcache.SetMulti(ctx, items)
+}
+
+// ignoreErrors returns nil if all errors of a MultiError are the passed-in error.
+func ignoreErrors(err error, ignored error) error {
+	mErr, ok := err.(ae.MultiError)
+	if !ok {
+		return err
+	}
+	for _, e := range mErr {
+		if e != nil && e != ignored {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package cache
+
+import (
+	. "github.com/101loops/bdd"
+
+	"appengine/memcache"
+)
+
+var _ = Describe("Memcache", func() {
+
+	BeforeEach(func() {
+		memcache.Flush(ctx)
+	})
+
+	cacheTests(func() Cache {
+		return NewMemcache()
+	})
+})
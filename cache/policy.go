@@ -0,0 +1,47 @@
+package cache
+
+import "time"
+
+// Mode defines how cached entities of a kind are kept up to date.
+type Mode int
+
+const (
+	// Invalidate locks the cached entities when they are written,
+	// so they are loaded from the datastore and cached again afterwards.
+	// It is the default mode.
+	Invalidate Mode = iota
+
+	// WriteThrough caches the entities when they are written, except inside
+	// a transaction, where they are locked as in Invalidate mode.
+	WriteThrough
+
+	// ReadOnly caches the entities when they are loaded, but leaves the
+	// cache untouched when they are written. A cached entity may be stale
+	// until it expires, so it is meant for entities that rarely change.
+	ReadOnly
+
+	// Never prevents caching the entities, locally and globally.
+	Never
+)
+
+// Policy defines how the entities of a kind are cached.
+// Its zero value is the default policy.
+type Policy struct {
+
+	// Mode defines how cached entities are kept up to date.
+	Mode Mode
+
+	// TTL is how long an entity stays in the global cache,
+	// zero means it stays until it is evicted.
+	TTL time.Duration
+}
+
+// Enabled returns whether the entities are cached at all.
+func (p Policy) Enabled() bool {
+	return p.Mode != Never
+}
+
+// LocksOnWrite returns whether the cached entities are locked while written.
+func (p Policy) LocksOnWrite() bool {
+	return p.Mode == Invalidate || p.Mode == WriteThrough
+}
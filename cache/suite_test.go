@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/memctx"
+
+	ae "appengine"
+)
+
+var (
+	ctx ae.Context
+)
+
+func TestSuite(t *testing.T) {
+	ctx = memctx.New()
+
+	RunSpecs(t, "HRD Cache Suite")
+}
+
+// cacheTests checks the behaviour that all caches must share.
+func cacheTests(newCache func() Cache) {
+
+	var (
+		c Cache
+	)
+
+	BeforeEach(func() {
+		c = newCache()
+		err := c.Set(ctx, map[string][]byte{"a": []byte("1"), "b": []byte("2")}, 0)
+		Check(err, IsNil)
+	})
+
+	It("should get values", func() {
+		values, err := c.Get(ctx, []string{"a", "b", "c"})
+		Check(err, IsNil)
+		Check(values, Equals, map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	})
+
+	It("should not overwrite a value", func() {
+		c.Set(ctx, map[string][]byte{"a": []byte("3")}, 0)
+
+		values, _ := c.Get(ctx, []string{"a"})
+		Check(values["a"], Equals, []byte("1"))
+	})
+
+	It("should replace values and locks", func() {
+		c.Lock(ctx, []string{"b"}, time.Minute)
+
+		err := c.Replace(ctx, map[string][]byte{"a": []byte("3"), "b": []byte("4")}, 0)
+		Check(err, IsNil)
+
+		values, _ := c.Get(ctx, []string{"a", "b"})
+		Check(values, Equals, map[string][]byte{"a": []byte("3"), "b": []byte("4")})
+	})
+
+	It("should delete values", func() {
+		err := c.Delete(ctx, []string{"a", "c"})
+		Check(err, IsNil)
+
+		values, _ := c.Get(ctx, []string{"a", "b"})
+		Check(values, HasLen, 1)
+	})
+
+	It("should lock keys", func() {
+		err := c.Lock(ctx, []string{"a", "c"}, time.Minute)
+		Check(err, IsNil)
+
+		c.Set(ctx, map[string][]byte{"a": []byte("3"), "c": []byte("3")}, 0)
+		values, _ := c.Get(ctx, []string{"a", "c"})
+		Check(values, HasLen, 0)
+
+		c.Delete(ctx, []string{"a"})
+		c.Set(ctx, map[string][]byte{"a": []byte("3")}, 0)
+		values, _ = c.Get(ctx, []string{"a"})
+		Check(values["a"], Equals, []byte("3"))
+	})
+}
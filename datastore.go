@@ -0,0 +1,144 @@
+package hrd
+
+import (
+	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+// Datastore performs the raw operations of a datastore for a Store,
+// which uses the App Engine datastore by default. Caching, encoding
+// and validation of entities are left to the Store.
+type Datastore interface {
+
+	// Get loads the entities for the passed-in keys.
+	Get(ctx ae.Context, keys []*ds.Key, dst []ds.PropertyLoadSaver) error
+
+	// Put saves the passed-in entities and returns their keys.
+	Put(ctx ae.Context, keys []*ds.Key, src []ds.PropertyLoadSaver) ([]*ds.Key, error)
+
+	// Delete deletes the entities for the passed-in keys.
+	Delete(ctx ae.Context, keys []*ds.Key) error
+
+	// Count returns the number of results for the passed-in query.
+	Count(ctx ae.Context, qry *DatastoreQuery) (int, error)
+
+	// Run executes the passed-in query and returns an iterator.
+	Run(ctx ae.Context, qry *DatastoreQuery) DatastoreIterator
+
+	// AllocateIDs allocates n numeric IDs for the passed-in kind and parent
+	// key, which may be nil. The range is inclusive at the low end and
+	// exclusive at the high end.
+	AllocateIDs(ctx ae.Context, kind string, parent *ds.Key, n int) (low, high int64, err error)
+
+	// AllocateIDRange allocates the numeric IDs from start to end, inclusive,
+	// for the passed-in kind and parent key, which may be nil.
+	// It returns an error if any of them may already be in use.
+	AllocateIDRange(ctx ae.Context, kind string, parent *ds.Key, start, end int64) error
+
+	// RunInTransaction runs the passed-in function in a transaction.
+	RunInTransaction(ctx ae.Context, f func(ae.Context) error, crossGroup bool) error
+}
+
+// DatastoreQuery is a query run by a Datastore.
+type DatastoreQuery struct {
+	Kind      string
+	Namespace string
+
+	// Ancestor restricts the results to its descendants, it may be nil.
+	Ancestor *ds.Key
+
+	Filters []DatastoreFilter
+	Orders  []DatastoreOrder
+
+	// Projection are the fields of a projection query, if any.
+	Projection []string
+
+	KeysOnly bool
+	Distinct bool
+	Eventual bool
+
+	// Limit is the maximum number of results, it is negative for no limit.
+	Limit  int
+	Offset int
+
+	// Start and End are the cursors the results start and end at, if any.
+	Start string
+	End   string
+}
+
+// DatastoreFilter is a conditional filter on the results of a DatastoreQuery,
+// e.g. a Filter of "Age >" with a Value of 18.
+type DatastoreFilter struct {
+	Filter string
+	Value  interface{}
+}
+
+// DatastoreOrder is a sort order on the results of a DatastoreQuery.
+type DatastoreOrder struct {
+	FieldName  string
+	Descending bool
+}
+
+// DatastoreIterator is the result of running a DatastoreQuery.
+type DatastoreIterator interface {
+
+	// Cursor returns a cursor for the iterator's current location.
+	Cursor() (string, error)
+
+	// Next returns the key of the next result, or datastore.Done.
+	// If the query is not keys-only, it also loads the entity stored
+	// for that key into the PropertyLoadSaver returned by pipeFunc.
+	Next(pipeFunc func(ae.Context) ds.PropertyLoadSaver) (*ds.Key, error)
+}
+
+// WithDatastore makes the store execute its datastore operations
+// with the passed-in Datastore instead of the App Engine datastore,
+// e.g. with the in-memory one of package hrdtest.
+func WithDatastore(d Datastore) StoreOption {
+	return withBackend(internal.NewBackend(&datastore{d}))
+}
+
+// datastore adapts a Datastore to the one of an internal.Backend.
+type datastore struct {
+	Datastore
+}
+
+func (d *datastore) Count(ctx ae.Context, qry *types.Query) (int, error) {
+	return d.Datastore.Count(ctx, exportQuery(ctx, qry))
+}
+
+func (d *datastore) Run(ctx ae.Context, qry *types.Query) types.Iterator {
+	return d.Datastore.Run(ctx, exportQuery(ctx, qry))
+}
+
+func exportQuery(ctx ae.Context, qry *types.Query) *DatastoreQuery {
+	ret := &DatastoreQuery{
+		Kind:      qry.Kind(),
+		Namespace: qry.Namespace,
+		Filters:   make([]DatastoreFilter, len(qry.Filter)),
+		Orders:    make([]DatastoreOrder, len(qry.Order)),
+		KeysOnly:  qry.TypeOf == types.KeysOnlyQuery,
+		Distinct:  qry.Distinct,
+		Eventual:  qry.Eventual,
+		Limit:     qry.Limit,
+		Offset:    qry.Offset,
+		Start:     qry.Start,
+		End:       qry.End,
+	}
+	if qry.Ancestor != nil {
+		ret.Ancestor = qry.Ancestor.ToDSKey(ctx)
+	}
+	for i, f := range qry.Filter {
+		ret.Filters[i] = DatastoreFilter{f.Filter, f.Value}
+	}
+	for i, o := range qry.Order {
+		ret.Orders[i] = DatastoreOrder{o.FieldName, o.Descending}
+	}
+	if qry.TypeOf == types.ProjectQuery {
+		ret.Projection = append([]string(nil), qry.Projection...)
+	}
+	return ret
+}
@@ -1,6 +1,10 @@
 package hrd
 
-import ae "appengine"
+import (
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
 
 // Deleter can delete entities from a kind.
 type Deleter struct {
@@ -45,14 +49,36 @@ func (d *Deleter) TextIDs(ids ...string) error {
 
 // Entity deletes the provided entity.
 func (d *Deleter) Entity(src interface{}) error {
-	return dsDelete(d.Kind(), src, false)
+	kind := d.kindOf(src)
+	key, err := types.GetEntityKey(kind, src)
+	if err != nil {
+		return err
+	}
+	return d.delete(kind, key)
 }
 
 // Entities deletes the provided entities.
 func (d *Deleter) Entities(srcs interface{}) error {
-	return dsDelete(d.Kind(), srcs, true)
+	kind := d.kindOf(srcs)
+	keys, err := types.GetEntitiesKeys(kind, srcs)
+	if err != nil {
+		return err
+	}
+	return d.delete(kind, keys...)
 }
 
 func (d *Deleter) deleteKeys(keys ...*Key) error {
-	return dsDeleteKeys(d.Kind(), toInternalKeys(keys)...)
+	return d.delete(d.Kind(), toInternalKeys(keys)...)
+}
+
+func (d *Deleter) delete(kind *types.Kind, keys ...*types.Key) (err error) {
+	span := d.kind.startSpan(d.ctx, "hrd.delete")
+	defer func() {
+		finishSpan(span, importKeys(keys), err)
+	}()
+
+	if err = d.validate(keys); err != nil {
+		return err
+	}
+	return d.backend().Delete(kind, keys...)
 }
@@ -2,28 +2,24 @@ package hrd
 
 import (
 	. "github.com/101loops/bdd"
-	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/entity/fixture"
 	"github.com/101loops/hrd/internal/types"
 )
 
 var _ = Describe("Deleter", func() {
 
 	BeforeEach(func() {
-		dsDelete = func(_ *types.Kind, _ interface{}, _ bool) error {
-			panic("unexpected call")
-		}
-		dsDeleteKeys = func(_ *types.Kind, _ ...*types.Key) error {
+		myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
 			panic("unexpected call")
 		}
 	})
 
 	AfterEach(func() {
-		dsDelete = internal.Delete
-		dsDeleteKeys = internal.DeleteKeys
+		myBackend.delete = nil
 	})
 
 	It("should delete an entity by key", func() {
-		dsDeleteKeys = func(kind *types.Kind, keys ...*types.Key) error {
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
 			Check(keys, Equals, toInternalKeys(myKind.NewNumKeys(42)))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
@@ -35,7 +31,7 @@ var _ = Describe("Deleter", func() {
 	It("should delete multiple entities by key", func() {
 		hrdKeys := []*Key{myKind.NewNumKey(1), myKind.NewNumKey(2)}
 
-		dsDeleteKeys = func(kind *types.Kind, keys ...*types.Key) error {
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
 			Check(keys, Equals, toInternalKeys(hrdKeys))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
@@ -45,7 +41,7 @@ var _ = Describe("Deleter", func() {
 	})
 
 	It("should delete an entity by numeric id", func() {
-		dsDeleteKeys = func(kind *types.Kind, keys ...*types.Key) error {
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
 			Check(keys, Equals, toInternalKeys(myKind.NewNumKeys(42)))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
@@ -55,7 +51,7 @@ var _ = Describe("Deleter", func() {
 	})
 
 	It("should delete multiple entities by numeric id", func() {
-		dsDeleteKeys = func(kind *types.Kind, keys ...*types.Key) error {
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
 			Check(keys, Equals, toInternalKeys(myKind.NewNumKeys(1, 2)))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
@@ -65,7 +61,7 @@ var _ = Describe("Deleter", func() {
 	})
 
 	It("should delete an entity by text id", func() {
-		dsDeleteKeys = func(kind *types.Kind, keys ...*types.Key) error {
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
 			Check(keys, Equals, toInternalKeys(myKind.NewTextKeys("a")))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
@@ -75,7 +71,7 @@ var _ = Describe("Deleter", func() {
 	})
 
 	It("should delete multiple entities by text id", func() {
-		dsDeleteKeys = func(kind *types.Kind, keys ...*types.Key) error {
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
 			Check(keys, Equals, toInternalKeys(myKind.NewTextKeys("a", "z")))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
@@ -85,28 +81,36 @@ var _ = Describe("Deleter", func() {
 	})
 
 	It("should delete an entity", func() {
-		entity := &MyModel{}
+		entity := &fixture.EntityWithNumID{}
+		entity.SetID(42)
 
-		dsDelete = func(kind *types.Kind, src interface{}, multi bool) error {
-			Check(multi, IsFalse)
-			Check(src, Equals, entity)
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
+			Check(keys, Equals, toInternalKeys(myKind.NewNumKeys(42)))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
 		}
 
-		myKind.Delete(ctx).Entity(entity)
+		err := myKind.Delete(ctx).Entity(entity)
+		Check(err, IsNil)
 	})
 
 	It("should delete multiple entities", func() {
-		entities := []*MyModel{&MyModel{}, &MyModel{}}
+		entities := []*fixture.EntityWithNumID{&fixture.EntityWithNumID{}, &fixture.EntityWithNumID{}}
+		entities[0].SetID(1)
+		entities[1].SetID(2)
 
-		dsDelete = func(kind *types.Kind, srcs interface{}, multi bool) error {
-			Check(multi, IsTrue)
-			Check(srcs, Equals, entities)
+		myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
+			Check(keys, Equals, toInternalKeys(myKind.NewNumKeys(1, 2)))
 			Check(kind.Name, Equals, "my-kind")
 			return nil
 		}
 
-		myKind.Delete(ctx).Entities(entities)
+		err := myKind.Delete(ctx).Entities(entities)
+		Check(err, IsNil)
+	})
+
+	It("should not delete an entity without id", func() {
+		err := myKind.Delete(ctx).Entity(&MyModel{})
+		Check(err, ErrorContains, "does not provide ID()")
 	})
 })
@@ -49,3 +49,20 @@ func (mdl *EntityWithParentTextID) SetParent(kind string, id string) {
 	mdl.parentKind = kind
 	mdl.parentID = id
 }
+
+// EntityWithParentKey is an entity with a parent key.
+type EntityWithParentKey struct {
+	entity.NumID
+
+	parent entity.Key
+}
+
+// ParentKey returns the entity's parent key.
+func (mdl *EntityWithParentKey) ParentKey() entity.Key {
+	return mdl.parent
+}
+
+// SetParentKey applies the entity's parent key.
+func (mdl *EntityWithParentKey) SetParentKey(key entity.Key) {
+	mdl.parent = key
+}
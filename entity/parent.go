@@ -19,3 +19,37 @@ type ParentNumIdentifier interface {
 	// SetParent sets the parent identifier.
 	SetParent(kind string, id int64)
 }
+
+// Key is the key of an entity, as implemented by *hrd.Key.
+type Key interface {
+
+	// Kind returns the key's kind.
+	Kind() string
+
+	// StringID returns the key's string ID, which may be empty.
+	StringID() string
+
+	// IntID returns the key's integer ID, which may be zero.
+	IntID() int64
+
+	// Namespace returns the key's namespace.
+	Namespace() string
+
+	// Encode returns an opaque representation of the key,
+	// including its parents.
+	Encode() string
+}
+
+// ParentKeyer identifies an entity's parent via its full key, including
+// the parent's own ancestors. Unlike ParentNumIdentifier and
+// ParentTextIdentifier, it allows entity groups of any depth.
+// It takes precedence over them. The parent must be in the namespace
+// the entity is saved in.
+type ParentKeyer interface {
+
+	// ParentKey returns the parent's key, which may be nil.
+	ParentKey() Key
+
+	// SetParentKey sets the parent's key, which is a *hrd.Key.
+	SetParentKey(Key)
+}
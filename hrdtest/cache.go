@@ -0,0 +1,127 @@
+package hrdtest
+
+import (
+	"sync"
+	"time"
+
+	ae "appengine"
+)
+
+// Cache is a global cache for tests. It keeps all values in memory and
+// ignores expirations, so its behaviour is deterministic.
+type Cache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	locks  map[string]bool
+	hits   int
+	misses int
+}
+
+// NewCache returns a new, empty Cache.
+func NewCache() *Cache {
+	return &Cache{
+		values: make(map[string][]byte),
+		locks:  make(map[string]bool),
+	}
+}
+
+// Get returns the values of the passed-in keys.
+func (c *Cache) Get(_ ae.Context, keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, ok := c.values[key]; ok {
+			ret[key] = append([]byte(nil), value...)
+			c.hits++
+		} else {
+			c.misses++
+		}
+	}
+	return ret, nil
+}
+
+// Set stores the passed-in values, unless a key has a value or is locked.
+func (c *Cache) Set(_ ae.Context, values map[string][]byte, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range values {
+		if _, ok := c.values[key]; !ok && !c.locks[key] {
+			c.values[key] = append([]byte(nil), value...)
+		}
+	}
+	return nil
+}
+
+// Replace stores the passed-in values in place of
+// the current values and locks of their keys.
+func (c *Cache) Replace(_ ae.Context, values map[string][]byte, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range values {
+		c.values[key] = append([]byte(nil), value...)
+		delete(c.locks, key)
+	}
+	return nil
+}
+
+// Delete removes the values and locks of the passed-in keys.
+func (c *Cache) Delete(_ ae.Context, keys []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.values, key)
+		delete(c.locks, key)
+	}
+	return nil
+}
+
+// Lock replaces the values of the passed-in keys with locks.
+// They are held until the keys are deleted or the cache is flushed.
+func (c *Cache) Lock(_ ae.Context, keys []string, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.values, key)
+		c.locks[key] = true
+	}
+	return nil
+}
+
+// Len returns the number of cached values.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.values)
+}
+
+// Locked returns the number of locked keys.
+func (c *Cache) Locked() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.locks)
+}
+
+// Stats returns how many keys were found and not found by Get.
+func (c *Cache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits, c.misses
+}
+
+// Flush removes all values and locks.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = make(map[string][]byte)
+	c.locks = make(map[string]bool)
+}
@@ -0,0 +1,48 @@
+package hrdtest
+
+import (
+	"github.com/101loops/hrd"
+	"github.com/101loops/hrd/internal/memory"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
+
+// datastore adapts the in-memory datastore to a hrd.Datastore.
+type datastore struct {
+	*memory.Datastore
+}
+
+func (d *datastore) Count(ctx ae.Context, qry *hrd.DatastoreQuery) (int, error) {
+	return d.Datastore.Count(ctx, importQuery(qry))
+}
+
+func (d *datastore) Run(ctx ae.Context, qry *hrd.DatastoreQuery) hrd.DatastoreIterator {
+	return d.Datastore.Run(ctx, importQuery(qry))
+}
+
+func importQuery(qry *hrd.DatastoreQuery) *types.Query {
+	ret := types.NewQuery(qry.Kind)
+	ret.Namespace = qry.Namespace
+	ret.Ancestor = types.ImportKey(qry.Ancestor)
+	for _, f := range qry.Filters {
+		ret.Filter = append(ret.Filter, types.Filter{Filter: f.Filter, Value: f.Value})
+	}
+	for _, o := range qry.Orders {
+		ret.Order = append(ret.Order, types.Order{FieldName: o.FieldName, Descending: o.Descending})
+	}
+	switch {
+	case qry.KeysOnly:
+		ret.TypeOf = types.KeysOnlyQuery
+	case len(qry.Projection) > 0:
+		ret.TypeOf = types.ProjectQuery
+		ret.Projection = qry.Projection
+	}
+	ret.Distinct = qry.Distinct
+	ret.Eventual = qry.Eventual
+	ret.Limit = qry.Limit
+	ret.Offset = qry.Offset
+	ret.Start = qry.Start
+	ret.End = qry.End
+	return ret
+}
@@ -0,0 +1,36 @@
+// Package hrdtest allows to test code that uses hrd without running
+// the App Engine development server.
+//
+// It provides a Datastore that keeps all entities in memory. Its queries are
+// strongly consistent and do not require composite indexes, otherwise it
+// behaves like the App Engine datastore. Instead of memcache, a Cache
+// that keeps all values in memory is used. A Logger records log messages
+// for inspection.
+package hrdtest
+
+import (
+	"github.com/101loops/hrd"
+	"github.com/101loops/hrd/internal/memory"
+
+	ae "appengine"
+)
+
+// NewContext returns a new App Engine context.
+// It discards all log messages and supports no App Engine service but memcache,
+// which keeps its items in memory.
+func NewContext() ae.Context {
+	return memory.NewContext()
+}
+
+// NewDatastore returns a new, empty Datastore that keeps all entities in memory.
+func NewDatastore() hrd.Datastore {
+	return &datastore{memory.NewDatastore()}
+}
+
+// NewStore returns a new Store that keeps all entities and
+// cached values in memory. The passed-in options are applied
+// after the in-memory datastore and cache.
+func NewStore(options ...hrd.StoreOption) *hrd.Store {
+	defaults := []hrd.StoreOption{hrd.WithDatastore(NewDatastore()), hrd.WithCache(NewCache())}
+	return hrd.NewStore(append(defaults, options...)...)
+}
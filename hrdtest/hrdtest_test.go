@@ -0,0 +1,457 @@
+package hrdtest
+
+import (
+	"fmt"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
+)
+
+var _ = Describe("In-memory Store", func() {
+
+	var (
+		store *hrd.Store
+		books *hrd.Kind
+	)
+
+	BeforeEach(func() {
+		store, books = newStore()
+	})
+
+	titlesOf := func(books []*Book) []string {
+		ret := make([]string, len(books))
+		for i, b := range books {
+			ret[i] = b.Title
+		}
+		return ret
+	}
+
+	Context("entities", func() {
+
+		It("should load an entity", func() {
+			var book *Book
+			key, err := books.Load(ctx).ID(1).GetOne(&book)
+
+			Check(err, IsNil)
+			Check(key.Exists(), IsTrue)
+			Check(book.ID(), EqualsNum, 1)
+			Check(book.Title, Equals, "Go")
+			Check(book.Tags, Equals, []string{"lang", "google"})
+		})
+
+		It("should load multiple entities", func() {
+			var list []*Book
+			keys, err := books.Load(ctx).IDs(1, 2, 666).GetAll(&list)
+
+			Check(err, IsNil)
+			Check(keys, HasLen, 3)
+			Check(keys[0].Exists(), IsTrue)
+			Check(keys[1].Exists(), IsTrue)
+			Check(keys[2].Exists(), IsFalse)
+		})
+
+		It("should save an entity with an incomplete key", func() {
+			book := &Book{Title: "Java"}
+			key, err := books.Save(ctx).Entity(book)
+
+			Check(err, IsNil)
+			Check(key.Incomplete(), IsFalse)
+			Check(book.ID(), Equals, key.IntID())
+		})
+
+		It("should save an entity with a parent", func() {
+			chapter := &Chapter{Pages: 42}
+			chapter.SetID("intro")
+			chapter.SetParent("Book", 1)
+
+			key, err := store.Kind("Chapter").Save(ctx).Entity(chapter)
+			Check(err, IsNil)
+			Check(key.Parent().IntID(), EqualsNum, 1)
+
+			var loaded *Chapter
+			_, err = store.Kind("Chapter").Load(ctx).TextID("intro", books.NewNumKey(1)).GetOne(&loaded)
+			Check(err, IsNil)
+			Check(loaded.Pages, EqualsNum, 42)
+		})
+
+		It("should save and load an entity with key fields", func() {
+			review := &Review{Book: books.NewNumKey(1), Related: books.NewNumKeys(2, 3)}
+			review.SetID(1)
+
+			_, err := store.Kind("Review").Save(ctx).Entity(review)
+			Check(err, IsNil)
+
+			var loaded *Review
+			_, err = store.Kind("Review").Load(ctx).ID(1).GetOne(&loaded)
+			Check(err, IsNil)
+			Check(loaded.Book.Equal(books.NewNumKey(1)), IsTrue)
+			Check(loaded.Related, HasLen, 2)
+			Check(loaded.Related[0].IntID(), EqualsNum, 2)
+			Check(loaded.Related[1].IntID(), EqualsNum, 3)
+		})
+
+		It("should delete an entity", func() {
+			err := books.Delete(ctx).ID(1)
+			Check(err, IsNil)
+
+			var book *Book
+			key, err := books.Load(ctx).ID(1).GetOne(&book)
+			Check(err, IsNil)
+			Check(key.Exists(), IsFalse)
+		})
+	})
+
+	Context("ID allocation", func() {
+
+		It("should allocate IDs", func() {
+			keys, err := books.AllocateIDs(ctx, 2)
+			Check(err, IsNil)
+			Check(keys, HasLen, 2)
+			Check(keys[0].Incomplete(), IsFalse)
+			Check(keys[1].IntID(), EqualsNum, keys[0].IntID()+1)
+
+			parent := books.NewNumKey(1)
+			keys, err = store.Kind("Chapter").AllocateIDs(ctx, 1, parent)
+			Check(err, IsNil)
+			Check(keys[0].Parent(), Equals, parent)
+		})
+
+		It("should allocate an ID range", func() {
+			err := books.AllocateIDRange(ctx, 1000, 1999)
+			Check(err, IsNil)
+
+			err = books.AllocateIDRange(ctx, 1500, 2500)
+			Check(err, HasOccurred)
+
+			keys, err := books.AllocateIDs(ctx, 1)
+			Check(err, IsNil)
+			Check(keys[0].IntID(), EqualsNum, 2000)
+		})
+
+		It("should assign IDs before saving", func() {
+			newBooks := []*Book{{Title: "Rust"}, {Title: "Zig"}}
+			newBooks[1].SetID(77)
+
+			keys, err := books.Save(ctx).AssignIDs(newBooks)
+			Check(err, IsNil)
+			Check(keys, HasLen, 2)
+			Check(newBooks[0].ID(), Equals, keys[0].IntID())
+			Check(newBooks[0].ID(), Not(EqualsNum), 0)
+			Check(keys[1].IntID(), EqualsNum, 77)
+
+			var book *Book
+			key, err := books.Load(ctx).Key(keys[0]).GetOne(&book)
+			Check(err, IsNil)
+			Check(key.Exists(), IsFalse)
+
+			saved, err := books.Save(ctx).CompleteKeys().Entities(newBooks)
+			Check(err, IsNil)
+			Check(saved[0].Equal(keys[0]), IsTrue)
+			Check(saved[1].Equal(keys[1]), IsTrue)
+		})
+	})
+
+	Context("queries", func() {
+
+		It("should count entities", func() {
+			count, err := books.Query(ctx).GetCount()
+			Check(err, IsNil)
+			Check(count, EqualsNum, 4)
+
+			count, err = books.Query(ctx).Filter("tags =", "google").GetCount()
+			Check(err, IsNil)
+			Check(count, EqualsNum, 3)
+		})
+
+		It("should filter by equality", func() {
+			var list []*Book
+			_, _, err := books.Query(ctx).Filter("year =", 2010).OrderAsc("title").GetAll(&list)
+
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Angular", "Rust"})
+		})
+
+		It("should filter by inequality", func() {
+			var list []*Book
+			_, _, err := books.Query(ctx).Filter("year >", 2009).Filter("year <=", 2010).GetAll(&list)
+
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Rust", "Angular"})
+		})
+
+		It("should not filter by unindexed property", func() {
+			var list []*Book
+			_, _, err := books.Query(ctx).Filter("notes =", "Go").GetAll(&list)
+
+			Check(err, IsNil)
+			Check(list, IsEmpty)
+		})
+
+		It("should order results", func() {
+			var list []*Book
+			_, _, err := books.Query(ctx).OrderDesc("year").OrderAsc("title").GetAll(&list)
+
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Dart", "Angular", "Rust", "Go"})
+		})
+
+		It("should apply offset and limit", func() {
+			var list []*Book
+			_, _, err := books.Query(ctx).OrderAsc("title").Offset(1).Limit(2).GetAll(&list)
+
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Dart", "Go"})
+		})
+
+		It("should continue from a cursor", func() {
+			var list []*Book
+			_, cursor, err := books.Query(ctx).OrderAsc("title").Limit(2).GetAll(&list)
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Angular", "Dart"})
+
+			_, _, err = books.Query(ctx).OrderAsc("title").Start(cursor).GetAll(&list)
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Go", "Rust"})
+
+			_, _, err = books.Query(ctx).OrderAsc("title").End(cursor).GetAll(&list)
+			Check(err, IsNil)
+			Check(titlesOf(list), Equals, []string{"Angular", "Dart"})
+		})
+
+		It("should return keys only", func() {
+			keys, _, err := books.Query(ctx).Filter("tags =", "web").GetKeys()
+
+			Check(err, IsNil)
+			Check(keys, HasLen, 2)
+			Check(keys[0].IntID(), EqualsNum, 2)
+			Check(keys[1].IntID(), EqualsNum, 4)
+		})
+
+		It("should project properties", func() {
+			var list []*Book
+			_, _, err := books.Query(ctx).Project("year").Distinct().OrderAsc("year").GetAll(&list)
+
+			Check(err, IsNil)
+			Check(list, HasLen, 3)
+			Check(list[0].Year, EqualsNum, 2009)
+			Check(list[0].Title, IsZero)
+		})
+
+		It("should filter by ancestor", func() {
+			chapters := store.Kind("Chapter")
+			for i, parentID := range []int64{1, 1, 2} {
+				chapter := &Chapter{Pages: int64(i)}
+				chapter.SetID(fmt.Sprintf("c%d", i))
+				chapter.SetParent("Book", parentID)
+				_, err := chapters.Save(ctx).Entity(chapter)
+				Check(err, IsNil)
+			}
+
+			count, err := chapters.Query(ctx).Ancestor(books.NewNumKey(1)).GetCount()
+			Check(err, IsNil)
+			Check(count, EqualsNum, 2)
+		})
+
+		It("should return an error for an invalid filter", func() {
+			_, err := books.Query(ctx).Filter("year !=", 2010).GetCount()
+			Check(err, ErrorContains, "invalid operator")
+		})
+	})
+
+	Context("namespaces", func() {
+
+		It("should isolate entities by namespace", func() {
+			other := books.Namespace("other")
+
+			key, err := other.Save(ctx).Entity(newBook(1, "Go in Other", 2015))
+			Check(err, IsNil)
+			Check(key.Namespace(), Equals, "other")
+
+			var book *Book
+			_, err = books.Load(ctx).ID(1).GetOne(&book)
+			Check(err, IsNil)
+			Check(book.Title, Equals, "Go")
+
+			_, err = other.Load(ctx).ID(1).GetOne(&book)
+			Check(err, IsNil)
+			Check(book.Title, Equals, "Go in Other")
+		})
+
+		It("should query entities of a namespace", func() {
+			other := store.Namespace("other").Kind("Book")
+			other.Save(ctx).Entity(newBook(5, "Elm", 2012, "lang"))
+
+			count, err := other.Query(ctx).GetCount()
+			Check(err, IsNil)
+			Check(count, EqualsNum, 1)
+
+			count, err = books.Query(ctx).GetCount()
+			Check(err, IsNil)
+			Check(count, EqualsNum, 4)
+		})
+	})
+
+	Context("transactions", func() {
+
+		It("should commit changes", func() {
+			err := store.TX(ctx).Run(func(tx hrd.TX) error {
+				_, err := books.Save(tx).Entity(newBook(1, "Go 2", 2022))
+				return err
+			})
+			Check(err, IsNil)
+
+			var book *Book
+			books.Load(ctx).ID(1).GetOne(&book)
+			Check(book.Title, Equals, "Go 2")
+		})
+
+		It("should roll back changes on error", func() {
+			err := store.TX(ctx).Run(func(tx hrd.TX) error {
+				books.Delete(tx).ID(1)
+				return fmt.Errorf("rollback")
+			})
+			Check(err, ErrorContains, "rollback")
+
+			var book *Book
+			key, _ := books.Load(ctx).ID(1).GetOne(&book)
+			Check(key.Exists(), IsTrue)
+		})
+
+		It("should load changes made in a transaction", func() {
+			var book *Book
+			books.Load(ctx).ID(1).GetOne(&book)
+			Check(book.Title, Equals, "Go")
+
+			store.TX(ctx).Run(func(tx hrd.TX) error {
+				_, err := books.Save(tx).Entity(newBook(1, "Go 2", 2022))
+				return err
+			})
+
+			books.Load(ctx).ID(1).GetOne(&book)
+			Check(book.Title, Equals, "Go 2")
+		})
+
+		It("should read a snapshot", func() {
+			store.TX(ctx).Run(func(tx hrd.TX) error {
+				books.Delete(tx).ID(1)
+
+				var book *Book
+				key, err := books.Load(tx).ID(1).GetOne(&book)
+				Check(err, IsNil)
+				Check(key.Exists(), IsTrue)
+				return nil
+			})
+		})
+
+		It("should require cross-group transaction for multiple entity groups", func() {
+			load := func(tx hrd.TX) error {
+				var list []*Book
+				_, err := books.Load(tx).IDs(1, 2).GetAll(&list)
+				return err
+			}
+
+			err := store.TX(ctx).Run(load)
+			Check(err, ErrorContains, "cross-group transaction")
+
+			err = store.TX(ctx).XG().Run(load)
+			Check(err, IsNil)
+		})
+
+		It("should not allow non-ancestor queries", func() {
+			err := store.TX(ctx).Run(func(tx hrd.TX) error {
+				_, err := books.Query(tx).GetCount()
+				return err
+			})
+			Check(err, ErrorContains, "only ancestor queries")
+		})
+	})
+
+	Context("metrics", func() {
+
+		It("should record operations", func() {
+			agg := metrics.NewAggregator()
+			store = NewStore(hrd.WithMetrics(agg))
+			store.RegisterEntityMust(&Book{})
+			books = store.Kind("Book")
+
+			books.Save(ctx).Entities([]*Book{newBook(1, "Go", 2009), newBook(2, "Dart", 2011)})
+			var book *Book
+			books.Load(ctx).ID(1).GetOne(&book)
+			books.Load(ctx).ID(1).GetOne(&book)
+			books.Query(ctx).GetCount()
+
+			stats := agg.Stats()
+			Check(stats, HasLen, 3)
+			Check(stats[0].Op, Equals, metrics.OpCount)
+			Check(stats[0].Entities, EqualsNum, 2)
+			Check(stats[1].Op, Equals, metrics.OpGet)
+			Check(stats[1].Count, EqualsNum, 2)
+			Check(stats[1].CacheHits, EqualsNum, 1)
+			Check(stats[1].CacheMisses, EqualsNum, 1)
+			Check(stats[2].Op, Equals, metrics.OpPut)
+			Check(stats[2].Entities, EqualsNum, 2)
+		})
+	})
+
+	Context("logging", func() {
+
+		var (
+			logger *Logger
+		)
+
+		BeforeEach(func() {
+			logger = NewLogger()
+			store = NewStore(hrd.WithLogger(logger))
+			store.RegisterEntityMust(&Book{})
+			books = store.Kind("Book")
+		})
+
+		It("should log an operation", func() {
+			_, err := books.Save(ctx).Entity(newBook(1, "Go", 2009))
+			Check(err, IsNil)
+
+			entries := logger.Entries()
+			Check(entries, HasLen, 1)
+			Check(entries[0].Level, Equals, logging.Info)
+			Check(entries[0].Msg, Equals, "putting Key{'Book', 1}")
+			Check(entries[0].Fields["op"], Equals, "put")
+			Check(entries[0].Fields["kind"], Equals, "Book")
+			Check(entries[0].Fields["keys"], Equals, 1)
+			Check(entries[0].Fields["duration"], NotNil)
+		})
+
+		It("should log cache hits", func() {
+			books.Save(ctx).Entity(newBook(1, "Go", 2009))
+
+			var book *Book
+			books.Load(ctx).ID(1).GetOne(&book)
+			books.Load(ctx).ID(1).GetOne(&book)
+
+			entries := logger.Entries()
+			Check(entries, HasLen, 3)
+			Check(entries[1].Fields["cache_hits"], Equals, 0)
+			Check(entries[2].Fields["cache_hits"], Equals, 1)
+		})
+
+		It("should log a query", func() {
+			books.Query(ctx).Filter("year =", 2009).Limit(10).GetCount()
+
+			entries := logger.Entries()
+			Check(entries, HasLen, 1)
+			Check(entries[0].Msg, Equals, "counting query")
+			Check(fmt.Sprint(entries[0].Fields["query"]), Equals, `KIND "Book" | FILTER 'year = 2009' | LIMIT 10`)
+			Check(entries[0].Fields["count"], Equals, 0)
+		})
+
+		It("should be silenced", func() {
+			store = NewStore(hrd.WithLogger(nil))
+			store.RegisterEntityMust(&Book{})
+
+			_, err := store.Kind("Book").Save(ctx).Entity(newBook(1, "Go", 2009))
+			Check(err, IsNil)
+			Check(logger.Entries(), HasLen, 0)
+		})
+	})
+})
@@ -0,0 +1,51 @@
+package hrdtest
+
+import (
+	"sync"
+
+	"github.com/101loops/hrd/logging"
+
+	ae "appengine"
+)
+
+// LogEntry is a message recorded by a Logger.
+type LogEntry struct {
+	Level  logging.Level
+	Msg    string
+	Fields logging.Fields
+}
+
+// Logger is a Logger for tests. It records all messages in memory.
+type Logger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// NewLogger returns a new Logger without any messages.
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// Log records a message.
+func (l *Logger) Log(_ ae.Context, level logging.Level, msg string, fields logging.Fields) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, LogEntry{level, msg, fields})
+}
+
+// Entries returns the recorded messages, oldest first.
+func (l *Logger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]LogEntry(nil), l.entries...)
+}
+
+// Reset removes all recorded messages.
+func (l *Logger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
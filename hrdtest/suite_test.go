@@ -0,0 +1,85 @@
+package hrdtest
+
+import (
+	"testing"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd"
+	"github.com/101loops/hrd/entity"
+
+	ae "appengine"
+)
+
+var (
+	ctx ae.Context
+)
+
+func TestSuite(t *testing.T) {
+	ctx = NewContext()
+
+	RunSpecs(t, "HRD Test Suite")
+}
+
+// ==== MODELS
+
+type Book struct {
+	entity.NumID
+
+	Title string   `datastore:"title,index"`
+	Year  int64    `datastore:"year,index"`
+	Tags  []string `datastore:"tags,index"`
+	Notes string   `datastore:"notes"`
+}
+
+type Chapter struct {
+	entity.TextID
+
+	parentKind string
+	parentID   int64
+
+	Pages int64 `datastore:"pages,index"`
+}
+
+func (mdl *Chapter) Parent() (kind string, id int64) {
+	return mdl.parentKind, mdl.parentID
+}
+
+func (mdl *Chapter) SetParent(kind string, id int64) {
+	mdl.parentKind = kind
+	mdl.parentID = id
+}
+
+type Review struct {
+	entity.NumID
+
+	Book    *hrd.Key   `datastore:"book,index"`
+	Related []*hrd.Key `datastore:"related"`
+}
+
+// ===== UTIL
+
+func newBook(id int64, title string, year int64, tags ...string) *Book {
+	book := &Book{Title: title, Year: year, Tags: tags, Notes: title}
+	book.SetID(id)
+	return book
+}
+
+func newStore() (*hrd.Store, *hrd.Kind) {
+	store := NewStore()
+	store.RegisterEntityMust(&Book{})
+	store.RegisterEntityMust(&Chapter{})
+	store.RegisterEntityMust(&Review{})
+
+	books := store.Kind("Book")
+	_, err := books.Save(ctx).Entities([]*Book{
+		newBook(1, "Go", 2009, "lang", "google"),
+		newBook(2, "Dart", 2011, "lang", "google", "web"),
+		newBook(3, "Rust", 2010, "lang"),
+		newBook(4, "Angular", 2010, "web", "google"),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return store, books
+}
@@ -0,0 +1,121 @@
+package hrd
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
+
+// IDStrategy generates the IDs of entities with text IDs
+// (see entity.TextIdentifier) that are saved with an incomplete key.
+// Entities with numeric IDs are always assigned an ID by the datastore.
+// It must be safe for concurrent use.
+type IDStrategy interface {
+
+	// NewID returns a new ID for an entity of the passed-in kind.
+	// An empty ID leaves the entity's key incomplete.
+	NewID(ctx ae.Context, kind string) (string, error)
+}
+
+// IDFunc is a function that is an IDStrategy.
+type IDFunc func(ctx ae.Context, kind string) (string, error)
+
+// NewID calls the function.
+func (f IDFunc) NewID(ctx ae.Context, kind string) (string, error) {
+	return f(ctx, kind)
+}
+
+var (
+	// DatastoreIDs allocates numeric IDs of the kind with the datastore,
+	// through the kind's store, and uses them in decimal, e.g. "42".
+	DatastoreIDs IDStrategy = datastoreIDs{}
+
+	// UUIDs generates random UUIDs (version 4), e.g.
+	// "1b4e28ba-2fa1-41d2-883f-0016d3cca427".
+	UUIDs IDStrategy = IDFunc(func(ae.Context, string) (string, error) {
+		return newUUID()
+	})
+
+	// ULIDs generates ULIDs, which sort by their time of creation
+	// (in milliseconds), e.g. "01ARZ3NDEKTSV4RRFFQ69G5FAV".
+	ULIDs IDStrategy = IDFunc(func(ae.Context, string) (string, error) {
+		return newULID(nowFunc())
+	})
+
+	randRead = rand.Read
+	nowFunc  = time.Now
+)
+
+// datastoreIDs is the type of DatastoreIDs. Its IDs are allocated
+// by the store of a kind, see Kind.allocatedIDs.
+type datastoreIDs struct{}
+
+// NewID fails, since datastore IDs are allocated by the store of a kind.
+func (datastoreIDs) NewID(_ ae.Context, kind string) (string, error) {
+	return "", fmt.Errorf("cannot allocate datastore ID for %q without its store", kind)
+}
+
+// allocatedIDs returns the ID strategy of DatastoreIDs for the passed-in
+// internal representation of the kind: it allocates one numeric ID at a time
+// through the store's backend. A dry run leaves the keys incomplete.
+func (k *Kind) allocatedIDs(kind *types.Kind) IDStrategy {
+	return IDFunc(func(ae.Context, string) (string, error) {
+		keys, err := k.store.run().AllocateIDs(kind, nil, 1)
+		if err != nil {
+			return "", err
+		}
+		if keys[0].IntID == 0 {
+			return "", nil
+		}
+		return strconv.FormatInt(keys[0].IntID, 10), nil
+	})
+}
+
+// IDStrategy sets how the IDs of the kind's entities with text IDs are
+// generated when they are saved with an incomplete key.
+func (k *Kind) IDStrategy(strategy IDStrategy) *Kind {
+	k.idStrategy = strategy
+	return k
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := randRead(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// crockford is the alphabet of Crockford's base32 encoding used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID of the passed-in time: a 48 bit timestamp in
+// milliseconds, followed by 80 random bits, encoded as 26 characters.
+func newULID(t time.Time) (string, error) {
+	var b [16]byte
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	if _, err := randRead(b[6:]); err != nil {
+		return "", err
+	}
+
+	// encode 128 bits as 26 characters of 5 bits, the first one having 3 bits
+	ret := make([]byte, 26)
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	for i := 25; i >= 0; i-- {
+		ret[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(ret), nil
+}
@@ -0,0 +1,135 @@
+package hrd
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
+
+var _ = Describe("IDStrategy", func() {
+
+	var (
+		_randRead = randRead
+		_nowFunc  = nowFunc
+	)
+
+	AfterEach(func() {
+		randRead = _randRead
+		nowFunc = _nowFunc
+	})
+
+	fillRand := func(v byte) {
+		randRead = func(b []byte) (int, error) {
+			for i := range b {
+				b[i] = v
+			}
+			return len(b), nil
+		}
+	}
+
+	Context("datastore IDs", func() {
+
+		AfterEach(func() {
+			myBackend.allocate = nil
+		})
+
+		It("should allocate numeric IDs through the backend", func() {
+			myBackend.allocate = func(kind *types.Kind, parent *types.Key, n int) ([]*types.Key, error) {
+				Check(parent, IsNil)
+				Check(n, EqualsNum, 1)
+				return []*types.Key{types.NewKey(kind.Name, "", 42, nil)}, nil
+			}
+
+			kind := myStore.Kind("my-kind").IDStrategy(DatastoreIDs).toInternal(ctx, nil)
+			id, err := kind.IDStrategy.NewID(ctx, "my-kind")
+			Check(err, IsNil)
+			Check(id, Equals, "42")
+		})
+
+		It("should return an error when the allocation fails", func() {
+			myBackend.allocate = func(_ *types.Kind, _ *types.Key, _ int) ([]*types.Key, error) {
+				return nil, fmt.Errorf("an error")
+			}
+
+			kind := myStore.Kind("my-kind").IDStrategy(DatastoreIDs).toInternal(ctx, nil)
+			_, err := kind.IDStrategy.NewID(ctx, "my-kind")
+			Check(err, ErrorContains, "an error")
+		})
+
+		It("should leave the IDs empty in a dry run", func() {
+			store := NewStore(withBackend(myBackend)).DryRun()
+			kind := store.Kind("my-kind").IDStrategy(DatastoreIDs).toInternal(ctx, nil)
+			id, err := kind.IDStrategy.NewID(ctx, "my-kind")
+			Check(err, IsNil)
+			Check(id, Equals, "")
+		})
+
+		It("should not allocate IDs without a store", func() {
+			_, err := DatastoreIDs.NewID(ctx, "my-kind")
+			Check(err, ErrorContains, "without its store")
+		})
+	})
+
+	It("should generate UUIDs", func() {
+		id, err := UUIDs.NewID(ctx, "my-kind")
+		Check(err, IsNil)
+		Check(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id), IsTrue)
+
+		fillRand(0xff)
+		id, err = UUIDs.NewID(ctx, "my-kind")
+		Check(err, IsNil)
+		Check(id, Equals, "ffffffff-ffff-4fff-bfff-ffffffffffff")
+	})
+
+	It("should generate ULIDs", func() {
+		fillRand(0)
+		nowFunc = func() time.Time {
+			return time.Unix(0, 1469918176385*int64(time.Millisecond))
+		}
+
+		id, err := ULIDs.NewID(ctx, "my-kind")
+		Check(err, IsNil)
+		Check(id, Equals, "01ARYZ6S410000000000000000")
+
+		fillRand(0xff)
+		id, err = ULIDs.NewID(ctx, "my-kind")
+		Check(err, IsNil)
+		Check(id, Equals, "01ARYZ6S41ZZZZZZZZZZZZZZZZ")
+	})
+
+	It("should generate ULIDs sorted by time", func() {
+		now := time.Now()
+		id1, _ := newULID(now)
+		id2, _ := newULID(now.Add(time.Millisecond))
+		Check(id1 < id2, IsTrue)
+	})
+
+	It("should return an error when random numbers fail", func() {
+		randRead = func(b []byte) (int, error) {
+			return 0, fmt.Errorf("an error")
+		}
+
+		_, err := UUIDs.NewID(ctx, "my-kind")
+		Check(err, ErrorContains, "an error")
+		_, err = ULIDs.NewID(ctx, "my-kind")
+		Check(err, ErrorContains, "an error")
+	})
+
+	It("should pass the kind's ID strategy on", func() {
+		strategy := IDFunc(func(_ ae.Context, kind string) (string, error) {
+			return kind + "-id", nil
+		})
+
+		kind := myStore.Kind("my-kind").IDStrategy(strategy)
+		id, err := kind.toInternal(ctx, nil).IDStrategy.NewID(ctx, kind.Name())
+		Check(err, IsNil)
+		Check(id, Equals, "my-kind-id")
+
+		Check(myKind.toInternal(ctx, nil).IDStrategy, IsNil)
+	})
+})
@@ -0,0 +1,238 @@
+package hrd
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
+
+var (
+	typeOfKey      = reflect.TypeOf(&Key{})
+	typeOfKeySlice = reflect.TypeOf([]*Key{})
+	typeOfRefSlice = reflect.TypeOf([]*Ref{})
+)
+
+// includeField is a field to load related entities into. The field is
+// tagged with the name of the field holding their keys, e.g.
+//
+//	AuthorKey *hrd.Key `datastore:"author"`
+//	Author    *User    `datastore:"-" hrd:"AuthorKey"`
+type includeField struct {
+	target   []int
+	source   []int
+	multi    bool
+	elemType reflect.Type
+}
+
+// includeGroup contains the related entities of one kind and namespace.
+type includeGroup struct {
+	kind     *Kind
+	keys     []*Key
+	entities map[string]reflect.Value
+}
+
+type includeGroupID struct {
+	elemType  reflect.Type
+	kind      string
+	namespace string
+}
+
+func newIncludeField(typ reflect.Type, name string) (*includeField, error) {
+	target, ok := typ.FieldByName(name)
+	if !ok || target.PkgPath != "" {
+		return nil, fmt.Errorf("cannot include unknown field %q of %q", name, typ)
+	}
+
+	sourceName := target.Tag.Get("hrd")
+	source, ok := typ.FieldByName(sourceName)
+	if sourceName == "" || !ok || source.PkgPath != "" {
+		return nil, fmt.Errorf("field %q of %q does not name a key field to include", name, typ)
+	}
+
+	ret := &includeField{target: target.Index, source: source.Index}
+	switch source.Type {
+	case typeOfKey, typeOfRef:
+		ret.elemType = target.Type
+	case typeOfKeySlice, typeOfRefSlice:
+		ret.multi = true
+		if target.Type.Kind() == reflect.Slice {
+			ret.elemType = target.Type.Elem()
+		}
+	default:
+		return nil, fmt.Errorf("cannot include field %q from field %q of type %q", name, sourceName, source.Type)
+	}
+
+	if ret.elemType == nil || ret.elemType.Kind() != reflect.Ptr || ret.elemType.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot include field %q of type %q (wanted struct pointer or slice of struct pointers)", name, target.Type)
+	}
+	return ret, nil
+}
+
+// keysOf returns the keys of the related entities of the entity.
+func (inc *includeField) keysOf(entity reflect.Value) []*Key {
+	var ret []*Key
+	switch x := entity.FieldByIndex(inc.source).Interface().(type) {
+	case *Key:
+		ret = append(ret, x)
+	case []*Key:
+		ret = append(ret, x...)
+	case *Ref:
+		if x != nil {
+			ret = append(ret, x.key)
+		}
+	case []*Ref:
+		for _, ref := range x {
+			if ref != nil {
+				ret = append(ret, ref.key)
+			}
+		}
+	}
+	return ret
+}
+
+// assign sets the related entities of the entity, leaving out missing ones.
+func (inc *includeField) assign(entity reflect.Value, lookup func(*Key) (reflect.Value, bool)) {
+	var related []reflect.Value
+	for _, key := range inc.keysOf(entity) {
+		if val, ok := lookup(key); ok {
+			related = append(related, val)
+		}
+	}
+
+	// resolve the references as well
+	resolve := func(ref *Ref) {
+		if ref == nil {
+			return
+		}
+		if val, ok := lookup(ref.key); ok {
+			ref.setEntity(val)
+		}
+	}
+	switch x := entity.FieldByIndex(inc.source).Interface().(type) {
+	case *Ref:
+		resolve(x)
+	case []*Ref:
+		for _, ref := range x {
+			resolve(ref)
+		}
+	}
+
+	target := entity.FieldByIndex(inc.target)
+
+	if inc.multi {
+		slice := reflect.MakeSlice(target.Type(), 0, len(related))
+		target.Set(reflect.Append(slice, related...))
+	} else if len(related) > 0 {
+		target.Set(related[0])
+	} else {
+		target.Set(reflect.Zero(target.Type()))
+	}
+}
+
+// loadIncludes loads the entities referenced by the passed entities,
+// with one batch per kind, and assigns them to the named fields.
+// It loads them with the passed-in options of the calling action
+// on the kind (see Kind.related).
+func (k *Kind) loadIncludes(ctx ae.Context, opts *types.Opts, entities interface{}, fields []string) error {
+	vals := structsOf(reflect.ValueOf(entities))
+	for _, field := range fields {
+		if err := k.loadInclude(ctx, opts, vals, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *Kind) loadInclude(ctx ae.Context, opts *types.Opts, entities []reflect.Value, field string) error {
+	incs := make(map[reflect.Type]*includeField)
+	groups := make(map[includeGroupID]*includeGroup)
+	groupIDOf := func(inc *includeField, key *Key) includeGroupID {
+		return includeGroupID{inc.elemType, key.Kind(), key.Namespace()}
+	}
+
+	// collect the keys of the related entities
+	for _, entity := range entities {
+		inc, ok := incs[entity.Type()]
+		if !ok {
+			var err error
+			if inc, err = newIncludeField(entity.Type(), field); err != nil {
+				return err
+			}
+			incs[entity.Type()] = inc
+		}
+
+		for _, key := range inc.keysOf(entity) {
+			if key == nil {
+				continue
+			}
+			id := groupIDOf(inc, key)
+			group, ok := groups[id]
+			if !ok {
+				group = &includeGroup{
+					kind:     k.related(id.kind, id.namespace, opts),
+					entities: make(map[string]reflect.Value),
+				}
+				groups[id] = group
+			}
+			if _, ok := group.entities[key.Encode()]; !ok {
+				group.entities[key.Encode()] = reflect.Value{}
+				group.keys = append(group.keys, key)
+			}
+		}
+	}
+
+	// load the related entities
+	for id, group := range groups {
+		dsts := reflect.New(reflect.SliceOf(id.elemType))
+		keys, err := group.kind.Load(ctx).Keys(group.keys).GetAll(dsts.Interface())
+		if err != nil {
+			return err
+		}
+		for i, key := range keys {
+			if key.Exists() {
+				group.entities[key.Encode()] = reflect.ValueOf(dsts.Elem().Index(i).Interface())
+			}
+		}
+	}
+
+	// assign the related entities
+	for _, entity := range entities {
+		inc := incs[entity.Type()]
+		inc.assign(entity, func(key *Key) (reflect.Value, bool) {
+			if key == nil {
+				return reflect.Value{}, false
+			}
+			val := groups[groupIDOf(inc, key)].entities[key.Encode()]
+			return val, val.IsValid()
+		})
+	}
+
+	return nil
+}
+
+// structsOf returns the settable structs of the passed entities.
+func structsOf(v reflect.Value) []reflect.Value {
+	var ret []reflect.Value
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			ret = structsOf(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			ret = append(ret, structsOf(v.Index(i))...)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			ret = append(ret, structsOf(v.MapIndex(k))...)
+		}
+	case reflect.Struct:
+		if v.CanSet() {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
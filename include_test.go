@@ -0,0 +1,147 @@
+package hrd
+
+import (
+	"time"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/types"
+)
+
+type includeAuthor struct {
+	entity.NumID
+
+	Name string
+}
+
+type includePost struct {
+	entity.NumID
+
+	AuthorKey  *Key             `datastore:"author"`
+	Author     *includeAuthor   `datastore:"-" hrd:"AuthorKey"`
+	EditorRefs []*Ref           `datastore:"editors"`
+	Editors    []*includeAuthor `datastore:"-" hrd:"EditorRefs"`
+}
+
+var _ = Describe("Include", func() {
+
+	var (
+		authors *Kind
+		posts   *Kind
+		gets    int
+
+		authorPolicy = cache.Policy{Mode: cache.WriteThrough, TTL: time.Hour}
+	)
+
+	BeforeEach(func() {
+		store := NewStore(withBackend(myBackend), WithInterceptor(func(op *Operation, next func() error) error {
+			if op.Type == OpGet {
+				gets++
+			}
+			return next()
+		}))
+		store.RegisterEntityMust(&includeAuthor{}, authorPolicy)
+		store.RegisterEntityMust(&includePost{})
+		authors = store.Kind("include-author")
+		posts = store.Kind("include-post")
+
+		list := []*includeAuthor{{Name: "Ann"}, {Name: "Bob"}}
+		list[0].SetID(1)
+		list[1].SetID(2)
+		_, err := authors.Save(ctx).Entities(list)
+		Check(err, IsNil)
+
+		postList := []*includePost{
+			{AuthorKey: authors.NewNumKey(1), EditorRefs: []*Ref{authors.NewRef(authors.NewNumKey(2))}},
+			{AuthorKey: authors.NewNumKey(2), EditorRefs: []*Ref{authors.NewRef(authors.NewNumKey(666))}},
+			{AuthorKey: authors.NewNumKey(1)},
+		}
+		for i, post := range postList {
+			post.SetID(int64(i + 1))
+		}
+		_, err = posts.Save(ctx).Entities(postList)
+		Check(err, IsNil)
+
+		gets = 0
+	})
+
+	It("should include related entities of a query", func() {
+		var list []*includePost
+		_, _, err := posts.Query(ctx).OrderAsc("__key__").Include("Author", "Editors").GetAll(&list)
+		Check(err, IsNil)
+		Check(list, HasLen, 3)
+
+		Check(list[0].Author.Name, Equals, "Ann")
+		Check(list[1].Author.Name, Equals, "Bob")
+		Check(list[2].Author.Name, Equals, "Ann")
+
+		Check(list[0].Editors, HasLen, 1)
+		Check(list[0].Editors[0].Name, Equals, "Bob")
+		Check(list[0].EditorRefs[0].IsResolved(), IsTrue)
+		Check(list[1].Editors, HasLen, 0)
+		Check(list[2].Editors, HasLen, 0)
+	})
+
+	It("should load related entities in one batch per field", func() {
+		var list []*includePost
+		_, err := posts.Load(ctx).NoGlobalCache().NoLocalCache().Include("Author").IDs(1, 2, 3).GetAll(&list)
+		Check(err, IsNil)
+		Check(gets, EqualsNum, 2)
+	})
+
+	It("should load related entities with the options of the caller, but their own policy", func() {
+		defer func() {
+			myBackend.get = nil
+		}()
+
+		var loaded []*types.Kind
+		myBackend.get = func(kind *types.Kind, keys []*types.Key, dst interface{}, opts *types.Opts, multi bool) ([]*types.Key, error) {
+			if kind.Name == "include-author" {
+				loaded = append(loaded, kind)
+				Check(opts.NoGlobalCache, IsTrue)
+				Check(opts.NoLocalCache, IsTrue)
+			}
+			return myBackend.backend.Get(kind, keys, dst, opts, multi)
+		}
+
+		policy := cache.Policy{Mode: cache.ReadOnly, TTL: time.Minute}
+		kind := posts.NoGlobalCache().CachePolicy(policy).IDStrategy(UUIDs)
+
+		var post *includePost
+		_, err := kind.Load(ctx).NoLocalCache().Include("Author").ID(2).GetOne(&post)
+		Check(err, IsNil)
+		Check(post.Author.Name, Equals, "Bob")
+
+		var list []*includePost
+		_, _, err = kind.Query(ctx).NoLocalCache().Include("Author").GetAll(&list)
+		Check(err, IsNil)
+
+		Check(loaded, HasLen, 2)
+		for _, kind := range loaded {
+			Check(kind.CachePolicy, Equals, authorPolicy)
+			Check(kind.IDStrategy, IsNil)
+		}
+	})
+
+	It("should include related entities of a single entity", func() {
+		var post *includePost
+		_, err := posts.Load(ctx).Include("Author").ID(2).GetOne(&post)
+		Check(err, IsNil)
+		Check(post.Author.Name, Equals, "Bob")
+	})
+
+	// ==== ERRORS
+
+	It("should return an error for an unknown field", func() {
+		var post *includePost
+		_, err := posts.Load(ctx).Include("Unknown").ID(1).GetOne(&post)
+		Check(err, ErrorContains, `cannot include unknown field "Unknown" of "hrd.includePost"`)
+	})
+
+	It("should return an error for a field without key field", func() {
+		var post *includePost
+		_, err := posts.Load(ctx).Include("AuthorKey").ID(1).GetOne(&post)
+		Check(err, ErrorContains, `field "AuthorKey" of "hrd.includePost" does not name a key field to include`)
+	})
+})
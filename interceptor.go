@@ -0,0 +1,218 @@
+package hrd
+
+import (
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/metrics"
+
+	ae "appengine"
+)
+
+// OpType is the type of a datastore operation.
+// It shares its values with the operations of metrics and log messages.
+type OpType metrics.Op
+
+const (
+	// OpGet loads entities by key.
+	OpGet = OpType(metrics.OpGet)
+
+	// OpPut saves entities.
+	OpPut = OpType(metrics.OpPut)
+
+	// OpDelete deletes entities by key.
+	OpDelete = OpType(metrics.OpDelete)
+
+	// OpQuery loads the results of a query.
+	OpQuery = OpType(metrics.OpQuery)
+
+	// OpCount counts the results of a query.
+	OpCount = OpType(metrics.OpCount)
+
+	// OpTransaction runs a transaction.
+	OpTransaction = OpType(metrics.OpTransaction)
+
+	// OpAllocate allocates numeric IDs.
+	OpAllocate = OpType(metrics.OpAllocate)
+)
+
+func (t OpType) String() string {
+	return string(t)
+}
+
+// Options are the options a datastore operation was issued with.
+type Options struct {
+
+	// CompleteKeys is whether an entity's key must be set before saving.
+	CompleteKeys bool
+
+	// NoGlobalCache is whether the global cache is not used.
+	NoGlobalCache bool
+
+	// NoLocalCache is whether the in-memory cache of a context is not used.
+	NoLocalCache bool
+}
+
+func exportOpts(opts *types.Opts) Options {
+	return Options{
+		CompleteKeys:  opts.CompleteKeys,
+		NoGlobalCache: opts.NoGlobalCache,
+		NoLocalCache:  opts.NoLocalCache,
+	}
+}
+
+// Operation describes a datastore operation passed to an Interceptor.
+type Operation struct {
+
+	// Type is the type of the operation.
+	Type OpType
+
+	// Context is the context the operation runs in.
+	Context ae.Context
+
+	// Kind is the name of the operation's kind, it is empty for a transaction.
+	Kind string
+
+	// Namespace is the namespace of the operation's kind.
+	Namespace string
+
+	// Keys are the keys of the entities to load or delete. Once the
+	// operation ran, they are the keys of the loaded or saved entities,
+	// or the allocated keys.
+	Keys []*Key
+
+	// Entities are the entities to save or the destination to load into.
+	Entities interface{}
+
+	// Options are the options of the operation.
+	Options Options
+
+	// Query describes the query of a query or count.
+	Query string
+
+	// Count is the number of results of a count, once it ran.
+	Count int
+}
+
+// Interceptor wraps the datastore operations of a Store.
+// It executes an operation by calling next, which may be skipped
+// (e.g. to deny an operation) or called more than once (e.g. to retry it).
+type Interceptor func(op *Operation, next func() error) error
+
+// WithInterceptor adds an Interceptor to the store. Interceptors wrap
+// operations in the order they were added, the first one being outermost.
+func WithInterceptor(interceptor Interceptor) StoreOption {
+	return func(s *Store) {
+		s.interceptors = append(s.interceptors, interceptor)
+	}
+}
+
+// interceptedBackend is a backend that runs
+// the operations through a chain of interceptors.
+type interceptedBackend struct {
+	backend
+	interceptors []Interceptor
+}
+
+func (b *interceptedBackend) intercept(op *Operation, f func() error) error {
+	next := f
+	for i := len(b.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := b.interceptors[i], next
+		next = func() error {
+			return interceptor(op, inner)
+		}
+	}
+	return next()
+}
+
+func (b *interceptedBackend) Get(kind *types.Kind, keys []*types.Key, dst interface{}, opts *types.Opts, multi bool) (ret []*types.Key, err error) {
+	op := newKindOperation(OpGet, kind)
+	op.Keys = importKeys(keys)
+	op.Entities = dst
+	op.Options = exportOpts(opts)
+
+	err = b.intercept(op, func() error {
+		ret, err = b.backend.Get(kind, keys, dst, opts, multi)
+		op.Keys = importKeys(ret)
+		return err
+	})
+	return
+}
+
+func (b *interceptedBackend) Put(kind *types.Kind, src interface{}, opts *types.Opts) (ret []*types.Key, err error) {
+	op := newKindOperation(OpPut, kind)
+	op.Entities = src
+	op.Options = exportOpts(opts)
+
+	err = b.intercept(op, func() error {
+		ret, err = b.backend.Put(kind, src, opts)
+		op.Keys = importKeys(ret)
+		return err
+	})
+	return
+}
+
+func (b *interceptedBackend) Delete(kind *types.Kind, keys ...*types.Key) error {
+	op := newKindOperation(OpDelete, kind)
+	op.Keys = importKeys(keys)
+
+	return b.intercept(op, func() error {
+		return b.backend.Delete(kind, keys...)
+	})
+}
+
+func (b *interceptedBackend) Count(kind *types.Kind, qry *types.Query) (ret int, err error) {
+	op := newKindOperation(OpCount, kind)
+	op.Query = qry.String()
+
+	err = b.intercept(op, func() error {
+		ret, err = b.backend.Count(kind, qry)
+		op.Count = ret
+		return err
+	})
+	return
+}
+
+// Query runs the query anew on every call of next,
+// so that a retry does not continue a used iterator.
+func (b *interceptedBackend) Query(kind *types.Kind, qry *types.Query, dsts interface{}, multi bool) (it types.Iterator, ret []*types.Key, err error) {
+	op := newKindOperation(OpQuery, kind)
+	op.Query = qry.String()
+	op.Entities = dsts
+
+	err = b.intercept(op, func() error {
+		it, ret, err = b.backend.Query(kind, qry, dsts, multi)
+		op.Keys = importKeys(ret)
+		return err
+	})
+	return
+}
+
+func (b *interceptedBackend) AllocateIDs(kind *types.Kind, parent *types.Key, n int) (ret []*types.Key, err error) {
+	op := newKindOperation(OpAllocate, kind)
+
+	err = b.intercept(op, func() error {
+		ret, err = b.backend.AllocateIDs(kind, parent, n)
+		op.Keys = importKeys(ret)
+		return err
+	})
+	return
+}
+
+func (b *interceptedBackend) AllocateIDRange(kind *types.Kind, parent *types.Key, start, end int64) error {
+	op := newKindOperation(OpAllocate, kind)
+
+	return b.intercept(op, func() error {
+		return b.backend.AllocateIDRange(kind, parent, start, end)
+	})
+}
+
+func (b *interceptedBackend) Transact(ctx ae.Context, crossGroup bool, f func(ae.Context) error) error {
+	op := &Operation{Type: OpTransaction, Context: ctx}
+
+	return b.intercept(op, func() error {
+		return b.backend.Transact(ctx, crossGroup, f)
+	})
+}
+
+func newKindOperation(typ OpType, kind *types.Kind) *Operation {
+	return &Operation{Type: typ, Context: kind.Context, Kind: kind.Name, Namespace: kind.Namespace}
+}
@@ -0,0 +1,230 @@
+package hrd
+
+import (
+	"fmt"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/metrics"
+
+	ae "appengine"
+)
+
+var _ = Describe("Interceptor", func() {
+
+	var (
+		ops []*Operation
+	)
+
+	record := func(op *Operation, next func() error) error {
+		ops = append(ops, op)
+		return next()
+	}
+
+	newInterceptedKind := func(interceptors ...Interceptor) *Kind {
+		options := []StoreOption{withBackend(myBackend)}
+		for _, i := range interceptors {
+			options = append(options, WithInterceptor(i))
+		}
+		return NewStore(options...).Kind("my-kind")
+	}
+
+	BeforeEach(func() {
+		ops = nil
+	})
+
+	AfterEach(func() {
+		myBackend.get = nil
+		myBackend.put = nil
+		myBackend.delete = nil
+		myBackend.count = nil
+		myBackend.query = nil
+		myBackend.transact = nil
+	})
+
+	It("should run interceptors in the order they were added", func() {
+		var calls []string
+		interceptor := func(name string) Interceptor {
+			return func(op *Operation, next func() error) error {
+				calls = append(calls, name+" before")
+				err := next()
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+		myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+			calls = append(calls, "delete")
+			return nil
+		}
+
+		kind := newInterceptedKind(interceptor("a"), interceptor("b"))
+		err := kind.Delete(ctx).ID(42)
+		Check(err, IsNil)
+		Check(calls, Equals, []string{"a before", "b before", "delete", "b after", "a after"})
+	})
+
+	It("should intercept a get", func() {
+		myBackend.get = func(_ *types.Kind, keys []*types.Key, _ interface{}, _ *types.Opts, _ bool) ([]*types.Key, error) {
+			return keys, nil
+		}
+
+		var entity *MyModel
+		kind := newInterceptedKind(record).Namespace("my-ns")
+		_, err := kind.Load(ctx).NoGlobalCache().ID(42).GetOne(&entity)
+		Check(err, IsNil)
+
+		Check(ops, HasLen, 1)
+		Check(ops[0].Type, Equals, OpGet)
+		Check(ops[0].Kind, Equals, "my-kind")
+		Check(ops[0].Namespace, Equals, "my-ns")
+		Check(ops[0].Keys, Equals, []*Key{kind.NewNumKey(42)})
+		Check(ops[0].Entities, Equals, &entity)
+		Check(ops[0].Options, Equals, Options{NoGlobalCache: true})
+	})
+
+	It("should intercept a put", func() {
+		myBackend.put = func(_ *types.Kind, _ interface{}, _ *types.Opts) ([]*types.Key, error) {
+			return toInternalKeys(myKind.NewNumKeys(42)), nil
+		}
+
+		entity := &MyModel{}
+		_, err := newInterceptedKind(record).NoGlobalCache().Save(ctx).CompleteKeys().Entity(entity)
+		Check(err, IsNil)
+
+		Check(ops, HasLen, 1)
+		Check(ops[0].Type, Equals, OpPut)
+		Check(ops[0].Keys, Equals, myKind.NewNumKeys(42))
+		Check(ops[0].Entities, Equals, entity)
+		Check(ops[0].Options, Equals, Options{CompleteKeys: true, NoGlobalCache: true})
+	})
+
+	It("should intercept a query", func() {
+		myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+			return nil, toInternalKeys(myKind.NewNumKeys(1, 2)), nil
+		}
+
+		query := newInterceptedKind(record).Query(ctx).Limit(2)
+		keys, _, err := query.GetKeys()
+		Check(err, IsNil)
+		Check(keys, HasLen, 2)
+
+		Check(ops, HasLen, 1)
+		Check(ops[0].Type, Equals, OpQuery)
+		Check(ops[0].Kind, Equals, "my-kind")
+		Check(ops[0].Query, Contains, "LIMIT 2")
+		Check(ops[0].Keys, Equals, myKind.NewNumKeys(1, 2))
+	})
+
+	It("should intercept a count", func() {
+		myBackend.count = func(_ *types.Kind, _ *types.Query) (int, error) {
+			return 3, nil
+		}
+
+		_, err := newInterceptedKind(record).Query(ctx).GetCount()
+		Check(err, IsNil)
+
+		Check(ops, HasLen, 1)
+		Check(ops[0].Type, Equals, OpCount)
+		Check(ops[0].Kind, Equals, "my-kind")
+		Check(ops[0].Query, Contains, `KIND "my-kind"`)
+		Check(ops[0].Count, EqualsNum, 3)
+	})
+
+	It("should intercept a transaction", func() {
+		myBackend.transact = func(ctx ae.Context, _ bool, f func(ae.Context) error) error {
+			return f(ctx)
+		}
+
+		store := NewStore(withBackend(myBackend), WithInterceptor(record))
+		err := store.TX(ctx).Run(func(_ TX) error {
+			return nil
+		})
+		Check(err, IsNil)
+
+		Check(ops, HasLen, 1)
+		Check(ops[0].Type, Equals, OpTransaction)
+		Check(ops[0].Context, Equals, ctx)
+	})
+
+	It("should name operations like metrics and logs", func() {
+		Check(OpGet.String(), Equals, "get")
+		Check(metrics.Op(OpTransaction), Equals, metrics.OpTransaction)
+		Check(metrics.Op(OpAllocate), Equals, metrics.OpAllocate)
+	})
+
+	It("should allow to deny an operation", func() {
+		myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+			panic("unexpected call")
+		}
+
+		deny := func(op *Operation, next func() error) error {
+			return fmt.Errorf("%v denied", op.Type)
+		}
+
+		err := newInterceptedKind(deny).Delete(ctx).ID(42)
+		Check(err, ErrorContains, "delete denied")
+	})
+
+	It("should deny a query before it runs", func() {
+		myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+			panic("unexpected call")
+		}
+
+		deny := func(op *Operation, next func() error) error {
+			return fmt.Errorf("%v denied", op.Type)
+		}
+
+		_, _, err := newInterceptedKind(deny).Query(ctx).GetKeys()
+		Check(err, ErrorContains, "query denied")
+	})
+
+	It("should allow to retry an operation", func() {
+		calls := 0
+		myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+			calls++
+			if calls < 3 {
+				return fmt.Errorf("an error")
+			}
+			return nil
+		}
+
+		retry := func(op *Operation, next func() error) (err error) {
+			for i := 0; i < 3; i++ {
+				if err = next(); err == nil {
+					return
+				}
+			}
+			return
+		}
+
+		err := newInterceptedKind(retry).Delete(ctx).ID(42)
+		Check(err, IsNil)
+		Check(calls, EqualsNum, 3)
+	})
+
+	It("should retry a query by running it again", func() {
+		var queries []*types.Query
+		myBackend.query = func(_ *types.Kind, qry *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+			queries = append(queries, qry)
+			if len(queries) < 3 {
+				return nil, nil, fmt.Errorf("an error")
+			}
+			return nil, toInternalKeys(myKind.NewNumKeys(1, 2)), nil
+		}
+
+		retry := func(op *Operation, next func() error) (err error) {
+			for i := 0; i < 3; i++ {
+				if err = next(); err == nil {
+					return
+				}
+			}
+			return
+		}
+
+		keys, _, err := newInterceptedKind(retry).Query(ctx).GetKeys()
+		Check(err, IsNil)
+		Check(keys, Equals, myKind.NewNumKeys(1, 2))
+		Check(queries, HasLen, 3)
+		Check(queries[2], Equals, queries[0])
+	})
+})
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/metrics"
+
+	ds "appengine/datastore"
+)
+
+// AllocateIDs allocates n numeric IDs for the given kind and parent key,
+// which may be nil, and returns them as complete keys.
+func (b *Backend) AllocateIDs(kind *types.Kind, parent *types.Key, n int) ([]*types.Key, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of IDs %d for %q", n, kind.Name)
+	}
+
+	start := time.Now()
+	ctx := types.WithNamespace(b.context(kind), kind.Namespace)
+	low, high, err := b.ds.AllocateIDs(ctx, kind.Name, allocParent(kind, parent), n)
+	reportAction(kind, fmt.Sprintf("allocating %d IDs for %q", n, kind.Name),
+		metrics.Event{Op: metrics.OpAllocate, BatchSize: n, Err: err}, start, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*types.Key, 0, high-low)
+	for id := low; id < high; id++ {
+		key := types.NewKey(kind.Name, "", id, parent)
+		if parent == nil {
+			key.Namespace = kind.Namespace
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// AllocateIDRange allocates the numeric IDs from start to end, inclusive,
+// for the given kind and parent key, which may be nil.
+func (b *Backend) AllocateIDRange(kind *types.Kind, parent *types.Key, start, end int64) error {
+	if start <= 0 || end < start {
+		return fmt.Errorf("invalid ID range [%d, %d] for %q", start, end, kind.Name)
+	}
+
+	now := time.Now()
+	ctx := types.WithNamespace(b.context(kind), kind.Namespace)
+	err := b.ds.AllocateIDRange(ctx, kind.Name, allocParent(kind, parent), start, end)
+	reportAction(kind, fmt.Sprintf("allocating IDs %d to %d for %q", start, end, kind.Name),
+		metrics.Event{Op: metrics.OpAllocate, BatchSize: int(end - start + 1), Err: err}, now, nil)
+	return err
+}
+
+func allocParent(kind *types.Kind, parent *types.Key) *ds.Key {
+	if parent == nil {
+		return nil
+	}
+	return parent.ToDSKey(kind.Context)
+}
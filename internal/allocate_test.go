@@ -0,0 +1,47 @@
+package internal
+
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
+)
+
+var _ = Describe("Allocate", func() {
+
+	var (
+		kind *types.Kind
+	)
+
+	BeforeEach(func() {
+		kind = randomKind()
+	})
+
+	It("should allocate IDs", func() {
+		keys, err := backend.AllocateIDs(kind, nil, 3)
+		Check(err, IsNil)
+		Check(keys, HasLen, 3)
+		for _, key := range keys {
+			Check(key.Kind, Equals, kind.Name)
+			Check(key.Incomplete(), IsFalse)
+		}
+	})
+
+	It("should allocate IDs for a parent", func() {
+		parent := types.NewKey("parent", "abc", 0, nil)
+		keys, err := backend.AllocateIDs(kind, parent, 1)
+		Check(err, IsNil)
+		Check(keys[0].Parent, Equals, parent)
+	})
+
+	It("should allocate an ID range", func() {
+		err := backend.AllocateIDRange(kind, nil, 1000, 1999)
+		Check(err, IsNil)
+	})
+
+	It("should not allocate an invalid number of IDs", func() {
+		_, err := backend.AllocateIDs(kind, nil, 0)
+		Check(err, ErrorContains, "invalid number of IDs")
+
+		err = backend.AllocateIDRange(kind, nil, 10, 1)
+		Check(err, ErrorContains, "invalid ID range")
+	})
+})
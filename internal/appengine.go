@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+var (
+	dsGet = func(ctx ae.Context, keys []*ds.Key, dst interface{}) error {
+		return ds.GetMulti(ctx, keys, dst)
+	}
+
+	dsPut = func(ctx ae.Context, keys []*ds.Key, src interface{}) ([]*ds.Key, error) {
+		return ds.PutMulti(ctx, keys, src)
+	}
+
+	dsDel = func(ctx ae.Context, keys []*ds.Key) error {
+		return ds.DeleteMulti(ctx, keys)
+	}
+)
+
+// AppEngine is the App Engine datastore.
+type AppEngine struct{}
+
+var _ Datastore = AppEngine{}
+
+// Get loads the entities for the passed-in keys.
+func (AppEngine) Get(ctx ae.Context, keys []*ds.Key, dst []ds.PropertyLoadSaver) error {
+	return dsGet(ctx, keys, dst)
+}
+
+// Put saves the passed-in entities and returns their keys.
+func (AppEngine) Put(ctx ae.Context, keys []*ds.Key, src []ds.PropertyLoadSaver) ([]*ds.Key, error) {
+	return dsPut(ctx, keys, src)
+}
+
+// Delete deletes the entities for the passed-in keys.
+func (AppEngine) Delete(ctx ae.Context, keys []*ds.Key) error {
+	return dsDel(ctx, keys)
+}
+
+// Count returns the number of results for the passed-in query.
+func (AppEngine) Count(ctx ae.Context, qry *types.Query) (int, error) {
+	ctx = types.WithNamespace(ctx, qry.Namespace)
+	return qry.ToDSQuery(ctx).Count(ctx)
+}
+
+// Run executes the passed-in query and returns an iterator.
+func (AppEngine) Run(ctx ae.Context, qry *types.Query) types.Iterator {
+	return types.NewIterator(types.WithNamespace(ctx, qry.Namespace), qry)
+}
+
+// AllocateIDs allocates n numeric IDs for the passed-in kind and parent key.
+func (AppEngine) AllocateIDs(ctx ae.Context, kind string, parent *ds.Key, n int) (int64, int64, error) {
+	return ds.AllocateIDs(ctx, kind, parent, n)
+}
+
+// AllocateIDRange allocates the numeric IDs from start to end, inclusive,
+// for the passed-in kind and parent key.
+func (AppEngine) AllocateIDRange(ctx ae.Context, kind string, parent *ds.Key, start, end int64) error {
+	return ds.AllocateIDRange(ctx, kind, parent, start, end)
+}
+
+// RunInTransaction runs the passed-in function in a transaction.
+func (AppEngine) RunInTransaction(ctx ae.Context, f func(ae.Context) error, crossGroup bool) error {
+	return ds.RunInTransaction(ctx, f, &ds.TransactionOptions{XG: crossGroup})
+}
@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/gob"
+	"time"
+
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+const (
+	// cacheLockTime is how long a key is locked in the global cache
+	// after its entity was written.
+	cacheLockTime = 32 * time.Second
+
+	// cacheKeyPrefix is the prefix of all keys in the global cache.
+	cacheKeyPrefix = "hrd:"
+
+	// maxCacheKeyLen is the maximum length of a key in the global cache.
+	maxCacheKeyLen = 250
+)
+
+func init() {
+	gob.Register(time.Time{})
+	gob.Register(ae.BlobKey(""))
+	gob.Register(ae.GeoPoint{})
+	gob.Register(&ds.Key{})
+}
+
+// cacheEntry is a cached entity.
+type cacheEntry struct {
+	props  []ds.Property
+	exists bool
+}
+
+// load loads a cached entity into a PropertyLoadSaver.
+func (e *cacheEntry) load(dst ds.PropertyLoadSaver) error {
+	if !e.exists {
+		return ds.ErrNoSuchEntity
+	}
+
+	c := make(chan ds.Property, len(e.props))
+	for _, prop := range e.props {
+		if b, ok := prop.Value.([]byte); ok {
+			prop.Value = append([]byte(nil), b...)
+		}
+		c <- prop
+	}
+	close(c)
+	return dst.Load(c)
+}
+
+// propertyRecorder is a PropertyLoadSaver that
+// keeps a copy of the properties it loads or saves.
+type propertyRecorder struct {
+	ds.PropertyLoadSaver
+	props []ds.Property
+}
+
+func (r *propertyRecorder) Load(c <-chan ds.Property) error {
+	for prop := range c {
+		r.props = append(r.props, prop)
+	}
+	return (&cacheEntry{r.props, true}).load(r.PropertyLoadSaver)
+}
+
+func (r *propertyRecorder) Save(c chan<- ds.Property) error {
+	defer close(c)
+
+	props := make(chan ds.Property, 32)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.PropertyLoadSaver.Save(props)
+	}()
+	for prop := range props {
+		r.props = append(r.props, prop)
+		c <- prop
+	}
+	return <-errc
+}
+
+// loadThrough loads entities from their cache entries; a nil entry is a miss.
+// The missing entities are loaded by next and then passed to store.
+func loadThrough(keys []*ds.Key, dst []ds.PropertyLoadSaver, cached []*cacheEntry,
+	next func([]*ds.Key, []ds.PropertyLoadSaver) error, store func([]*ds.Key, []*cacheEntry)) error {
+
+	mErr := make(ae.MultiError, len(keys))
+
+	var missing []int
+	var missingKeys []*ds.Key
+	var recorders []ds.PropertyLoadSaver
+	for i, entry := range cached {
+		if entry != nil {
+			mErr[i] = entry.load(dst[i])
+			continue
+		}
+		missing = append(missing, i)
+		missingKeys = append(missingKeys, keys[i])
+		recorders = append(recorders, &propertyRecorder{PropertyLoadSaver: dst[i]})
+	}
+
+	if len(missing) > 0 {
+		err := next(missingKeys, recorders)
+		nextErr, isMulti := err.(ae.MultiError)
+		if err != nil && !isMulti {
+			return err
+		}
+
+		var storeKeys []*ds.Key
+		var storeEntries []*cacheEntry
+		for j, i := range missing {
+			if isMulti {
+				mErr[i] = nextErr[j]
+			}
+			switch mErr[i] {
+			case nil:
+				props := recorders[j].(*propertyRecorder).props
+				storeKeys = append(storeKeys, missingKeys[j])
+				storeEntries = append(storeEntries, &cacheEntry{props, true})
+			case ds.ErrNoSuchEntity:
+				storeKeys = append(storeKeys, missingKeys[j])
+				storeEntries = append(storeEntries, &cacheEntry{nil, false})
+			}
+		}
+		if len(storeKeys) > 0 {
+			store(storeKeys, storeEntries)
+		}
+	}
+
+	for _, err := range mErr {
+		if err != nil {
+			return mErr
+		}
+	}
+	return nil
+}
+
+// globalCache is a Cache of entities, shared by all contexts.
+// Its errors are logged, except when locking keys, since
+// a failure to do so could leave stale entities in the cache.
+type globalCache struct {
+	ctx   ae.Context
+	kind  *types.Kind
+	cache cache.Cache
+}
+
+// get returns the cached entities for the passed-in keys,
+// an entity is nil if it is not cached.
+func (gc *globalCache) get(keys []*ds.Key) []*cacheEntry {
+	ret := make([]*cacheEntry, len(keys))
+
+	values, err := gc.cache.Get(gc.ctx, cacheKeys(keys))
+	if err != nil {
+		gc.warn("failed to read from cache", nil, err)
+		return ret
+	}
+
+	for i, key := range keys {
+		if value, ok := values[cacheKey(key)]; ok {
+			var props []ds.Property
+			if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&props); err != nil {
+				gc.warn("failed to decode cached entity", key, err)
+				continue
+			}
+			ret[i] = &cacheEntry{props, true}
+		}
+	}
+	return ret
+}
+
+// set caches the passed-in entities. Missing entities are not cached.
+func (gc *globalCache) set(keys []*ds.Key, entries []*cacheEntry, expiration time.Duration) {
+	values := gc.encode(keys, entries)
+	if len(values) == 0 {
+		return
+	}
+	if err := gc.cache.Set(gc.ctx, values, expiration); err != nil {
+		gc.warn("failed to write to cache", nil, err)
+	}
+}
+
+// replace caches the passed-in entities in place of their current values
+// and locks, so it must only be called after they were written.
+func (gc *globalCache) replace(keys []*ds.Key, entries []*cacheEntry, expiration time.Duration) {
+	values := gc.encode(keys, entries)
+	if len(values) == 0 {
+		return
+	}
+	if err := gc.cache.Replace(gc.ctx, values, expiration); err != nil {
+		gc.warn("failed to write to cache", nil, err)
+	}
+}
+
+// encode returns the cache values of the passed-in entities,
+// leaving out missing ones and those that can not be encoded.
+func (gc *globalCache) encode(keys []*ds.Key, entries []*cacheEntry) map[string][]byte {
+	values := make(map[string][]byte, len(keys))
+	for i, key := range keys {
+		if !entries[i].exists {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(entries[i].props); err != nil {
+			gc.warn("failed to encode entity for cache", key, err)
+			continue
+		}
+		values[cacheKey(key)] = buf.Bytes()
+	}
+	return values
+}
+
+// lock prevents the passed-in keys from being cached while they are written.
+func (gc *globalCache) lock(keys []*ds.Key) error {
+	var lockKeys []string
+	for _, key := range keys {
+		if key != nil && !key.Incomplete() {
+			lockKeys = append(lockKeys, cacheKey(key))
+		}
+	}
+
+	if len(lockKeys) == 0 {
+		return nil
+	}
+	return gc.cache.Lock(gc.ctx, lockKeys, cacheLockTime)
+}
+
+// warn logs a failure of the cache, optionally for a single key.
+func (gc *globalCache) warn(msg string, key *ds.Key, err error) {
+	fields := logging.Fields{"kind": gc.kind.Name, "error": err}
+	if key != nil {
+		fields["key"] = key
+	}
+	gc.kind.Log(logging.Warning, msg, fields)
+}
+
+// cacheKey returns the key of an entity in the global cache.
+// Too long keys are replaced by their hash.
+func cacheKey(key *ds.Key) string {
+	ret := cacheKeyPrefix + key.Encode()
+	if len(ret) > maxCacheKeyLen {
+		hash := sha1.Sum([]byte(ret))
+		ret = cacheKeyPrefix + base64.URLEncoding.EncodeToString(hash[:])
+	}
+	return ret
+}
+
+func cacheKeys(keys []*ds.Key) []string {
+	ret := make([]string, len(keys))
+	for i, key := range keys {
+		ret[i] = cacheKey(key)
+	}
+	return ret
+}
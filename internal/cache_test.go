@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+var _ = Describe("Global Cache", func() {
+
+	var (
+		kind     *types.Kind
+		noCaches *types.Kind
+		lru      *cache.LRU
+		key      []*types.Key
+		opts     = &types.Opts{NoLocalCache: true}
+	)
+
+	load := func(kind *types.Kind, opts *types.Opts) string {
+		var entity *MyModel
+		_, err := backend.Get(kind, key, &entity, opts, false)
+		Check(err, IsNil)
+		if entity == nil {
+			return ""
+		}
+		return entity.Text
+	}
+
+	save := func(kind *types.Kind, text string) {
+		entity := &MyModel{Text: text}
+		entity.SetID(key[0].IntID)
+		_, err := backend.Put(kind, entity, &types.Opts{CompleteKeys: true})
+		Check(err, IsNil)
+	}
+
+	BeforeEach(func() {
+		lru = cache.NewLRU(10)
+		kind = randomKind()
+		kind.Cache = lru
+		noCaches = newKind(kind.Name)
+		noCaches.Cache = nil
+		key = []*types.Key{types.NewKey(kind.Name, "", 1, nil)}
+
+		save(noCaches, "cached")
+		Check(load(kind, opts), Equals, "cached")
+		Check(lru.Len(), EqualsNum, 1)
+	})
+
+	It("should load an entity from the global cache", func() {
+		save(noCaches, "changed")
+
+		Check(load(kind, opts), Equals, "cached")
+		Check(load(kind, noCache), Equals, "changed")
+	})
+
+	It("should lock an entity on save", func() {
+		save(kind, "changed")
+		Check(lru.Len(), EqualsNum, 1)
+
+		Check(load(kind, opts), Equals, "changed")
+		Check(load(kind, opts), Equals, "changed")
+		values, _ := lru.Get(ctx, cacheKeys(toDSKeys(ctx, key)))
+		Check(values, HasLen, 0)
+	})
+
+	It("should lock an entity on delete", func() {
+		err := backend.Delete(kind, key...)
+		Check(err, IsNil)
+
+		Check(load(kind, opts), Equals, "")
+	})
+
+	It("should not be used inside a transaction", func() {
+		save(noCaches, "changed")
+
+		backend.Transact(ctx, false, func(tx ae.Context) error {
+			txKind := newKind(kind.Name)
+			txKind.Context = tx
+			txKind.Cache = lru
+			Check(load(txKind, opts), Equals, "changed")
+			return nil
+		})
+	})
+
+	Context("with a cache policy", func() {
+
+		It("should cache an entity on save", func() {
+			kind.CachePolicy = cache.Policy{Mode: cache.WriteThrough}
+			save(kind, "changed")
+			save(noCaches, "stale")
+
+			Check(load(kind, opts), Equals, "changed")
+		})
+
+		It("should not cache an entity saved inside a transaction", func() {
+			kind.CachePolicy = cache.Policy{Mode: cache.WriteThrough}
+
+			err := backend.Transact(ctx, false, func(tx ae.Context) error {
+				txKind := newKind(kind.Name)
+				txKind.Context = tx
+				txKind.Cache = lru
+				txKind.CachePolicy = kind.CachePolicy
+				save(txKind, "rolled back")
+				return errors.New("an error")
+			})
+			Check(err, ErrorContains, "an error")
+
+			Check(load(kind, opts), Equals, "cached")
+		})
+
+		It("should not lock an entity on save", func() {
+			kind.CachePolicy = cache.Policy{Mode: cache.ReadOnly}
+			save(kind, "changed")
+
+			Check(load(kind, opts), Equals, "cached")
+			Check(load(kind, noCache), Equals, "changed")
+		})
+
+		It("should not cache an entity", func() {
+			kind.CachePolicy = cache.Policy{Mode: cache.Never}
+			save(noCaches, "changed")
+
+			Check(load(kind, opts), Equals, "changed")
+			Check(load(kind, &types.Opts{}), Equals, "changed")
+		})
+
+		It("should expire cached entities", func() {
+			kind.CachePolicy = cache.Policy{TTL: time.Nanosecond}
+			lru.Delete(ctx, cacheKeys(toDSKeys(ctx, key)))
+			Check(load(kind, opts), Equals, "cached")
+			time.Sleep(time.Millisecond)
+			save(noCaches, "changed")
+
+			Check(load(kind, opts), Equals, "changed")
+		})
+	})
+
+	It("should create keys of limited length", func() {
+		dsKey := ds.NewKey(ctx, kind.Name, "", 1, nil)
+		Check(cacheKey(dsKey), Equals, cacheKeyPrefix+dsKey.Encode())
+
+		long := make([]byte, maxCacheKeyLen)
+		for i := range long {
+			long[i] = 'x'
+		}
+		dsKey = ds.NewKey(ctx, kind.Name, string(long), 0, nil)
+		Check(len(cacheKey(dsKey)), IsLessThan, maxCacheKeyLen)
+	})
+})
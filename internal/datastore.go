@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+// Datastore performs the raw operations of a datastore.
+type Datastore interface {
+
+	// Get loads the entities for the passed-in keys.
+	Get(ctx ae.Context, keys []*ds.Key, dst []ds.PropertyLoadSaver) error
+
+	// Put saves the passed-in entities and returns their keys.
+	Put(ctx ae.Context, keys []*ds.Key, src []ds.PropertyLoadSaver) ([]*ds.Key, error)
+
+	// Delete deletes the entities for the passed-in keys.
+	Delete(ctx ae.Context, keys []*ds.Key) error
+
+	// Count returns the number of results for the passed-in query.
+	Count(ctx ae.Context, qry *types.Query) (int, error)
+
+	// Run executes the passed-in query and returns an iterator.
+	Run(ctx ae.Context, qry *types.Query) types.Iterator
+
+	// AllocateIDs allocates n numeric IDs for the passed-in kind and parent
+	// key, which may be nil. The range is inclusive at the low end and
+	// exclusive at the high end.
+	AllocateIDs(ctx ae.Context, kind string, parent *ds.Key, n int) (low, high int64, err error)
+
+	// AllocateIDRange allocates the numeric IDs from start to end, inclusive,
+	// for the passed-in kind and parent key, which may be nil.
+	// It returns an error if any of them may already be in use.
+	AllocateIDRange(ctx ae.Context, kind string, parent *ds.Key, start, end int64) error
+
+	// RunInTransaction runs the passed-in function in a transaction.
+	RunInTransaction(ctx ae.Context, f func(ae.Context) error, crossGroup bool) error
+}
+
+// Backend executes datastore operations on entities.
+// The raw operations are delegated to its Datastore. Loaded entities
+// are kept in a local cache per context and in the kind's global cache.
+type Backend struct {
+	ds    Datastore
+	local *localCache
+}
+
+// globalCache returns the global cache of the passed-in kind,
+// if it has one and its entities may be cached.
+func (b *Backend) globalCache(kind *types.Kind) *globalCache {
+	if kind.Cache == nil || !kind.CachePolicy.Enabled() {
+		return nil
+	}
+	return &globalCache{b.context(kind), kind, kind.Cache}
+}
+
+// context returns the context to make the service calls of an operation
+// on the passed-in kind with, it notifies the kind's listener, if any.
+// Inside a transaction, the calls are observed by the context
+// the transaction was started with instead.
+func (b *Backend) context(kind *types.Kind) ae.Context {
+	if kind.Listener == nil || b.local.inTransaction(kind.Context) {
+		return kind.Context
+	}
+	return types.ListenContext(kind.Context, kind.Listener)
+}
+
+// NewBackend creates a new Backend for the passed-in Datastore.
+func NewBackend(ds Datastore) *Backend {
+	return &Backend{ds, newLocalCache()}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/memory"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/rpc"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+var _ = Describe("Backend", func() {
+
+	var (
+		backend *Backend
+		kind    *types.Kind
+		counter *rpc.Counter
+	)
+
+	BeforeEach(func() {
+		backend = NewBackend(&callingDatastore{memory.NewDatastore()})
+		counter = rpc.NewCounter()
+		kind = randomKind()
+		kind.Listener = counter
+	})
+
+	It("should notify the kind's listener of service calls", func() {
+		entity := &MyModel{}
+		entity.SetID(1)
+		keys, err := backend.Put(kind, entity, &types.Opts{CompleteKeys: true})
+		Check(err, IsNil)
+
+		var loaded *MyModel
+		_, err = backend.Get(kind, keys, &loaded, &types.Opts{NoLocalCache: true}, false)
+		Check(err, IsNil)
+
+		calls := counter.Calls()
+		Check(calls["datastore_v3.Put"], EqualsNum, 1)
+		Check(calls["datastore_v3.Get"], EqualsNum, 1)
+		Check(calls["memcache.Set"], EqualsNum, 2)
+		Check(calls["memcache.Get"], EqualsNum, 1)
+	})
+
+	It("should notify a listener once inside a transaction", func() {
+		err := backend.Transact(types.ListenContext(ctx, counter), false, func(tx ae.Context) error {
+			txKind := newKind(kind.Name)
+			txKind.Context = tx
+			txKind.Listener = counter
+
+			entity := &MyModel{}
+			entity.SetID(1)
+			_, err := backend.Put(txKind, entity, &types.Opts{CompleteKeys: true})
+			return err
+		})
+		Check(err, IsNil)
+
+		calls := counter.Calls()
+		Check(calls["datastore_v3.Put"], EqualsNum, 1)
+		Check(calls["datastore_v3.Commit"], EqualsNum, 1)
+	})
+})
+
+// callingDatastore is a Datastore that makes a datastore service call
+// for each operation, so that they can be observed by a listener.
+// The calls fail since the in-memory context has no datastore service.
+type callingDatastore struct {
+	*memory.Datastore
+}
+
+func (d *callingDatastore) Get(ctx ae.Context, keys []*ds.Key, dst []ds.PropertyLoadSaver) error {
+	ctx.Call("datastore_v3", "Get", nil, nil, nil)
+	return d.Datastore.Get(ctx, keys, dst)
+}
+
+func (d *callingDatastore) Put(ctx ae.Context, keys []*ds.Key, src []ds.PropertyLoadSaver) ([]*ds.Key, error) {
+	ctx.Call("datastore_v3", "Put", nil, nil, nil)
+	return d.Datastore.Put(ctx, keys, src)
+}
+
+func (d *callingDatastore) RunInTransaction(ctx ae.Context, f func(ae.Context) error, crossGroup bool) error {
+	return d.Datastore.RunInTransaction(ctx, func(tx ae.Context) error {
+		if err := f(tx); err != nil {
+			return err
+		}
+		tx.Call("datastore_v3", "Commit", nil, nil, nil)
+		return nil
+	}, crossGroup)
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/101loops/hrd/internal/trafo"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
+)
+
+// DryRunPut validates and encodes the given entities like Put,
+// but only logs them instead of saving them.
+// It returns the keys of the entities, which may be incomplete.
+// Generated IDs are part of the keys, but not assigned to the entities.
+func DryRunPut(kind *types.Kind, src interface{}, completeKeys bool) ([]*types.Key, error) {
+	docList, err := trafo.NewReadableDocList(kind, src)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := docList.Keys()
+	if kind.IDStrategy != nil {
+		if keys, err = docList.GeneratedTextIDKeys(newIDFunc(kind)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validatePutKeys(kind, keys, completeKeys); err != nil {
+		return nil, err
+	}
+
+	props := 0
+	for _, doc := range docList.Pipe(kind.Context).Docs {
+		docProps, err := doc.Save(kind.Context)
+		if err != nil {
+			return nil, err
+		}
+		props += len(docProps)
+	}
+
+	kind.Log(logging.Info, "dry run: "+LogDatastoreAction("putting", "in", keys, kind.Name),
+		logging.Fields{"op": string(metrics.OpPut), "kind": kind.Name, "keys": len(keys), "properties": props})
+	return keys, nil
+}
+
+// DryRunAllocateIDs only logs the allocation of n numeric IDs for the given
+// kind and parent key, which may be nil. It returns n incomplete keys.
+func DryRunAllocateIDs(kind *types.Kind, parent *types.Key, n int) ([]*types.Key, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of IDs %d for %q", n, kind.Name)
+	}
+
+	kind.Log(logging.Info, fmt.Sprintf("dry run: allocating %d IDs for %q", n, kind.Name),
+		logging.Fields{"op": string(metrics.OpAllocate), "kind": kind.Name, "keys": n})
+
+	keys := make([]*types.Key, n)
+	for i := range keys {
+		keys[i] = types.NewKey(kind.Name, "", 0, parent)
+		if parent == nil {
+			keys[i].Namespace = kind.Namespace
+		}
+	}
+	return keys, nil
+}
+
+// DryRunAllocateIDRange only logs the allocation of the numeric IDs
+// from start to end, inclusive, for the given kind and parent key.
+func DryRunAllocateIDRange(kind *types.Kind, parent *types.Key, start, end int64) error {
+	if start <= 0 || end < start {
+		return fmt.Errorf("invalid ID range [%d, %d] for %q", start, end, kind.Name)
+	}
+
+	kind.Log(logging.Info, fmt.Sprintf("dry run: allocating IDs %d to %d for %q", start, end, kind.Name),
+		logging.Fields{"op": string(metrics.OpAllocate), "kind": kind.Name, "keys": int(end - start + 1)})
+	return nil
+}
+
+// DryRunDelete only logs the deletion of the entities for the given keys.
+func DryRunDelete(kind *types.Kind, keys ...*types.Key) error {
+	kind.Log(logging.Info, "dry run: "+LogDatastoreAction("deleting", "from", keys, kind.Name),
+		logging.Fields{"op": string(metrics.OpDelete), "kind": kind.Name, "keys": len(keys)})
+	return nil
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+
+	ae "appengine"
+)
+
+var _ = Describe("Dry Run", func() {
+
+	var (
+		kind *types.Kind
+		msgs []string
+	)
+
+	BeforeEach(func() {
+		msgs = nil
+		kind = randomKind()
+		kind.Logger = logFunc(func(_ ae.Context, _ logging.Level, msg string, _ logging.Fields) {
+			msgs = append(msgs, msg)
+		})
+	})
+
+	It("should encode and log entities without saving them", func() {
+		entity := &MyModel{}
+		entity.SetID(42)
+
+		keys, err := DryRunPut(kind, entity, true)
+		Check(err, IsNil)
+		Check(keys, HasLen, 1)
+		Check(keys[0].IntID, EqualsNum, 42)
+		Check(entity.lifecycle, Equals, []string{"before-save", "after-save"})
+		Check(msgs, Equals, []string{"dry run: putting " + keys[0].String()})
+
+		var loaded *MyModel
+		_, err = backend.Get(kind, keys, &loaded, noCache, false)
+		Check(err, IsNil)
+		Check(loaded, IsNil)
+	})
+
+	It("should generate text IDs without assigning them to the entities", func() {
+		kind.IDStrategy = idFunc(func() (string, error) {
+			return "generated", nil
+		})
+
+		entities := []*MyTextModel{{}, {}}
+		entities[1].SetID("fixed")
+		keys, err := DryRunPut(kind, entities, true)
+		Check(err, IsNil)
+		Check(keys, HasLen, 2)
+
+		Check(keys[0].StringID, Equals, "generated")
+		Check(entities[0].ID(), Equals, "")
+		Check(keys[1].StringID, Equals, "fixed")
+		Check(entities[1].ID(), Equals, "fixed")
+	})
+
+	It("should validate entities", func() {
+		_, err := DryRunPut(kind, &MyModel{}, true)
+		Check(err, ErrorContains, "is incomplete")
+
+		_, err = DryRunPut(kind, []*MyModel{}, false)
+		Check(err, ErrorContains, "no keys provided")
+
+		Check(msgs, HasLen, 0)
+	})
+
+	It("should log deletions", func() {
+		key := types.NewKey(kind.Name, "", 42, nil)
+		err := DryRunDelete(kind, key)
+		Check(err, IsNil)
+		Check(msgs, Equals, []string{"dry run: deleting " + key.String()})
+	})
+})
+
+type logFunc func(ctx ae.Context, level logging.Level, msg string, fields logging.Fields)
+
+func (f logFunc) Log(ctx ae.Context, level logging.Level, msg string, fields logging.Fields) {
+	f(ctx, level, msg, fields)
+}
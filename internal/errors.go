@@ -3,11 +3,12 @@ package internal
 import (
 	"fmt"
 
-	ae "appengine"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
 )
 
-func logErr(ctx ae.Context, e interface{}) error {
+func logErr(kind *types.Kind, e interface{}) error {
 	err := fmt.Errorf("%v", e)
-	ctx.Errorf("%v", err)
+	kind.Log(logging.Error, err.Error(), logging.Fields{"kind": kind.Name})
 	return err
 }
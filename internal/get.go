@@ -2,50 +2,105 @@ package internal
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/101loops/hrd/internal/trafo"
 	"github.com/101loops/hrd/internal/types"
-	"github.com/qedus/nds"
+	"github.com/101loops/hrd/metrics"
 
-	ae "appengine"
 	ds "appengine/datastore"
 )
 
-var (
-	dsGet = func(ctx ae.Context, keys []*ds.Key, dst interface{}) error {
-		return ds.GetMulti(ctx, keys, dst)
-	}
-
-	ndsGet = func(ctx ae.Context, keys []*ds.Key, dst interface{}) error {
-		return nds.GetMulti(ctx, keys, dst)
-	}
-)
-
 // Get loads entities for the given keys.
-func Get(kind *types.Kind, keys []*types.Key, dst interface{}, useGlobalCache bool, multi bool) ([]*types.Key, error) {
+func (b *Backend) Get(kind *types.Kind, keys []*types.Key, dst interface{}, opts *types.Opts, multi bool) ([]*types.Key, error) {
 	if err := validateGetKeys(kind, keys); err != nil {
 		return nil, err
 	}
 
 	ctx := kind.Context
-	ctx.Infof(LogDatastoreAction("getting", "from", keys, kind.Name))
+	start := time.Now()
 
-	docList, err := trafo.NewWriteableDocList(dst, keys, multi)
+	docList, err := trafo.NewWriteableDocList(kind, dst, keys, multi)
 	if err != nil {
 		return nil, err
 	}
 	docsPipe := docList.Pipe(ctx)
 
-	var dsErr error
 	dsKeys := toDSKeys(ctx, keys)
-	if useGlobalCache {
-		dsErr = ndsGet(ctx, dsKeys, docsPipe.Properties())
-	}
-	dsErr = dsGet(ctx, dsKeys, docsPipe.Properties())
+	stats, dsErr := b.get(kind, dsKeys, docsPipe.Properties(), opts)
+
+	reportAction(kind, LogDatastoreAction("getting", "from", keys, kind.Name), metrics.Event{
+		Op: metrics.OpGet, BatchSize: len(keys), CacheHits: stats.hits, CacheMisses: stats.misses, Err: dsErr,
+	}, start, nil)
 
 	return docList.ApplyResult(dsKeys, dsErr)
 }
 
+// cacheStats counts the entities that were looked up in a cache.
+type cacheStats struct {
+	// hits is the number of entities loaded from a cache.
+	hits int
+
+	// misses is the number of entities loaded from the datastore instead.
+	misses int
+}
+
+// get loads the entities for the passed-in keys from the local cache,
+// unless it is disabled for the action or kind, and the missing ones
+// via getGlobal.
+func (b *Backend) get(kind *types.Kind, keys []*ds.Key, dst []ds.PropertyLoadSaver, opts *types.Opts) (cacheStats, error) {
+	var global cacheStats
+	next := func(keys []*ds.Key, dst []ds.PropertyLoadSaver) (err error) {
+		global, err = b.getGlobal(kind, keys, dst, opts)
+		return
+	}
+	if opts.NoLocalCache || !kind.CachePolicy.Enabled() {
+		err := next(keys, dst)
+		return global, err
+	}
+
+	ctx := kind.Context
+	cached := b.local.get(ctx, keys)
+	err := loadThrough(keys, dst, cached, next, func(keys []*ds.Key, entries []*cacheEntry) {
+		b.local.set(ctx, keys, entries)
+	})
+
+	hits := countHits(cached) + global.hits
+	return cacheStats{hits, len(keys) - hits}, err
+}
+
+// getGlobal loads the entities for the passed-in keys from the global cache,
+// unless it is disabled or inside a transaction, and the missing ones
+// from the datastore.
+func (b *Backend) getGlobal(kind *types.Kind, keys []*ds.Key, dst []ds.PropertyLoadSaver, opts *types.Opts) (cacheStats, error) {
+	ctx := b.context(kind)
+	gc := b.globalCache(kind)
+	if gc == nil || opts.NoGlobalCache || b.local.inTransaction(kind.Context) {
+		return cacheStats{}, b.ds.Get(ctx, keys, dst)
+	}
+
+	next := func(keys []*ds.Key, dst []ds.PropertyLoadSaver) error {
+		return b.ds.Get(ctx, keys, dst)
+	}
+	cached := gc.get(keys)
+	err := loadThrough(keys, dst, cached, next, func(keys []*ds.Key, entries []*cacheEntry) {
+		gc.set(keys, entries, kind.CachePolicy.TTL)
+	})
+
+	hits := countHits(cached)
+	return cacheStats{hits, len(keys) - hits}, err
+}
+
+func countHits(cached []*cacheEntry) int {
+	var hits int
+	for _, entry := range cached {
+		if entry != nil {
+			hits++
+		}
+	}
+	return hits
+}
+
 func validateGetKeys(kind *types.Kind, keys []*types.Key) error {
 	if keys == nil || len(keys) == 0 {
 		return fmt.Errorf("no keys provided")
@@ -61,7 +116,7 @@ func validateGetKeys(kind *types.Kind, keys []*types.Key) error {
 		keyKind := k.Kind
 		if keyKind != kind.Name {
 			err := fmt.Errorf("invalid key kind '%v' for kind '%v'", keyKind, kind.Name)
-			return logErr(kind.Context, err)
+			return logErr(kind, err)
 		}
 	}
 
@@ -22,6 +22,7 @@ func dsLoadTests(useGlobalCache bool) {
 
 	var (
 		kind *types.Kind
+		opts = &types.Opts{NoGlobalCache: !useGlobalCache, NoLocalCache: true}
 	)
 
 	BeforeEach(func() {
@@ -33,7 +34,7 @@ func dsLoadTests(useGlobalCache bool) {
 			entity.SetID(i)
 			entities[i-1] = entity
 		}
-		keys, err := Put(kind, entities, true)
+		keys, err := backend.Put(kind, entities, &types.Opts{CompleteKeys: true})
 		Check(err, IsNil)
 		Check(keys, HasLen, 4)
 
@@ -43,7 +44,7 @@ func dsLoadTests(useGlobalCache bool) {
 	It("should load an entity", func() {
 		var entity *MyModel
 		dsKey := ds.NewKey(ctx, kind.Name, "", 1, nil)
-		keys, err := Get(kind, types.ImportKeys(dsKey), &entity, useGlobalCache, false)
+		keys, err := backend.Get(kind, types.ImportKeys(dsKey), &entity, opts, false)
 
 		Check(err, IsNil)
 		Check(keys, HasLen, 1)
@@ -59,7 +60,7 @@ func dsLoadTests(useGlobalCache bool) {
 			ds.NewKey(ctx, kind.Name, "", 2, nil),
 			ds.NewKey(ctx, kind.Name, "", 666, nil),
 		}
-		keys, err := Get(kind, types.ImportKeys(dsKeys...), &entities, useGlobalCache, true)
+		keys, err := backend.Get(kind, types.ImportKeys(dsKeys...), &entities, opts, true)
 
 		Check(err, IsNil)
 		Check(keys, HasLen, 3)
@@ -85,7 +86,7 @@ func dsLoadTests(useGlobalCache bool) {
 			ds.NewKey(ctx, kind.Name, "", 2, nil),
 			ds.NewKey(ctx, kind.Name, "", 666, nil),
 		}
-		keys, err := Get(kind, types.ImportKeys(dsKeys...), &entities, useGlobalCache, true)
+		keys, err := backend.Get(kind, types.ImportKeys(dsKeys...), &entities, opts, true)
 
 		Check(err, IsNil)
 		Check(keys, HasLen, 3)
@@ -99,7 +100,7 @@ func dsLoadTests(useGlobalCache bool) {
 			ds.NewKey(ctx, kind.Name, "", 2, nil),
 			ds.NewKey(ctx, kind.Name, "", 666, nil),
 		}
-		keys, err := Get(kind, types.ImportKeys(dsKeys...), &entities, useGlobalCache, true)
+		keys, err := backend.Get(kind, types.ImportKeys(dsKeys...), &entities, opts, true)
 
 		Check(err, IsNil)
 		Check(keys, HasLen, 3)
@@ -112,7 +113,7 @@ func dsLoadTests(useGlobalCache bool) {
 
 		It("should not save nil entity", func() {
 			dsKey := ds.NewKey(ctx, kind.Name, "", 1, nil)
-			keys, err := Get(kind, types.ImportKeys(dsKey), nil, useGlobalCache, false)
+			keys, err := backend.Get(kind, types.ImportKeys(dsKey), nil, opts, false)
 
 			Check(keys, IsNil)
 			Check(err, ErrorContains, `invalid value kind "invalid" (wanted non-nil pointer)`)
@@ -126,7 +127,7 @@ func dsLoadTests(useGlobalCache bool) {
 		It("should not accept key for different Kind", func() {
 			var entity *MyModel
 			invalidKey := ds.NewKey(ctx, "wrong-kind", "", 1, nil)
-			keys, err := Get(kind, types.ImportKeys(invalidKey), &entity, useGlobalCache, false)
+			keys, err := backend.Get(kind, types.ImportKeys(invalidKey), &entity, opts, false)
 
 			Check(keys, IsNil)
 			Check(entity, IsNil)
@@ -135,7 +136,7 @@ func dsLoadTests(useGlobalCache bool) {
 
 		It("should not load empty keys", func() {
 			var entities []*MyModel
-			keys, err := Get(kind, nil, &entities, useGlobalCache, false)
+			keys, err := backend.Get(kind, nil, &entities, opts, false)
 
 			Check(keys, IsNil)
 			Check(err, ErrorContains, "no keys provided")
@@ -144,7 +145,7 @@ func dsLoadTests(useGlobalCache bool) {
 		It("should not load incomplete key", func() {
 			var entity *MyModel
 			incompleteKey := ds.NewKey(ctx, kind.Name, "", 0, nil)
-			keys, err := Get(kind, types.ImportKeys(incompleteKey), &entity, useGlobalCache, false)
+			keys, err := backend.Get(kind, types.ImportKeys(incompleteKey), &entity, opts, false)
 
 			Check(keys, IsNil)
 			Check(err, ErrorContains, "is incomplete")
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"container/list"
+	"sync"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+const (
+	// maxLocalCaches is the maximum number of contexts with a local cache.
+	// Since the end of a request can not be observed, the cache of the
+	// oldest context is dropped once there are more.
+	maxLocalCaches = 128
+
+	// maxLocalEntries is the maximum number of entities cached per context.
+	// The oldest ones are dropped once there are more.
+	maxLocalEntries = 1000
+)
+
+// localCache keeps loaded entities in memory, separately for each context
+// and up to maxLocalEntries per context.
+// Inside a transaction it is bypassed; the keys written by a transaction
+// are invalidated in its parent context when the transaction ends.
+type localCache struct {
+	mu sync.Mutex
+
+	// entries contains the cached entities by context.
+	entries map[ae.Context]*contextCache
+
+	// contexts contains the contexts with a cache, oldest first.
+	contexts []ae.Context
+
+	// txs contains the running transactions by context.
+	txs map[ae.Context]*localTx
+}
+
+// contextCache contains the cached entities of a context by key.
+type contextCache struct {
+	elems map[string]*list.Element
+
+	// order contains the cached entities, oldest first.
+	order *list.List
+}
+
+// localEntry is a cached entity in the order of a contextCache.
+type localEntry struct {
+	key   string
+	entry *cacheEntry
+}
+
+func newContextCache() *contextCache {
+	return &contextCache{elems: make(map[string]*list.Element), order: list.New()}
+}
+
+func (cc *contextCache) get(key string) *cacheEntry {
+	if elem := cc.elems[key]; elem != nil {
+		return elem.Value.(*localEntry).entry
+	}
+	return nil
+}
+
+// set caches an entity, dropping the oldest one if there are too many.
+func (cc *contextCache) set(key string, entry *cacheEntry) {
+	cc.remove(key)
+	cc.elems[key] = cc.order.PushBack(&localEntry{key, entry})
+	if cc.order.Len() > maxLocalEntries {
+		cc.remove(cc.order.Front().Value.(*localEntry).key)
+	}
+}
+
+func (cc *contextCache) remove(key string) {
+	if elem := cc.elems[key]; elem != nil {
+		cc.order.Remove(elem)
+		delete(cc.elems, key)
+	}
+}
+
+// localTx tracks the keys written inside a transaction.
+type localTx struct {
+	parent ae.Context
+	keys   []*ds.Key
+}
+
+func newLocalCache() *localCache {
+	return &localCache{
+		entries: make(map[ae.Context]*contextCache),
+		txs:     make(map[ae.Context]*localTx),
+	}
+}
+
+// get returns the cached entities for the passed-in keys,
+// an entity is nil if it is not cached.
+func (lc *localCache) get(ctx ae.Context, keys []*ds.Key) []*cacheEntry {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	ret := make([]*cacheEntry, len(keys))
+	if cached := lc.entries[ctx]; cached != nil {
+		for i, key := range keys {
+			ret[i] = cached.get(key.Encode())
+		}
+	}
+	return ret
+}
+
+// inTransaction returns whether the context belongs to a transaction.
+func (lc *localCache) inTransaction(ctx ae.Context) bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	return lc.txs[ctx] != nil
+}
+
+// set caches entities, unless the context belongs to a transaction.
+func (lc *localCache) set(ctx ae.Context, keys []*ds.Key, entries []*cacheEntry) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.txs[ctx] != nil {
+		return
+	}
+
+	cached := lc.entries[ctx]
+	if cached == nil {
+		cached = newContextCache()
+		lc.entries[ctx] = cached
+		lc.contexts = append(lc.contexts, ctx)
+		if len(lc.contexts) > maxLocalCaches {
+			delete(lc.entries, lc.contexts[0])
+			lc.contexts = lc.contexts[1:]
+		}
+	}
+	for i, key := range keys {
+		cached.set(key.Encode(), entries[i])
+	}
+}
+
+// invalidate removes the passed-in keys from the cache. Inside a transaction,
+// they are removed from the parent context and remembered by the transaction.
+func (lc *localCache) invalidate(ctx ae.Context, keys []*ds.Key) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for tx := lc.txs[ctx]; tx != nil; tx = lc.txs[ctx] {
+		tx.keys = append(tx.keys, keys...)
+		ctx = tx.parent
+	}
+
+	if cached := lc.entries[ctx]; cached != nil {
+		for _, key := range keys {
+			if key != nil && !key.Incomplete() {
+				cached.remove(key.Encode())
+			}
+		}
+	}
+}
+
+// transaction wraps the function of a transaction started in the passed-in
+// context. The returned function must be called when the transaction ended.
+func (lc *localCache) transaction(ctx ae.Context, f func(ae.Context) error) (func(ae.Context) error, func()) {
+	tx := &localTx{parent: ctx}
+
+	run := func(txCtx ae.Context) error {
+		lc.mu.Lock()
+		lc.txs[txCtx] = tx
+		lc.mu.Unlock()
+
+		defer func() {
+			lc.mu.Lock()
+			delete(lc.txs, txCtx)
+			lc.mu.Unlock()
+		}()
+
+		return f(txCtx)
+	}
+
+	done := func() {
+		lc.invalidate(tx.parent, tx.keys)
+	}
+
+	return run, done
+}
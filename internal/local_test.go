@@ -0,0 +1,143 @@
+package internal
+
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+var _ = Describe("Local Cache", func() {
+
+	var (
+		kind  *types.Kind
+		key   []*types.Key
+		other *Backend
+		opts  = &types.Opts{NoGlobalCache: true}
+	)
+
+	// load returns the entity's text as seen by the passed-in backend.
+	load := func(b *Backend, opts *types.Opts) string {
+		var entity *MyModel
+		_, err := b.Get(kind, key, &entity, opts, false)
+		Check(err, IsNil)
+		if entity == nil {
+			return ""
+		}
+		return entity.Text
+	}
+
+	// save updates the entity using the passed-in backend.
+	save := func(b *Backend, text string) {
+		entity := &MyModel{Text: text}
+		entity.SetID(key[0].IntID)
+		_, err := b.Put(kind, entity, &types.Opts{CompleteKeys: true})
+		Check(err, IsNil)
+	}
+
+	BeforeEach(func() {
+		kind = randomKind()
+		key = []*types.Key{types.NewKey(kind.Name, "", 1, nil)}
+
+		// other backend shares the datastore, but not the local cache
+		other = NewBackend(datastore)
+
+		save(backend, "cached")
+		Check(load(backend, opts), Equals, "cached")
+	})
+
+	It("should load an entity from the local cache", func() {
+		save(other, "changed")
+
+		Check(load(backend, opts), Equals, "cached")
+		Check(load(backend, noCache), Equals, "changed")
+	})
+
+	It("should invalidate an entity on save", func() {
+		save(backend, "changed")
+
+		Check(load(backend, opts), Equals, "changed")
+	})
+
+	It("should invalidate an entity on delete", func() {
+		err := backend.Delete(kind, key...)
+		Check(err, IsNil)
+
+		Check(load(backend, opts), Equals, "")
+	})
+
+	It("should cache a missing entity", func() {
+		err := backend.Delete(kind, key...)
+		Check(err, IsNil)
+		Check(load(backend, opts), Equals, "")
+
+		save(other, "changed")
+		Check(load(backend, opts), Equals, "")
+	})
+
+	It("should invalidate an entity written inside a transaction", func() {
+		err := backend.Transact(ctx, false, func(tx ae.Context) error {
+			txKind := types.NewKind(tx, kind.Name)
+			txKind.Codecs = codecs
+
+			entity := &MyModel{Text: "changed"}
+			entity.SetID(key[0].IntID)
+			_, err := backend.Put(txKind, entity, &types.Opts{CompleteKeys: true})
+			return err
+		})
+		Check(err, IsNil)
+
+		Check(load(backend, opts), Equals, "changed")
+	})
+
+	It("should keep a separate cache per context", func() {
+		save(other, "changed")
+
+		kind = types.NewKind(&otherContext{ctx}, kind.Name)
+		kind.Codecs = codecs
+		Check(load(backend, opts), Equals, "changed")
+	})
+
+	It("should drop the cache of the oldest context", func() {
+		lc := newLocalCache()
+		dsKey := key[0].ToDSKey(ctx)
+
+		contexts := make([]ae.Context, maxLocalCaches+1)
+		for i := range contexts {
+			contexts[i] = &otherContext{ctx}
+			lc.set(contexts[i], []*ds.Key{dsKey}, []*cacheEntry{{}})
+		}
+
+		Check(lc.entries, HasLen, maxLocalCaches)
+		Check(lc.get(contexts[0], []*ds.Key{dsKey})[0], IsNil)
+		Check(lc.get(contexts[1], []*ds.Key{dsKey})[0], NotNil)
+	})
+
+	It("should drop the oldest entities of a context", func() {
+		lc := newLocalCache()
+
+		dsKeys := make([]*ds.Key, maxLocalEntries+2)
+		entries := make([]*cacheEntry, len(dsKeys))
+		for i := range dsKeys {
+			dsKeys[i] = ds.NewKey(ctx, kind.Name, "", int64(i+1), nil)
+			entries[i] = &cacheEntry{}
+		}
+		lc.set(ctx, dsKeys[:maxLocalEntries], entries[:maxLocalEntries])
+		lc.set(ctx, dsKeys[:1], entries[:1]) // refreshes the oldest entity
+		lc.set(ctx, dsKeys[maxLocalEntries:], entries[maxLocalEntries:])
+
+		cached := lc.get(ctx, dsKeys)
+		Check(lc.entries[ctx].order.Len(), EqualsNum, maxLocalEntries)
+		Check(cached[0], NotNil)
+		Check(cached[1], IsNil)
+		Check(cached[2], IsNil)
+		Check(cached[3], NotNil)
+		Check(cached[maxLocalEntries+1], NotNil)
+	})
+})
+
+// otherContext is a context that is distinct from the one it wraps.
+type otherContext struct {
+	ae.Context
+}
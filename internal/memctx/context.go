@@ -0,0 +1,45 @@
+// Package memctx provides an App Engine context that does not require the
+// SDK's development server, for tests. Its memcache keeps all items in memory.
+package memctx
+
+import (
+	"fmt"
+
+	ae "appengine"
+	"appengine_internal"
+)
+
+const appID = "hrdtest"
+
+// context is an App Engine context that does not require the SDK's
+// development server. Its only App Engine service is memcache.
+type context struct {
+	memcache *memcacheService
+}
+
+// New returns a new App Engine context with its own, empty memcache.
+// Log messages are discarded and any call to another App Engine service fails.
+func New() ae.Context {
+	return &context{newMemcache()}
+}
+
+func (c *context) Debugf(format string, args ...interface{})    {}
+func (c *context) Infof(format string, args ...interface{})     {}
+func (c *context) Warningf(format string, args ...interface{})  {}
+func (c *context) Errorf(format string, args ...interface{})    {}
+func (c *context) Criticalf(format string, args ...interface{}) {}
+
+func (c *context) Call(service, method string, in, out appengine_internal.ProtoMessage, _ *appengine_internal.CallOptions) error {
+	if service == "memcache" {
+		return c.memcache.call(method, in, out)
+	}
+	return fmt.Errorf("service '%v.%v' is not available in memory", service, method)
+}
+
+func (c *context) FullyQualifiedAppID() string {
+	return appID
+}
+
+func (c *context) Request() interface{} {
+	return nil
+}
@@ -0,0 +1,142 @@
+package memctx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"appengine_internal"
+	pb "appengine_internal/memcache"
+)
+
+// maxRelativeExpiration is the largest expiration in seconds that is
+// relative to the current time, larger ones are Unix timestamps.
+const maxRelativeExpiration = 30 * 24 * 60 * 60
+
+// memcacheService is a memcache service that keeps all items in memory.
+type memcacheService struct {
+	mu    sync.Mutex
+	items map[string]*memcacheItem
+	casID uint64
+}
+
+// memcacheItem is a stored memcache item.
+type memcacheItem struct {
+	value   []byte
+	flags   uint32
+	casID   uint64
+	expires time.Time
+}
+
+func newMemcache() *memcacheService {
+	return &memcacheService{items: make(map[string]*memcacheItem)}
+}
+
+// call executes a call to the memcache service.
+func (m *memcacheService) call(method string, in, out appengine_internal.ProtoMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch method {
+	case "Get":
+		m.get(in.(*pb.MemcacheGetRequest), out.(*pb.MemcacheGetResponse))
+	case "Set":
+		m.set(in.(*pb.MemcacheSetRequest), out.(*pb.MemcacheSetResponse))
+	case "Delete":
+		m.delete(in.(*pb.MemcacheDeleteRequest), out.(*pb.MemcacheDeleteResponse))
+	case "FlushAll":
+		m.items = make(map[string]*memcacheItem)
+	default:
+		return fmt.Errorf("service 'memcache.%v' is not available in memory", method)
+	}
+	return nil
+}
+
+func (m *memcacheService) get(req *pb.MemcacheGetRequest, res *pb.MemcacheGetResponse) {
+	for _, key := range req.Key {
+		item := m.item(string(key))
+		if item == nil {
+			continue
+		}
+		flags, casID := item.flags, item.casID
+		res.Item = append(res.Item, &pb.MemcacheGetResponse_Item{
+			Key:   key,
+			Value: append([]byte(nil), item.value...),
+			Flags: &flags,
+			CasId: &casID,
+		})
+	}
+}
+
+func (m *memcacheService) set(req *pb.MemcacheSetRequest, res *pb.MemcacheSetResponse) {
+	for _, reqItem := range req.Item {
+		key := string(reqItem.Key)
+		current := m.item(key)
+
+		status := pb.MemcacheSetResponse_STORED
+		switch reqItem.GetSetPolicy() {
+		case pb.MemcacheSetRequest_ADD:
+			if current != nil {
+				status = pb.MemcacheSetResponse_NOT_STORED
+			}
+		case pb.MemcacheSetRequest_REPLACE:
+			if current == nil {
+				status = pb.MemcacheSetResponse_NOT_STORED
+			}
+		case pb.MemcacheSetRequest_CAS:
+			if current == nil {
+				status = pb.MemcacheSetResponse_NOT_STORED
+			} else if current.casID != reqItem.GetCasId() {
+				status = pb.MemcacheSetResponse_EXISTS
+			}
+		}
+
+		if status == pb.MemcacheSetResponse_STORED {
+			m.casID++
+			m.items[key] = &memcacheItem{
+				value:   append([]byte(nil), reqItem.Value...),
+				flags:   reqItem.GetFlags(),
+				casID:   m.casID,
+				expires: expiresAt(reqItem.GetExpirationTime()),
+			}
+		}
+		res.SetStatus = append(res.SetStatus, status)
+	}
+}
+
+func (m *memcacheService) delete(req *pb.MemcacheDeleteRequest, res *pb.MemcacheDeleteResponse) {
+	for _, reqItem := range req.Item {
+		key := string(reqItem.Key)
+		status := pb.MemcacheDeleteResponse_NOT_FOUND
+		if m.item(key) != nil {
+			delete(m.items, key)
+			status = pb.MemcacheDeleteResponse_DELETED
+		}
+		res.DeleteStatus = append(res.DeleteStatus, status)
+	}
+}
+
+// item returns the unexpired item of a key, if any.
+func (m *memcacheService) item(key string) *memcacheItem {
+	item, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expires.IsZero() && !time.Now().Before(item.expires) {
+		delete(m.items, key)
+		return nil
+	}
+	return item
+}
+
+// expiresAt returns the time an item with the passed-in expiration
+// in seconds expires at, or the zero time if it does not expire.
+func expiresAt(expiration uint32) time.Time {
+	switch {
+	case expiration == 0:
+		return time.Time{}
+	case expiration <= maxRelativeExpiration:
+		return time.Now().Add(time.Duration(expiration) * time.Second)
+	}
+	return time.Unix(int64(expiration), 0)
+}
@@ -0,0 +1,57 @@
+package memctx
+
+import (
+	"time"
+
+	. "github.com/101loops/bdd"
+
+	"appengine/memcache"
+)
+
+var _ = Describe("Memcache", func() {
+
+	BeforeEach(func() {
+		err := memcache.Flush(ctx)
+		Check(err, IsNil)
+
+		err = memcache.Set(ctx, &memcache.Item{Key: "a", Value: []byte("1"), Flags: 42})
+		Check(err, IsNil)
+	})
+
+	It("should get an item", func() {
+		item, err := memcache.Get(ctx, "a")
+		Check(err, IsNil)
+		Check(item.Value, Equals, []byte("1"))
+		Check(item.Flags, EqualsNum, 42)
+
+		_, err = memcache.Get(ctx, "b")
+		Check(err, Equals, memcache.ErrCacheMiss)
+	})
+
+	It("should add an item only if it is missing", func() {
+		err := memcache.Add(ctx, &memcache.Item{Key: "a", Value: []byte("2")})
+		Check(err, Equals, memcache.ErrNotStored)
+
+		err = memcache.Add(ctx, &memcache.Item{Key: "b", Value: []byte("2")})
+		Check(err, IsNil)
+	})
+
+	It("should delete an item", func() {
+		err := memcache.Delete(ctx, "a")
+		Check(err, IsNil)
+
+		err = memcache.Delete(ctx, "a")
+		Check(err, Equals, memcache.ErrCacheMiss)
+	})
+
+	It("should expire an item", func() {
+		Check(expiresAt(0).IsZero(), IsTrue)
+		Check(expiresAt(60).After(time.Now()), IsTrue)
+		Check(expiresAt(maxRelativeExpiration+1), Equals, time.Unix(maxRelativeExpiration+1, 0))
+	})
+
+	It("should keep the items of each context apart", func() {
+		_, err := memcache.Get(New(), "a")
+		Check(err, Equals, memcache.ErrCacheMiss)
+	})
+})
@@ -0,0 +1,19 @@
+package memctx
+
+import (
+	"testing"
+
+	. "github.com/101loops/bdd"
+
+	ae "appengine"
+)
+
+var (
+	ctx ae.Context
+)
+
+func TestSuite(t *testing.T) {
+	ctx = New()
+
+	RunSpecs(t, "HRD Memory Context Suite")
+}
@@ -0,0 +1,297 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+const (
+	// maxIndexedStringLen is the maximum length of an indexed string in bytes.
+	maxIndexedStringLen = 1500
+)
+
+// Datastore is a datastore that keeps all entities in memory.
+//
+// Its queries are always strongly consistent and do not require any
+// composite index. Apart from that, it behaves like the App Engine datastore:
+// e.g. unindexed properties can not be queried and reads inside a transaction
+// do not see the transaction's own writes.
+type Datastore struct {
+	mu sync.Mutex
+
+	// entities contains all stored entities by key.
+	entities map[string]*entity
+
+	// versions contains the version of each entity group,
+	// it is incremented with every write to the group.
+	versions map[string]int64
+
+	// lastID is the last allocated numeric ID.
+	lastID int64
+}
+
+// entity is a stored datastore entity.
+type entity struct {
+	key   *ds.Key
+	props []ds.Property
+}
+
+// NewDatastore creates a new, empty Datastore.
+func NewDatastore() *Datastore {
+	return &Datastore{
+		entities: make(map[string]*entity),
+		versions: make(map[string]int64),
+	}
+}
+
+// Get loads the entities for the passed-in keys.
+func (d *Datastore) Get(ctx ae.Context, keys []*ds.Key, dst []ds.PropertyLoadSaver) error {
+	if len(keys) != len(dst) {
+		return fmt.Errorf("keys and destinations have different length")
+	}
+
+	entities, err := d.read(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	mErr := make(ae.MultiError, len(keys))
+	hasErr := false
+	for i, key := range keys {
+		if key.Incomplete() {
+			mErr[i] = ds.ErrInvalidKey
+		} else if e := entities[keyString(key)]; e == nil {
+			mErr[i] = ds.ErrNoSuchEntity
+		} else {
+			mErr[i] = loadProps(dst[i], e.props)
+		}
+		hasErr = hasErr || mErr[i] != nil
+	}
+
+	if hasErr {
+		return mErr
+	}
+	return nil
+}
+
+// Put saves the passed-in entities and returns their keys.
+func (d *Datastore) Put(ctx ae.Context, keys []*ds.Key, src []ds.PropertyLoadSaver) ([]*ds.Key, error) {
+	if len(keys) != len(src) {
+		return nil, fmt.Errorf("keys and sources have different length")
+	}
+
+	ret := make([]*ds.Key, len(keys))
+	entities := make([]*entity, len(keys))
+
+	mErr := make(ae.MultiError, len(keys))
+	hasErr := false
+	for i, key := range keys {
+		props, err := saveProps(src[i])
+		if err != nil {
+			mErr[i] = err
+			hasErr = true
+			continue
+		}
+		if key.Incomplete() {
+			nsCtx := types.WithNamespace(ctx, key.Namespace())
+			key = ds.NewKey(nsCtx, key.Kind(), "", d.allocateID(), key.Parent())
+		}
+		ret[i] = key
+		entities[i] = &entity{key, props}
+	}
+	if hasErr {
+		return nil, mErr
+	}
+
+	if err := d.write(ctx, ret, entities); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Delete deletes the entities for the passed-in keys.
+func (d *Datastore) Delete(ctx ae.Context, keys []*ds.Key) error {
+	for _, key := range keys {
+		if key.Incomplete() {
+			return ds.ErrInvalidKey
+		}
+	}
+	return d.write(ctx, keys, make([]*entity, len(keys)))
+}
+
+// read returns the entities for the passed-in keys.
+// Inside a transaction, they are read from the transaction's snapshot.
+func (d *Datastore) read(ctx ae.Context, keys []*ds.Key) (map[string]*entity, error) {
+	if tx := d.transaction(ctx); tx != nil {
+		if err := tx.touch(keys...); err != nil {
+			return nil, err
+		}
+		return tx.snapshot, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ret := make(map[string]*entity, len(keys))
+	for _, key := range keys {
+		id := keyString(key)
+		ret[id] = d.entities[id]
+	}
+	return ret, nil
+}
+
+// write saves the passed-in entities, a nil entity deletes the key.
+// Inside a transaction, the changes are applied when it commits.
+func (d *Datastore) write(ctx ae.Context, keys []*ds.Key, entities []*entity) error {
+	if tx := d.transaction(ctx); tx != nil {
+		if err := tx.touch(keys...); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			tx.writes[keyString(key)] = write{key, entities[i]}
+		}
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, key := range keys {
+		d.apply(key, entities[i])
+	}
+	return nil
+}
+
+// apply saves/deletes an entity. The caller must hold the lock.
+func (d *Datastore) apply(key *ds.Key, e *entity) {
+	id := keyString(key)
+	if e == nil {
+		delete(d.entities, id)
+	} else {
+		d.entities[id] = e
+	}
+	d.versions[groupString(key)]++
+}
+
+// AllocateIDs allocates n numeric IDs. Since all IDs are allocated from
+// a single sequence, they are unique regardless of kind and parent key.
+func (d *Datastore) AllocateIDs(_ ae.Context, _ string, _ *ds.Key, n int) (int64, int64, error) {
+	if n < 0 {
+		return 0, 0, fmt.Errorf("invalid number of IDs %d", n)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	low := d.lastID + 1
+	d.lastID += int64(n)
+	return low, d.lastID + 1, nil
+}
+
+// AllocateIDRange allocates the numeric IDs from start to end, inclusive.
+// It returns an error if any of them was allocated before.
+func (d *Datastore) AllocateIDRange(_ ae.Context, _ string, _ *ds.Key, start, end int64) error {
+	if start <= 0 || end < start {
+		return fmt.Errorf("invalid ID range [%d, %d]", start, end)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if start <= d.lastID {
+		return fmt.Errorf("ID range [%d, %d] collides with allocated IDs", start, end)
+	}
+	d.lastID = end
+	return nil
+}
+
+func (d *Datastore) allocateID() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastID++
+	return d.lastID
+}
+
+func saveProps(src ds.PropertyLoadSaver) ([]ds.Property, error) {
+	c := make(chan ds.Property, 32)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- src.Save(c)
+	}()
+
+	var props []ds.Property
+	var err error
+	for prop := range c {
+		if err == nil {
+			prop, err = normalizeProp(prop)
+		}
+		props = append(props, prop)
+	}
+
+	if saveErr := <-errc; saveErr != nil {
+		return nil, saveErr
+	}
+	return props, err
+}
+
+func loadProps(dst ds.PropertyLoadSaver, props []ds.Property) error {
+	c := make(chan ds.Property, len(props))
+	for _, prop := range props {
+		if b, ok := prop.Value.([]byte); ok {
+			prop.Value = append([]byte(nil), b...)
+		}
+		c <- prop
+	}
+	close(c)
+	return dst.Load(c)
+}
+
+func normalizeProp(prop ds.Property) (ds.Property, error) {
+	prop.Value = normalizeValue(prop.Value)
+
+	switch v := prop.Value.(type) {
+	case nil, int64, bool, string, float64, time.Time, *ds.Key, ae.BlobKey, ae.GeoPoint:
+	case []byte:
+		prop.Value = append([]byte(nil), v...)
+		prop.NoIndex = true
+	default:
+		return prop, fmt.Errorf("property %q has invalid type %T", prop.Name, v)
+	}
+
+	if s, ok := prop.Value.(string); ok && !prop.NoIndex && len(s) > maxIndexedStringLen {
+		return prop, fmt.Errorf("property %q is too long to be indexed (%d bytes)", prop.Name, len(s))
+	}
+
+	return prop, nil
+}
+
+// keyString returns a unique string representation of the passed-in key.
+func keyString(key *ds.Key) string {
+	var path []string
+	for k := key; k != nil; k = k.Parent() {
+		id := strconv.Quote(k.StringID())
+		if k.StringID() == "" {
+			id = strconv.FormatInt(k.IntID(), 10)
+		}
+		path = append([]string{strconv.Quote(k.Kind()) + "," + id}, path...)
+	}
+	return strconv.Quote(key.Namespace()) + "/" + strings.Join(path, "/")
+}
+
+// groupString returns a unique string representation
+// of the entity group of the passed-in key.
+func groupString(key *ds.Key) string {
+	for key.Parent() != nil {
+		key = key.Parent()
+	}
+	return keyString(key)
+}
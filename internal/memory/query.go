@@ -0,0 +1,399 @@
+package memory
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+const (
+	keyFieldName = "__key__"
+	cursorPrefix = "memory-cursor:"
+)
+
+var (
+	operators = map[string]func(int) bool{
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+		"=":  func(c int) bool { return c == 0 },
+		">=": func(c int) bool { return c >= 0 },
+		">":  func(c int) bool { return c > 0 },
+	}
+)
+
+// filter is a parsed query filter.
+type filter struct {
+	field string
+	op    string
+	value interface{}
+}
+
+// row is a single query result.
+type row struct {
+	key       *ds.Key
+	props     []ds.Property
+	order     []interface{}
+	projected bool
+}
+
+// Count returns the number of results for the passed-in query.
+func (d *Datastore) Count(ctx ae.Context, qry *types.Query) (int, error) {
+	rows, _, err := d.query(ctx, qry)
+	return len(rows), err
+}
+
+// Run executes the passed-in query and returns an iterator.
+func (d *Datastore) Run(ctx ae.Context, qry *types.Query) types.Iterator {
+	rows, pos, err := d.query(ctx, qry)
+	return &iterator{
+		ctx:      ctx,
+		rows:     rows,
+		pos:      pos,
+		keysOnly: qry.TypeOf == types.KeysOnlyQuery,
+		err:      err,
+	}
+}
+
+// query returns the results of the passed-in query
+// as well as the position of the first result.
+func (d *Datastore) query(ctx ae.Context, qry *types.Query) ([]*row, int, error) {
+	filters, err := parseFilters(qry.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	orders, err := queryOrders(qry.Order, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ancestor *ds.Key
+	if qry.Ancestor != nil {
+		ancestor = qry.Ancestor.ToDSKey(ctx)
+	}
+
+	entities, err := d.scan(ctx, ancestor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var rows []*row
+	for _, e := range entities {
+		if qry.Kind() != "" && e.key.Kind() != qry.Kind() {
+			continue
+		}
+		if ancestor != nil && !hasAncestor(e.key, ancestor) {
+			continue
+		}
+		if e.key.Namespace() != qry.Namespace {
+			continue
+		}
+		rows = append(rows, entityRows(e, qry, filters, orders)...)
+	}
+
+	sort.Sort(byOrder{rows, orders})
+
+	if qry.Distinct {
+		rows = distinctRows(rows)
+	}
+
+	return paginate(rows, qry)
+}
+
+// scan returns all entities that are visible to a query.
+// Inside a transaction, only ancestor queries are allowed.
+func (d *Datastore) scan(ctx ae.Context, ancestor *ds.Key) ([]*entity, error) {
+	entities := make([]*entity, 0)
+
+	if tx := d.transaction(ctx); tx != nil {
+		if ancestor == nil {
+			return nil, fmt.Errorf("only ancestor queries are allowed inside transactions")
+		}
+		if err := tx.touch(ancestor); err != nil {
+			return nil, err
+		}
+		for _, e := range tx.snapshot {
+			entities = append(entities, e)
+		}
+		return entities, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, e := range d.entities {
+		entities = append(entities, e)
+	}
+	return entities, nil
+}
+
+// entityRows returns the query results for an entity: none if it does
+// not match, otherwise one - or one per combination of projected values.
+func entityRows(e *entity, qry *types.Query, filters []filter, orders []types.Order) []*row {
+	values := make(map[string][]interface{})
+	values[keyFieldName] = []interface{}{e.key}
+	for _, prop := range e.props {
+		if !prop.NoIndex {
+			values[prop.Name] = append(values[prop.Name], prop.Value)
+		}
+	}
+
+	for _, f := range filters {
+		var matches []interface{}
+		for _, v := range values[f.field] {
+			if operators[f.op](compareValues(v, f.value)) {
+				matches = append(matches, v)
+			}
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+		values[f.field] = matches
+	}
+
+	for _, o := range orders {
+		if len(values[o.FieldName]) == 0 {
+			return nil
+		}
+	}
+
+	rows := []*row{{key: e.key, props: e.props}}
+	if qry.TypeOf == types.ProjectQuery {
+		rows = []*row{{key: e.key, projected: true}}
+		for _, field := range qry.Projection {
+			if len(values[field]) == 0 {
+				return nil
+			}
+			var projected []*row
+			for _, r := range rows {
+				for _, v := range values[field] {
+					prop := ds.Property{Name: field, Value: v}
+					props := append(append([]ds.Property(nil), r.props...), prop)
+					projected = append(projected, &row{key: r.key, props: props, projected: true})
+				}
+			}
+			rows = projected
+		}
+	}
+
+	for _, r := range rows {
+		r.order = make([]interface{}, len(orders))
+		for i, o := range orders {
+			r.order[i] = orderValue(r, o, values[o.FieldName])
+		}
+	}
+	return rows
+}
+
+// orderValue returns the value a row is sorted by: the projected value or,
+// for multi-valued properties, the smallest (ascending) or largest (descending).
+func orderValue(r *row, o types.Order, values []interface{}) interface{} {
+	if r.projected {
+		for _, prop := range r.props {
+			if prop.Name == o.FieldName {
+				return prop.Value
+			}
+		}
+	}
+
+	ret := values[0]
+	for _, v := range values[1:] {
+		c := compareValues(v, ret)
+		if (o.Descending && c > 0) || (!o.Descending && c < 0) {
+			ret = v
+		}
+	}
+	return ret
+}
+
+func distinctRows(rows []*row) []*row {
+	var ret []*row
+	seen := make(map[string]bool)
+	for _, r := range rows {
+		var values []string
+		for _, prop := range r.props {
+			v := prop.Value
+			if key, ok := v.(*ds.Key); ok {
+				v = keyString(key)
+			}
+			values = append(values, fmt.Sprintf("%v=%#v", prop.Name, v))
+		}
+		id := strings.Join(values, "|")
+		if !seen[id] {
+			seen[id] = true
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// paginate applies the query's cursors, offset and limit.
+func paginate(rows []*row, qry *types.Query) ([]*row, int, error) {
+	start, end := 0, len(rows)
+	if qry.Start != "" {
+		pos, err := decodeCursor(qry.Start)
+		if err != nil {
+			return nil, 0, err
+		}
+		start = pos
+	}
+	if qry.End != "" {
+		pos, err := decodeCursor(qry.End)
+		if err != nil {
+			return nil, 0, err
+		}
+		end = pos
+	}
+
+	if qry.Offset < 0 {
+		return nil, 0, fmt.Errorf("negative query offset")
+	}
+	start += qry.Offset
+	if qry.Limit >= 0 && start+qry.Limit < end {
+		end = start + qry.Limit
+	}
+
+	if end > len(rows) {
+		end = len(rows)
+	}
+	if start > end {
+		start = end
+	}
+	return rows[start:end], start, nil
+}
+
+func parseFilters(filters []types.Filter) ([]filter, error) {
+	ret := make([]filter, len(filters))
+
+	inequalityField := ""
+	for i, f := range filters {
+		str := strings.TrimSpace(f.Filter)
+		field := strings.TrimRight(str, " ><=!")
+		op := strings.TrimSpace(str[len(field):])
+		if field == "" {
+			return nil, fmt.Errorf("invalid filter: %q", f.Filter)
+		}
+		if _, ok := operators[op]; !ok {
+			return nil, fmt.Errorf("invalid operator %q in filter %q", op, f.Filter)
+		}
+
+		if op != "=" {
+			if inequalityField != "" && inequalityField != field {
+				return nil, fmt.Errorf("inequality filters on multiple properties: %q and %q", inequalityField, field)
+			}
+			inequalityField = field
+		}
+
+		ret[i] = filter{field, op, normalizeValue(f.Value)}
+	}
+
+	return ret, nil
+}
+
+// queryOrders returns the sort orders of a query, including the implicit
+// order by the inequality filter's property.
+func queryOrders(orders []types.Order, filters []filter) ([]types.Order, error) {
+	for _, f := range filters {
+		if f.op == "=" {
+			continue
+		}
+		if len(orders) == 0 {
+			return []types.Order{{FieldName: f.field}}, nil
+		}
+		if orders[0].FieldName != f.field {
+			return nil, fmt.Errorf("first sort property must be the same as the property "+
+				"to which the inequality filter is applied: %q", f.field)
+		}
+		break
+	}
+	return orders, nil
+}
+
+// byOrder sorts rows by the query's sort orders and then by key.
+type byOrder struct {
+	rows   []*row
+	orders []types.Order
+}
+
+func (s byOrder) Len() int {
+	return len(s.rows)
+}
+
+func (s byOrder) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+}
+
+func (s byOrder) Less(i, j int) bool {
+	a, b := s.rows[i], s.rows[j]
+	for n, o := range s.orders {
+		c := compareValues(a.order[n], b.order[n])
+		if o.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return compareKeys(a.key, b.key) < 0
+}
+
+func encodeCursor(pos int) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(pos)))
+}
+
+func decodeCursor(s string) (int, error) {
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err == nil && strings.HasPrefix(string(b), cursorPrefix) {
+		var pos int
+		if pos, err = strconv.Atoi(strings.TrimPrefix(string(b), cursorPrefix)); err == nil && pos >= 0 {
+			return pos, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", s)
+}
+
+// iterator is the result of running a query.
+type iterator struct {
+	ctx      ae.Context
+	rows     []*row
+	pos      int
+	next     int
+	keysOnly bool
+	err      error
+}
+
+// Cursor returns a cursor for the iterator's current location.
+func (it *iterator) Cursor() (string, error) {
+	if it.err != nil {
+		return "", it.err
+	}
+	return encodeCursor(it.pos + it.next), nil
+}
+
+// Next returns the key of the next result. If the query is not keys-only,
+// it also loads the entity stored for that key into a PropertyLoadSaver.
+func (it *iterator) Next(pipeFunc func(ae.Context) ds.PropertyLoadSaver) (*ds.Key, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if it.next >= len(it.rows) {
+		return nil, ds.Done
+	}
+
+	r := it.rows[it.next]
+	it.next++
+
+	if !it.keysOnly {
+		if err := loadProps(pipeFunc(it.ctx), r.props); err != nil {
+			return r.key, err
+		}
+	}
+	return r.key, nil
+}
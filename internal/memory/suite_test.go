@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"testing"
+
+	. "github.com/101loops/bdd"
+
+	ae "appengine"
+)
+
+var (
+	ctx ae.Context
+)
+
+func TestSuite(t *testing.T) {
+	ctx = NewContext()
+
+	RunSpecs(t, "HRD Memory Suite")
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+const (
+	// maxTransactionAttempts is how often a transaction is tried to commit.
+	maxTransactionAttempts = 3
+
+	// maxCrossGroups is the maximum number of entity groups
+	// a cross-group transaction can touch.
+	maxCrossGroups = 25
+)
+
+var (
+	errNestedTransaction = errors.New("nested transactions are not supported")
+)
+
+// transaction tracks the reads and writes of a running transaction.
+type transaction struct {
+	store      *Datastore
+	crossGroup bool
+
+	// snapshot contains all entities at the start of the transaction.
+	snapshot map[string]*entity
+
+	// versions contains the version of each entity group
+	// at the start of the transaction.
+	versions map[string]int64
+
+	// groups contains the entity groups touched by the transaction.
+	groups map[string]bool
+
+	// writes contains the pending writes by key.
+	writes map[string]write
+}
+
+// write is a pending write of a transaction, a nil entity deletes the key.
+type write struct {
+	key    *ds.Key
+	entity *entity
+}
+
+// txContext is the App Engine context inside a transaction.
+type txContext struct {
+	ae.Context
+	tx *transaction
+}
+
+// RunInTransaction runs the passed-in function in a transaction.
+// If the transaction conflicts with a concurrent write it is retried.
+func (d *Datastore) RunInTransaction(ctx ae.Context, f func(ae.Context) error, crossGroup bool) error {
+	if _, ok := ctx.(*txContext); ok {
+		return errNestedTransaction
+	}
+
+	for i := 0; i < maxTransactionAttempts; i++ {
+		tx := d.begin(crossGroup)
+		if err := f(&txContext{ctx, tx}); err != nil {
+			return err
+		}
+		if err := tx.commit(); err != ds.ErrConcurrentTransaction {
+			return err
+		}
+	}
+	return ds.ErrConcurrentTransaction
+}
+
+// transaction returns the transaction of the passed-in context, if any.
+func (d *Datastore) transaction(ctx ae.Context) *transaction {
+	if txCtx, ok := ctx.(*txContext); ok && txCtx.tx.store == d {
+		return txCtx.tx
+	}
+	return nil
+}
+
+func (d *Datastore) begin(crossGroup bool) *transaction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tx := &transaction{
+		store:      d,
+		crossGroup: crossGroup,
+		snapshot:   make(map[string]*entity, len(d.entities)),
+		versions:   make(map[string]int64, len(d.versions)),
+		groups:     make(map[string]bool),
+		writes:     make(map[string]write),
+	}
+	for id, e := range d.entities {
+		tx.snapshot[id] = e
+	}
+	for group, version := range d.versions {
+		tx.versions[group] = version
+	}
+	return tx
+}
+
+// touch adds the entity groups of the passed-in keys to the transaction.
+func (tx *transaction) touch(keys ...*ds.Key) error {
+	for _, key := range keys {
+		tx.groups[groupString(key)] = true
+	}
+
+	if !tx.crossGroup && len(tx.groups) > 1 {
+		return fmt.Errorf("cross-group transaction need to be explicitly specified")
+	}
+	if len(tx.groups) > maxCrossGroups {
+		return fmt.Errorf("operating on too many entity groups in a single transaction")
+	}
+	return nil
+}
+
+// commit applies the transaction's writes, unless one of its entity groups
+// has been modified since the transaction started.
+func (tx *transaction) commit() error {
+	d := tx.store
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for group := range tx.groups {
+		if d.versions[group] != tx.versions[group] {
+			return ds.ErrConcurrentTransaction
+		}
+	}
+
+	for _, w := range tx.writes {
+		d.apply(w.key, w.entity)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	. "github.com/101loops/bdd"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+var _ = Describe("Transaction", func() {
+
+	var (
+		store *Datastore
+		keys  []*ds.Key
+	)
+
+	BeforeEach(func() {
+		store = NewDatastore()
+		keys = []*ds.Key{ds.NewKey(ctx, "a", "", 1, nil), ds.NewKey(ctx, "a", "", 2, nil)}
+		for _, key := range keys {
+			store.apply(key, &entity{key: key})
+		}
+	})
+
+	It("should not allow nested transactions", func() {
+		err := store.RunInTransaction(ctx, func(tc ae.Context) error {
+			return store.RunInTransaction(tc, func(_ ae.Context) error {
+				return nil
+			}, false)
+		}, false)
+
+		Check(err, Equals, errNestedTransaction)
+	})
+
+	It("should apply writes on commit", func() {
+		err := store.RunInTransaction(ctx, func(tc ae.Context) error {
+			err := store.Delete(tc, keys[:1])
+			Check(store.entities, HasLen, 2)
+			return err
+		}, false)
+
+		Check(err, IsNil)
+		Check(store.entities, HasLen, 1)
+	})
+
+	It("should retry on concurrent modification", func() {
+		attempts := 0
+		err := store.RunInTransaction(ctx, func(tc ae.Context) error {
+			attempts++
+			if err := store.Delete(tc, keys[:1]); err != nil {
+				return err
+			}
+			return store.Delete(ctx, keys[:1])
+		}, false)
+
+		Check(err, Equals, ds.ErrConcurrentTransaction)
+		Check(attempts, EqualsNum, maxTransactionAttempts)
+	})
+
+	It("should limit the number of entity groups", func() {
+		err := store.RunInTransaction(ctx, func(tc ae.Context) error {
+			return store.Delete(tc, keys)
+		}, false)
+		Check(err, ErrorContains, "cross-group transaction")
+
+		err = store.RunInTransaction(ctx, func(tc ae.Context) error {
+			return store.Delete(tc, keys)
+		}, true)
+		Check(err, IsNil)
+	})
+})
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"strings"
+	"time"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+// normalizeValue converts a value to the type it is stored as.
+func normalizeValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case float32:
+		return float64(x)
+	case time.Time:
+		// the datastore stores times with microsecond precision
+		return time.Unix(0, x.UnixNano()/1e3*1e3)
+	}
+	return v
+}
+
+// typeRank returns the position of a value's type in the datastore's
+// sort order. Values of different types are ordered by their type.
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int64, time.Time:
+		return 1
+	case bool:
+		return 2
+	case []byte:
+		return 3
+	case string, ae.BlobKey:
+		return 4
+	case float64:
+		return 5
+	case ae.GeoPoint:
+		return 6
+	case *ds.Key:
+		return 7
+	}
+	return 8
+}
+
+// compareValues returns an integer comparing two values in datastore order.
+// The result is 0 if a == b, negative if a < b and positive if a > b.
+func compareValues(a, b interface{}) int {
+	a, b = normalizeValue(a), normalizeValue(b)
+
+	rankA, rankB := typeRank(a), typeRank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+
+	switch x := a.(type) {
+	case int64, time.Time:
+		return compareInts(fixedPoint(a), fixedPoint(b))
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		} else if !x {
+			return -1
+		}
+		return 1
+	case []byte:
+		return strings.Compare(string(x), string(b.([]byte)))
+	case string:
+		return strings.Compare(x, stringOf(b))
+	case ae.BlobKey:
+		return strings.Compare(string(x), stringOf(b))
+	case float64:
+		y := b.(float64)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case ae.GeoPoint:
+		y := b.(ae.GeoPoint)
+		if c := compareValues(x.Lat, y.Lat); c != 0 {
+			return c
+		}
+		return compareValues(x.Lng, y.Lng)
+	case *ds.Key:
+		return compareKeys(x, b.(*ds.Key))
+	}
+	return 0
+}
+
+// compareKeys returns an integer comparing two keys in datastore order.
+func compareKeys(a, b *ds.Key) int {
+	if c := strings.Compare(a.AppID(), b.AppID()); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Namespace(), b.Namespace()); c != 0 {
+		return c
+	}
+
+	pathA, pathB := keyPath(a), keyPath(b)
+	for i := 0; i < len(pathA) && i < len(pathB); i++ {
+		x, y := pathA[i], pathB[i]
+		if c := strings.Compare(x.Kind(), y.Kind()); c != 0 {
+			return c
+		}
+		// numeric IDs are ordered before string IDs
+		if x.StringID() == "" && y.StringID() == "" {
+			if c := compareInts(x.IntID(), y.IntID()); c != 0 {
+				return c
+			}
+		} else if x.StringID() == "" {
+			return -1
+		} else if y.StringID() == "" {
+			return 1
+		} else if c := strings.Compare(x.StringID(), y.StringID()); c != 0 {
+			return c
+		}
+	}
+	return len(pathA) - len(pathB)
+}
+
+// keyPath returns the passed-in key and its ancestors, starting with the root.
+func keyPath(key *ds.Key) []*ds.Key {
+	var path []*ds.Key
+	for k := key; k != nil; k = k.Parent() {
+		path = append([]*ds.Key{k}, path...)
+	}
+	return path
+}
+
+// hasAncestor returns whether the key equals or descends from the ancestor.
+func hasAncestor(key, ancestor *ds.Key) bool {
+	for k := key; k != nil; k = k.Parent() {
+		if compareKeys(k, ancestor) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func fixedPoint(v interface{}) int64 {
+	if t, ok := v.(time.Time); ok {
+		return t.UnixNano() / 1e3
+	}
+	return v.(int64)
+}
+
+func stringOf(v interface{}) string {
+	if k, ok := v.(ae.BlobKey); ok {
+		return string(k)
+	}
+	return v.(string)
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"time"
+
+	. "github.com/101loops/bdd"
+
+	ds "appengine/datastore"
+)
+
+var _ = Describe("Value", func() {
+
+	It("should normalize values", func() {
+		Check(normalizeValue(42), Equals, int64(42))
+		Check(normalizeValue(int32(42)), Equals, int64(42))
+		Check(normalizeValue(float32(1.5)), Equals, float64(1.5))
+		Check(normalizeValue("abc"), Equals, "abc")
+
+		t := time.Unix(0, 1234567)
+		Check(normalizeValue(t), Equals, time.Unix(0, 1234000))
+	})
+
+	It("should compare values of the same type", func() {
+		Check(compareValues(1, 2), IsLessThan, 0)
+		Check(compareValues(int64(2), 2), EqualsNum, 0)
+		Check(compareValues("b", "a"), IsGreaterThan, 0)
+		Check(compareValues(false, true), IsLessThan, 0)
+		Check(compareValues(1.5, 0.5), IsGreaterThan, 0)
+		Check(compareValues(time.Unix(1, 0), time.Unix(2, 0)), IsLessThan, 0)
+	})
+
+	It("should compare values of different types by type", func() {
+		Check(compareValues(nil, 1), IsLessThan, 0)
+		Check(compareValues(100, true), IsLessThan, 0)
+		Check(compareValues(true, "a"), IsLessThan, 0)
+		Check(compareValues("z", 0.5), IsLessThan, 0)
+		Check(compareValues(0.5, ds.NewKey(ctx, "a", "", 1, nil)), IsLessThan, 0)
+	})
+
+	It("should compare keys", func() {
+		parent := ds.NewKey(ctx, "parent", "", 1, nil)
+
+		Check(compareKeys(ds.NewKey(ctx, "a", "", 1, nil), ds.NewKey(ctx, "b", "", 1, nil)), IsLessThan, 0)
+		Check(compareKeys(ds.NewKey(ctx, "a", "", 2, nil), ds.NewKey(ctx, "a", "", 1, nil)), IsGreaterThan, 0)
+		Check(compareKeys(ds.NewKey(ctx, "a", "", 9, nil), ds.NewKey(ctx, "a", "x", 0, nil)), IsLessThan, 0)
+		Check(compareKeys(parent, ds.NewKey(ctx, "a", "", 1, parent)), IsLessThan, 0)
+	})
+
+	It("should return whether a key has an ancestor", func() {
+		parent := ds.NewKey(ctx, "parent", "", 1, nil)
+		child := ds.NewKey(ctx, "child", "", 1, parent)
+
+		Check(hasAncestor(child, parent), IsTrue)
+		Check(hasAncestor(parent, parent), IsTrue)
+		Check(hasAncestor(parent, child), IsFalse)
+	})
+})
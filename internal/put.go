@@ -2,23 +2,16 @@ package internal
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/101loops/hrd/cache"
 	"github.com/101loops/hrd/internal/trafo"
 	"github.com/101loops/hrd/internal/types"
-	"github.com/qedus/nds"
-
-	ae "appengine"
-	ds "appengine/datastore"
-)
-
-var (
-	ndsPut = func(ctx ae.Context, keys []*ds.Key, dst interface{}) ([]*ds.Key, error) {
-		return nds.PutMulti(ctx, keys, dst)
-	}
+	"github.com/101loops/hrd/metrics"
 )
 
 // Put saves the given entities.
-func Put(kind *types.Kind, src interface{}, completeKeys bool) ([]*types.Key, error) {
+func (b *Backend) Put(kind *types.Kind, src interface{}, opts *types.Opts) ([]*types.Key, error) {
 	ctx := kind.Context
 
 	docList, err := trafo.NewReadableDocList(kind, src)
@@ -26,27 +19,89 @@ func Put(kind *types.Kind, src interface{}, completeKeys bool) ([]*types.Key, er
 		return nil, err
 	}
 
+	if err := generateIDs(kind, docList); err != nil {
+		return nil, err
+	}
+
 	keys := docList.Keys()
-	if err := validatePutKeys(kind, keys, completeKeys); err != nil {
+	if err := validatePutKeys(kind, keys, opts.CompleteKeys); err != nil {
 		return nil, err
 	}
 
-	ctx.Infof(LogDatastoreAction("putting", "in", keys, kind.Name))
+	start := time.Now()
+	gc := b.globalCache(kind)
+	policy := kind.CachePolicy
+	if gc != nil && policy.LocksOnWrite() {
+		if err := gc.lock(toDSKeys(ctx, keys)); err != nil {
+			return nil, err
+		}
+	}
 
 	docsPipe := docList.Pipe(kind.Context)
-	dsKeys, dsErr := ndsPut(ctx, toDSKeys(ctx, keys), docsPipe.Properties())
+	props := docsPipe.Properties()
+	// inside a transaction, the entities are only cached once they are
+	// loaded after the commit, since the transaction may still fail
+	var recorders []*propertyRecorder
+	if gc != nil && policy.Mode == cache.WriteThrough && !b.local.inTransaction(ctx) {
+		recorders = make([]*propertyRecorder, len(props))
+		for i := range props {
+			recorders[i] = &propertyRecorder{PropertyLoadSaver: props[i]}
+			props[i] = recorders[i]
+		}
+	}
+
+	dsKeys, dsErr := b.ds.Put(b.context(kind), toDSKeys(ctx, keys), props)
+	reportAction(kind, LogDatastoreAction("putting", "in", keys, kind.Name),
+		metrics.Event{Op: metrics.OpPut, BatchSize: len(keys), Err: dsErr}, start, nil)
+	b.local.invalidate(ctx, toDSKeys(ctx, keys))
 	if dsErr != nil {
 		return nil, dsErr
 	}
+	b.local.invalidate(ctx, dsKeys)
+
+	if recorders != nil {
+		entries := make([]*cacheEntry, len(recorders))
+		for i, r := range recorders {
+			entries[i] = &cacheEntry{r.props, true}
+		}
+		gc.replace(dsKeys, entries, policy.TTL)
+	}
 
 	return docList.ApplyResult(dsKeys, dsErr)
 }
 
+// generateIDs generates the IDs of the entities with text IDs and
+// incomplete keys using the kind's ID strategy, if it has one.
+func generateIDs(kind *types.Kind, docList *trafo.DocList) error {
+	if kind.IDStrategy == nil {
+		return nil
+	}
+	return docList.GenerateTextIDs(newIDFunc(kind))
+}
+
+// newIDFunc returns a function that generates text IDs
+// with the ID strategy of the given kind.
+func newIDFunc(kind *types.Kind) func() (string, error) {
+	return func() (string, error) {
+		id, err := kind.IDStrategy.NewID(kind.Context, kind.Name)
+		if err != nil {
+			return "", fmt.Errorf("cannot generate ID for %q: %v", kind.Name, err)
+		}
+		return id, nil
+	}
+}
+
 func validatePutKeys(kind *types.Kind, keys []*types.Key, completeKeys bool) error {
 	if len(keys) == 0 {
 		return fmt.Errorf("no keys provided for %q", kind.Name)
 	}
 
+	for _, key := range keys {
+		if err := key.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if completeKeys {
 		for i, key := range keys {
 			if key.Incomplete() {
@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/memory"
 	"github.com/101loops/hrd/internal/types"
 
 	ae "appengine"
@@ -26,7 +27,7 @@ var _ = Describe("Put", func() {
 		Check(entity.UpdatedAt(), IsZero)
 		Check(entity.CreatedAt(), IsZero)
 
-		keys, err := Put(kind, entity, false)
+		keys, err := backend.Put(kind, entity, &types.Opts{})
 		Check(err, IsNil)
 		Check(keys, HasLen, 1)
 
@@ -43,7 +44,7 @@ var _ = Describe("Put", func() {
 			&MyModel{}, &MyModel{},
 		}
 
-		keys, err := Put(kind, entities, false)
+		keys, err := backend.Put(kind, entities, &types.Opts{})
 		Check(err, IsNil)
 		Check(keys, HasLen, 2)
 
@@ -53,11 +54,41 @@ var _ = Describe("Put", func() {
 		Check(entities[1].ID(), EqualsNum, keys[1].IntID)
 	})
 
+	It("should generate text IDs with the kind's ID strategy", func() {
+		n := 0
+		kind.IDStrategy = idFunc(func() (string, error) {
+			n++
+			return fmt.Sprintf("id-%d", n), nil
+		})
+
+		entities := []*MyTextModel{{}, {}}
+		entities[1].SetID("fixed")
+		keys, err := backend.Put(kind, entities, &types.Opts{CompleteKeys: true})
+		Check(err, IsNil)
+		Check(keys, HasLen, 2)
+
+		Check(keys[0].StringID, Equals, "id-1")
+		Check(entities[0].ID(), Equals, "id-1")
+		Check(keys[1].StringID, Equals, "fixed")
+		Check(entities[1].ID(), Equals, "fixed")
+	})
+
+	It("should not generate numeric IDs with the kind's ID strategy", func() {
+		kind.IDStrategy = idFunc(func() (string, error) {
+			panic("unexpected call")
+		})
+
+		entity := &MyModel{}
+		keys, err := backend.Put(kind, entity, &types.Opts{})
+		Check(err, IsNil)
+		Check(keys[0].IntID, IsGreaterThan, 0)
+	})
+
 	It("should save an entity with id", func() {
 		entity := &MyModel{}
 		entity.SetID(42)
 
-		keys, err := Put(kind, entity, true)
+		keys, err := backend.Put(kind, entity, &types.Opts{CompleteKeys: true})
 		Check(err, IsNil)
 		Check(keys, HasLen, 1)
 
@@ -70,7 +101,7 @@ var _ = Describe("Put", func() {
 		entities[0].SetID(1)
 		entities[1].SetID(2)
 
-		keys, err := Put(kind, entities, true)
+		keys, err := backend.Put(kind, entities, &types.Opts{CompleteKeys: true})
 		Check(err, IsNil)
 		Check(keys, HasLen, 2)
 
@@ -81,7 +112,7 @@ var _ = Describe("Put", func() {
 	// ==== ERRORS
 
 	It("should not save nil entity", func() {
-		keys, err := Put(kind, nil, false)
+		keys, err := backend.Put(kind, nil, &types.Opts{})
 
 		Check(keys, IsNil)
 		Check(err, ErrorContains, "must be non-nil")
@@ -90,16 +121,10 @@ var _ = Describe("Put", func() {
 	// NOTE: other cases of invalid entity/entities are checked inside the trafo package
 
 	It("should return an error when operation fails", func() {
-		_put := ndsPut
-		defer func() {
-			ndsPut = _put
-		}()
-		ndsPut = func(_ ae.Context, _ []*ds.Key, _ interface{}) ([]*ds.Key, error) {
-			return nil, fmt.Errorf("an error")
-		}
+		backend := NewBackend(&failingDatastore{datastore})
 
 		entity := &MyModel{}
-		keys, err := Put(kind, entity, false)
+		keys, err := backend.Put(kind, entity, &types.Opts{})
 
 		Check(keys, IsNil)
 		Check(err, HasOccurred)
@@ -107,17 +132,43 @@ var _ = Describe("Put", func() {
 
 	It("should not save complete entity without Id", func() {
 		entity := &MyModel{}
-		keys, err := Put(kind, entity, true)
+		keys, err := backend.Put(kind, entity, &types.Opts{CompleteKeys: true})
 
 		Check(keys, IsNil)
 		Check(err, ErrorContains, "is incomplete")
 	})
 
+	It("should return an error when the ID strategy fails", func() {
+		kind.IDStrategy = idFunc(func() (string, error) {
+			return "", fmt.Errorf("an error")
+		})
+
+		keys, err := backend.Put(kind, &MyTextModel{}, &types.Opts{})
+		Check(keys, IsNil)
+		Check(err, ErrorContains, "cannot generate ID")
+	})
+
 	It("should not save empty entities", func() {
 		entities := []*MyModel{}
-		keys, err := Put(kind, entities, false)
+		keys, err := backend.Put(kind, entities, &types.Opts{})
 
 		Check(keys, IsNil)
 		Check(err, ErrorContains, "no keys provided")
 	})
 })
+
+// idFunc is a function that is a types.IDStrategy.
+type idFunc func() (string, error)
+
+func (f idFunc) NewID(_ ae.Context, _ string) (string, error) {
+	return f()
+}
+
+// failingDatastore is a Datastore whose writes fail.
+type failingDatastore struct {
+	*memory.Datastore
+}
+
+func (*failingDatastore) Put(_ ae.Context, _ []*ds.Key, _ []ds.PropertyLoadSaver) ([]*ds.Key, error) {
+	return nil, fmt.Errorf("an error")
+}
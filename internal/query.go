@@ -1,24 +1,50 @@
 package internal
 
 import (
+	"time"
+
 	"github.com/101loops/hrd/internal/trafo"
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
 
-	ae "appengine"
 	ds "appengine/datastore"
 )
 
-// Count returns the number of results for a query.
-func Count(ctx ae.Context, qry *types.Query) (int, error) {
-	return qry.ToDSQuery(ctx).Count(ctx)
+// Count returns the number of results for a query on a kind.
+func (b *Backend) Count(kind *types.Kind, qry *types.Query) (int, error) {
+	start := time.Now()
+	count, err := b.ds.Count(b.context(kind), qry)
+
+	reportAction(kind, "counting query", metrics.Event{Op: metrics.OpCount, BatchSize: count, Err: err},
+		start, logging.Fields{"query": qry})
+	return count, err
+}
+
+// Run executes a query on a kind and returns an iterator.
+func (b *Backend) Run(kind *types.Kind, qry *types.Query) types.Iterator {
+	return b.ds.Run(b.context(kind), qry)
+}
+
+// Query executes a query on a kind and loads its results into dsts.
+// It returns the query's iterator for continuing from its cursor.
+func (b *Backend) Query(kind *types.Kind, qry *types.Query, dsts interface{}, multi bool) (types.Iterator, []*types.Key, error) {
+	it := b.Run(kind, qry)
+	keys, err := b.Iterate(kind, it, dsts, multi)
+	return it, keys, err
 }
 
-// Iterate loads entities from an iterator.
-func Iterate(it *types.Iterator, dsts interface{}, multi bool) (keys []*types.Key, err error) {
+// Iterate loads entities of a kind from an iterator.
+func (b *Backend) Iterate(kind *types.Kind, it types.Iterator, dsts interface{}, multi bool) (keys []*types.Key, err error) {
+	start := time.Now()
+	defer func() {
+		reportAction(kind, LogDatastoreAction("loading", "from", keys, kind.Name),
+			metrics.Event{Op: metrics.OpQuery, BatchSize: len(keys), Err: err}, start, nil)
+	}()
 
 	var docList *trafo.DocList
 	if dsts != nil {
-		docList, err = trafo.NewWriteableDocList(dsts, nil, multi)
+		docList, err = trafo.NewWriteableDocList(kind, dsts, nil, multi)
 		if err != nil {
 			return
 		}
@@ -17,8 +17,8 @@ var _ = Describe("Query", func() {
 	)
 
 	runQuery := func(dst interface{}, multi bool) ([]*types.Key, string, error) {
-		it := types.NewIterator(ctx, query)
-		keys, err := Iterate(it, dst, multi)
+		it := backend.Run(kind, query)
+		keys, err := backend.Iterate(kind, it, dst, multi)
 		cursor, _ := it.Cursor()
 		return keys, cursor, err
 	}
@@ -34,7 +34,7 @@ var _ = Describe("Query", func() {
 			entity.SetID(i)
 			entities[i-1] = entity
 		}
-		keys, err = Put(kind, entities, true)
+		keys, err = backend.Put(kind, entities, &types.Opts{CompleteKeys: true})
 		Check(err, IsNil)
 		Check(keys, HasLen, 4)
 		Check(keys[0].IntID, EqualsNum, 1)
@@ -43,13 +43,13 @@ var _ = Describe("Query", func() {
 		Check(keys[3].IntID, EqualsNum, 4)
 
 		// next step is required because of eventual consistency :(
-		Get(kind, keys, &entities, false, true)
+		backend.Get(kind, keys, &entities, noCache, true)
 
 		query = types.NewQuery(kind.Name)
 	})
 
 	It("should count entities", func() {
-		count, err := Count(ctx, query)
+		count, err := backend.Count(kind, query)
 
 		Check(err, IsNil)
 		Check(count, EqualsNum, 4)
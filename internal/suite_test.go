@@ -7,28 +7,32 @@ import (
 	"testing"
 
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
 	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/memory"
 	"github.com/101loops/hrd/internal/trafo"
 	"github.com/101loops/hrd/internal/types"
 
-	"appengine/aetest"
+	ae "appengine"
 	"appengine/memcache"
 )
 
 var (
-	ctx aetest.Context
+	ctx       ae.Context
+	datastore *memory.Datastore
+	backend   *Backend
+	codecs    = trafo.NewCodecSet()
+	noCache   = &types.Opts{NoGlobalCache: true, NoLocalCache: true}
 )
 
 func TestSuite(t *testing.T) {
-	var err error
-	ctx, err = aetest.NewContext(nil)
-	if err != nil {
-		panic(err)
-	}
-	defer ctx.Close()
+	ctx = memory.NewContext()
+	datastore = memory.NewDatastore()
+	backend = NewBackend(datastore)
 
-	trafo.CodecSet.AddMust(MyModel{})
-	trafo.CodecSet.AddMust(InvalidModel{})
+	codecs.AddMust(MyModel{})
+	codecs.AddMust(InvalidModel{})
+	codecs.AddMust(MyTextModel{})
 
 	RunSpecs(t, "HRD Internal Suite")
 }
@@ -36,13 +40,26 @@ func TestSuite(t *testing.T) {
 func randomKind() *types.Kind {
 	var n int32
 	binary.Read(rand.Reader, binary.LittleEndian, &n)
-	return types.NewKind(ctx, fmt.Sprintf("coll_%v", n))
+	return newKind(fmt.Sprintf("coll_%v", n))
+}
+
+func newKind(name string) *types.Kind {
+	kind := types.NewKind(ctx, name)
+	kind.Codecs = codecs
+	kind.Cache = cache.NewMemcache()
+	return kind
 }
 
 // ==== MODELS
 
 type InvalidModel struct{}
 
+type MyTextModel struct {
+	entity.TextID
+
+	Text string `datastore:"text"`
+}
+
 type MyModel struct {
 	entity.NumID
 	entity.CreatedTime
@@ -81,7 +98,7 @@ func clearCache() {
 
 func existsInDB(keys ...*types.Key) bool {
 	var entity *MyModel
-	keys, err := Get(types.NewKind(ctx, keys[0].Kind), keys, &entity, false, false)
+	keys, err := backend.Get(newKind(keys[0].Kind), keys, &entity, noCache, false)
 	if err != nil {
 		panic(err)
 	}
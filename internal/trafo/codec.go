@@ -7,6 +7,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/101loops/hrd/internal/types"
 	"github.com/101loops/structor"
 
 	ae "appengine"
@@ -18,9 +19,6 @@ const (
 )
 
 var (
-	// CodecSet is a set of all registered entity codecs.
-	CodecSet *structor.Set
-
 	errFieldIgnored = errors.New("field ignored")
 
 	typeOfStr       = reflect.TypeOf("")
@@ -28,21 +26,26 @@ var (
 	typeOfByteSlice = reflect.TypeOf([]byte(nil))
 	typeOfTime      = reflect.TypeOf(time.Time{})
 	typeOfGeoPoint  = reflect.TypeOf(ae.GeoPoint{})
-)
 
-func init() {
-	newCodecSet()
-}
+	typeOfDSKeyConverter = reflect.TypeOf((*types.DSKeyConverter)(nil)).Elem()
+	typeOfKeySetter      = reflect.TypeOf((*types.KeySetter)(nil)).Elem()
+)
 
-func newCodecSet() {
-	CodecSet = structor.NewSet("datastore")
-	CodecSet.SetValidateFunc(validateCodec)
+// NewCodecSet creates a new, empty set of entity codecs.
+func NewCodecSet() *structor.Set {
+	codecs := structor.NewSet("datastore")
+	codecs.SetValidateFunc(validateCodec)
+	return codecs
 }
 
 // getCodec returns an entity's codec.
 // The entity must be been added to the codec set beforehand.
-func getCodec(entity interface{}) (*structor.Codec, error) {
-	codec, err := CodecSet.Get(entity)
+func getCodec(codecs *structor.Set, entity interface{}) (*structor.Codec, error) {
+	if codecs == nil {
+		return nil, fmt.Errorf("no codec set to look up type %q", reflect.TypeOf(entity))
+	}
+
+	codec, err := codecs.Get(entity)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +53,7 @@ func getCodec(entity interface{}) (*structor.Codec, error) {
 	return codec, nil
 }
 
-func validateCodec(_ *structor.Set, codec *structor.Codec) error {
+func validateCodec(codecs *structor.Set, codec *structor.Codec) error {
 	labels := make(map[string]bool, 0)
 
 	for _, field := range codec.Fields {
@@ -68,7 +71,7 @@ func validateCodec(_ *structor.Set, codec *structor.Codec) error {
 			return fmt.Errorf("field %q %v", field.Name, err)
 		}
 
-		if err := validateSubField(labels, field); err != nil {
+		if err := validateSubField(codecs, labels, field); err != nil {
 			return err
 		}
 	}
@@ -77,7 +80,7 @@ func validateCodec(_ *structor.Set, codec *structor.Codec) error {
 }
 
 func validateFieldType(field *structor.FieldCodec) error {
-	if field.Type.Kind() == reflect.Ptr && field.Type != typeOfDSKey {
+	if field.Type.Kind() == reflect.Ptr && field.Type != typeOfDSKey && !isKeyType(field.Type) {
 		return fmt.Errorf("has invalid type 'pointer'")
 	}
 
@@ -123,13 +126,13 @@ func validateFieldName(labels map[string]bool, field *structor.FieldCodec) error
 	return nil
 }
 
-func validateSubField(labels map[string]bool, parentField *structor.FieldCodec) error {
+func validateSubField(codecs *structor.Set, labels map[string]bool, parentField *structor.FieldCodec) error {
 	subType := subTypeOf(parentField.Type, parentField.ElemType)
 	if subType == nil || subType.Kind() != reflect.Struct {
 		return nil
 	}
 
-	subCodec, _ := CodecSet.Get(subType)
+	subCodec, _ := codecs.Get(subType)
 	if !subCodec.Complete {
 		return fmt.Errorf("recursive struct at field %q", parentField.Name)
 	}
@@ -160,6 +163,10 @@ func validateSubField(labels map[string]bool, parentField *structor.FieldCodec)
 }
 
 func subTypeOf(fieldType reflect.Type, elemType *reflect.Type) reflect.Type {
+	if isKeyType(fieldType) || (elemType != nil && isKeyType(*elemType)) {
+		return nil
+	}
+
 	if fieldType.Kind() == reflect.Struct {
 		if fieldType != typeOfTime {
 			return fieldType
@@ -173,6 +180,18 @@ func subTypeOf(fieldType reflect.Type, elemType *reflect.Type) reflect.Type {
 	return nil
 }
 
+// isKeyType returns whether the type is a key that is saved as
+// a datastore.Key, e.g. *hrd.Key, other than datastore.Key itself.
+func isKeyType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Ptr && typ.Implements(typeOfDSKeyConverter)
+}
+
+// isKeyFieldType returns whether the type is a key type
+// or a slice of a key type (see isKeyType).
+func isKeyFieldType(typ reflect.Type) bool {
+	return isKeyType(typ) || (typ.Kind() == reflect.Slice && isKeyType(typ.Elem()))
+}
+
 func calcLabel(f *structor.FieldCodec) string {
 	label := f.Tag.Values[0]
 	if label == "" {
@@ -2,7 +2,10 @@ package trafo
 
 import (
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
 	"github.com/101loops/structor"
+
+	ds "appengine/datastore"
 )
 
 var _ = Describe("Codec", func() {
@@ -15,20 +18,47 @@ var _ = Describe("Codec", func() {
 		}
 
 		entity := &MyModel{}
-		err := CodecSet.Add(entity)
+		err := codecs.Add(entity)
 		Check(err, IsNil)
 
 		var codec *structor.Codec
-		codec, err = getCodec(entity)
+		codec, err = getCodec(codecs, entity)
 		Check(err, IsNil)
 		Check(codec, NotNil)
 		Check(codec.Complete, IsTrue)
 	})
 
+	It("should accept key fields", func() {
+		type MyModel struct {
+			DSKey  *ds.Key
+			Key    *types.Key
+			Keys   []*types.Key
+			Nested struct {
+				Key *types.Key
+			}
+		}
+
+		err := codecs.Add(MyModel{})
+		Check(err, IsNil)
+	})
+
+	It("should only return codec from its codec set", func() {
+		type MyModel struct{}
+		codecs.AddMust(MyModel{})
+
+		codec, err := getCodec(NewCodecSet(), MyModel{})
+		Check(codec, IsNil)
+		Check(err, HasOccurred)
+
+		codec, err = getCodec(nil, MyModel{})
+		Check(codec, IsNil)
+		Check(err, ErrorContains, "no codec set")
+	})
+
 	// ==== ERRORS
 
 	It("should return error for invalid codec", func() {
-		codec, err := getCodec("invalid-type")
+		codec, err := getCodec(codecs, "invalid-type")
 
 		Check(codec, IsNil)
 		Check(err, ErrorContains, `value is not a struct, struct pointer or reflect.Type - but "string"`)
@@ -38,14 +68,14 @@ var _ = Describe("Codec", func() {
 		type InvalidModel struct {
 			InvalidName string `datastore:"$invalid-name"`
 		}
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `field "InvalidName" begins with invalid character '$'`)
 
 		// as sub-field:
 		type Wrapper struct {
 			Inner InvalidModel
 		}
-		err = CodecSet.Add(Wrapper{})
+		err = codecs.Add(Wrapper{})
 		Check(err, ErrorContains, `field "InvalidName" begins with invalid character '$'`)
 	})
 
@@ -53,14 +83,14 @@ var _ = Describe("Codec", func() {
 		type InvalidModel struct {
 			InvalidName string `datastore:"invalid@name"`
 		}
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `field "InvalidName" contains invalid character '@'`)
 
 		// as sub-field:
 		type Wrapper struct {
 			Inner InvalidModel
 		}
-		err = CodecSet.Add(Wrapper{})
+		err = codecs.Add(Wrapper{})
 		Check(err, ErrorContains, `field "InvalidName" contains invalid character '@'`)
 	})
 
@@ -69,7 +99,7 @@ var _ = Describe("Codec", func() {
 			ID1 string `datastore:"id"`
 			ID2 string `datastore:"id"`
 		}
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `duplicate field name "id"`)
 
 		// from sub-field:
@@ -80,7 +110,7 @@ var _ = Describe("Codec", func() {
 			ID string `datastore:"id"`
 			InnerModel
 		}
-		err = CodecSet.Add(MyModel{})
+		err = codecs.Add(MyModel{})
 		Check(err, ErrorContains, `duplicate field name "id"`)
 	})
 
@@ -88,7 +118,7 @@ var _ = Describe("Codec", func() {
 		type InvalidModel struct {
 			Ptr *string
 		}
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `field "Ptr" has invalid type 'pointer'`)
 	})
 
@@ -96,7 +126,7 @@ var _ = Describe("Codec", func() {
 		type InvalidModel struct {
 			Map map[int]string
 		}
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `field "Map" has invalid map key type 'int' - only 'string' is allowed`)
 	})
 
@@ -105,7 +135,7 @@ var _ = Describe("Codec", func() {
 			Recursive []InvalidModel
 		}
 
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `recursive struct at field "Recursive"`)
 	})
 
@@ -117,7 +147,7 @@ var _ = Describe("Codec", func() {
 			OuterSlide []Model
 		}
 
-		err := CodecSet.Add(InvalidModel{})
+		err := codecs.Add(InvalidModel{})
 		Check(err, ErrorContains, `field "OuterSlide" leads to a slice of slices`)
 	})
 })
@@ -22,9 +22,12 @@ type Doc struct {
 
 	// codec of the entity.
 	codec *structor.Codec
+
+	// codecs contains the codecs of nested entities.
+	codecs *structor.Set
 }
 
-func newDoc(srcVal reflect.Value) (*Doc, error) {
+func newDoc(codecs *structor.Set, srcVal reflect.Value) (*Doc, error) {
 	srcType := srcVal.Type()
 	srcKind := srcVal.Kind()
 	switch srcKind {
@@ -39,20 +42,20 @@ func newDoc(srcVal reflect.Value) (*Doc, error) {
 		return nil, fmt.Errorf("invalid value kind %q (wanted struct or struct pointer)", srcKind)
 	}
 
-	codec, err := getCodec(srcType)
+	codec, err := getCodec(codecs, srcType)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Doc{srcVal, codec}, nil
+	return &Doc{srcVal, codec, codecs}, nil
 }
 
-func newDocFromInst(src interface{}) (*Doc, error) {
-	return newDoc(reflect.ValueOf(src))
+func newDocFromInst(codecs *structor.Set, src interface{}) (*Doc, error) {
+	return newDoc(codecs, reflect.ValueOf(src))
 }
 
-func newDocFromType(typ reflect.Type) (*Doc, error) {
-	return newDoc(reflect.New(typ.Elem()))
+func newDocFromType(codecs *structor.Set, typ reflect.Type) (*Doc, error) {
+	return newDoc(codecs, reflect.New(typ.Elem()))
 }
 
 // Nil sets the value of the entity to nil.
@@ -76,7 +79,9 @@ func (doc *Doc) setKey(key *types.Key) {
 	src := doc.get()
 
 	var parentKey = key.Parent
-	if parentKey != nil {
+	if parent, ok := src.(entity.ParentKeyer); ok && parentKey != nil {
+		parent.SetParentKey(types.ExportKey(parentKey))
+	} else if parentKey != nil {
 		id := parentKey.IntID
 		if parent, ok := src.(entity.ParentNumIdentifier); id != 0 && ok {
 			parent.SetParent(parentKey.Kind, id)
@@ -13,44 +13,44 @@ var _ = Describe("Doc", func() {
 	type UnknownModel struct{}
 
 	BeforeEach(func() {
-		CodecSet.AddMust(MyModel{})
+		codecs.AddMust(MyModel{})
 	})
 
 	Context("create from instance", func() {
 
 		It("should create new Doc from known struct", func() {
-			doc, err := newDocFromInst(MyModel{})
+			doc, err := newDocFromInst(codecs, MyModel{})
 			Check(err, IsNil)
 			Check(doc, NotNil)
 		})
 
 		It("should create new Doc from pointer to known struct", func() {
-			doc, err := newDocFromInst(&MyModel{})
+			doc, err := newDocFromInst(codecs, &MyModel{})
 			Check(err, IsNil)
 			Check(doc, NotNil)
 		})
 
 		It("should not create new Doc from unknown struct", func() {
-			doc, err := newDocFromInst(UnknownModel{})
+			doc, err := newDocFromInst(codecs, UnknownModel{})
 			Check(doc, IsNil)
 			Check(err, ErrorContains, "no registered codec found for type 'trafo.UnknownModel'")
 		})
 
 		It("should not create new Doc from pointer to unknown struct", func() {
-			doc, err := newDocFromInst(&UnknownModel{})
+			doc, err := newDocFromInst(codecs, &UnknownModel{})
 			Check(doc, IsNil)
 			Check(err, ErrorContains, "no registered codec found for type 'trafo.UnknownModel'")
 		})
 
 		It("should not create new Doc from non-struct", func() {
-			doc, err := newDocFromInst("invalid")
+			doc, err := newDocFromInst(codecs, "invalid")
 			Check(doc, IsNil)
 			Check(err, ErrorContains, `invalid value kind "string" (wanted struct or struct pointer)`)
 		})
 
 		It("should not create new Doc from pointer to non-struct", func() {
 			invalidEntity := "invalid"
-			doc, err := newDocFromInst(&invalidEntity)
+			doc, err := newDocFromInst(codecs, &invalidEntity)
 			Check(doc, IsNil)
 			Check(err, ErrorContains, `invalid value kind "string" (wanted struct pointer)`)
 		})
@@ -59,13 +59,13 @@ var _ = Describe("Doc", func() {
 	Context("create from type", func() {
 
 		It("should create new Doc from pointer to known struct", func() {
-			doc, err := newDocFromType(reflect.TypeOf(&MyModel{}))
+			doc, err := newDocFromType(codecs, reflect.TypeOf(&MyModel{}))
 			Check(err, IsNil)
 			Check(doc, NotNil)
 		})
 
 		It("should not create new Doc from pointer to unknown struct", func() {
-			doc, err := newDocFromType(reflect.TypeOf(&UnknownModel{}))
+			doc, err := newDocFromType(codecs, reflect.TypeOf(&UnknownModel{}))
 			Check(doc, IsNil)
 			Check(err, ErrorContains, "no registered codec found for type 'trafo.UnknownModel'")
 		})
@@ -74,7 +74,7 @@ var _ = Describe("Doc", func() {
 	//	It("should set to nil", func() {
 	//		entity := &MyModel{}
 	//
-	//		doc, err := newDocFromInst(&entity)
+	//		doc, err := newDocFromInst(codecs, &entity)
 	//		Check(err, IsNil)
 	//		Check(entity, NotNil)
 	//
@@ -86,9 +86,9 @@ var _ = Describe("Doc", func() {
 
 		It("should set key from with numeric id", func() {
 			entity := fixture.EntityWithNumID{}
-			CodecSet.AddMust(entity)
+			codecs.AddMust(entity)
 
-			doc, _ := newDocFromInst(&entity)
+			doc, _ := newDocFromInst(codecs, &entity)
 			doc.setKey(types.NewKey("my-kind", "", 42, nil))
 
 			Check(entity.ID(), EqualsNum, 42)
@@ -96,9 +96,9 @@ var _ = Describe("Doc", func() {
 
 		It("should set key from text id", func() {
 			entity := fixture.EntityWithTextID{}
-			CodecSet.AddMust(entity)
+			codecs.AddMust(entity)
 
-			doc, _ := newDocFromInst(&entity)
+			doc, _ := newDocFromInst(codecs, &entity)
 			doc.setKey(types.NewKey("my-kind", "abc", 0, nil))
 
 			Check(entity.ID(), Equals, "abc")
@@ -106,9 +106,9 @@ var _ = Describe("Doc", func() {
 
 		It("should set key from numeric parent id", func() {
 			entity := fixture.EntityWithParentNumID{}
-			CodecSet.AddMust(entity)
+			codecs.AddMust(entity)
 
-			doc, _ := newDocFromInst(&entity)
+			doc, _ := newDocFromInst(codecs, &entity)
 			doc.setKey(types.NewKey("my-kind", "", 1, types.NewKey("my-kind", "", 2, nil)))
 
 			Check(entity.ID(), EqualsNum, 1)
@@ -117,11 +117,23 @@ var _ = Describe("Doc", func() {
 			Check(parentID, EqualsNum, 2)
 		})
 
+		It("should set key from parent key", func() {
+			entity := fixture.EntityWithParentKey{}
+			codecs.AddMust(entity)
+
+			parent := types.NewKey("my-parent", "", 2, types.NewKey("my-grandparent", "xyz", 0, nil))
+			doc, _ := newDocFromInst(codecs, &entity)
+			doc.setKey(types.NewKey("my-kind", "", 1, parent))
+
+			Check(entity.ID(), EqualsNum, 1)
+			Check(entity.ParentKey().Encode(), Equals, parent.Encode())
+		})
+
 		It("should set key from text parent id", func() {
 			entity := fixture.EntityWithParentTextID{}
-			CodecSet.AddMust(entity)
+			codecs.AddMust(entity)
 
-			doc, _ := newDocFromInst(&entity)
+			doc, _ := newDocFromInst(codecs, &entity)
 			doc.setKey(types.NewKey("my-kind", "abc", 0, types.NewKey("my-kind", "xyz", 0, nil)))
 
 			Check(entity.ID(), Equals, "abc")
@@ -5,7 +5,9 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/101loops/hrd/entity"
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/structor"
 
 	ae "appengine"
 	ds "appengine/datastore"
@@ -13,6 +15,7 @@ import (
 
 // DocList represents a collection of Doc.
 type DocList struct {
+	codecs   *structor.Set
 	list     []*Doc
 	keyList  []*types.Key
 	srcVal   reflect.Value
@@ -52,7 +55,7 @@ func NewReadableDocList(kind *types.Kind, src interface{}) (*DocList, error) {
 	for i := 0; i < srcCollLen; i++ {
 		entity := srcColl.Index(i).Interface()
 
-		d, err := newDocFromInst(entity)
+		d, err := newDocFromInst(kind.Codecs, entity)
 		if err != nil {
 			return nil, err
 		}
@@ -65,12 +68,12 @@ func NewReadableDocList(kind *types.Kind, src interface{}) (*DocList, error) {
 		keys[i] = key
 	}
 
-	return &DocList{list: list, keyList: keys}, nil
+	return &DocList{codecs: kind.Codecs, list: list, keyList: keys}, nil
 }
 
 // NewWriteableDocList creates a new DocList, suitable for writing to it.
-func NewWriteableDocList(src interface{}, keys []*types.Key, multi bool) (*DocList, error) {
-	ret := &DocList{keyList: keys}
+func NewWriteableDocList(kind *types.Kind, src interface{}, keys []*types.Key, multi bool) (*DocList, error) {
+	ret := &DocList{codecs: kind.Codecs, keyList: keys}
 	keysLen := len(keys)
 
 	// resolve pointer
@@ -113,7 +116,7 @@ func NewWriteableDocList(src interface{}, keys []*types.Key, multi bool) (*DocLi
 		// generate list of doc
 		ret.srcVal = srcVal
 		for _, key := range keys {
-			d, err := newDocFromType(ret.elemType)
+			d, err := newDocFromType(ret.codecs, ret.elemType)
 			if err != nil {
 				return nil, err
 			}
@@ -125,7 +128,7 @@ func NewWriteableDocList(src interface{}, keys []*types.Key, multi bool) (*DocLi
 		}
 
 		srcVal.Set(reflect.New(srcType.Elem()))
-		d, err := newDoc(srcVal)
+		d, err := newDoc(ret.codecs, srcVal)
 		if err != nil {
 			return nil, err
 		}
@@ -147,13 +150,56 @@ func (l *DocList) Keys() []*types.Key {
 	return l.keyList
 }
 
+// GenerateTextIDs completes the incomplete keys of the entities with text IDs
+// using the passed-in function and assigns the generated IDs to the entities.
+func (l *DocList) GenerateTextIDs(newID func() (string, error)) error {
+	_, err := l.generateTextIDs(newID, true)
+	return err
+}
+
+// GeneratedTextIDKeys returns the list's keys, completed like GenerateTextIDs
+// does, but on copies: neither the list nor the entities are changed.
+func (l *DocList) GeneratedTextIDKeys(newID func() (string, error)) ([]*types.Key, error) {
+	return l.generateTextIDs(newID, false)
+}
+
+func (l *DocList) generateTextIDs(newID func() (string, error), assign bool) ([]*types.Key, error) {
+	keys := make([]*types.Key, len(l.keyList))
+	copy(keys, l.keyList)
+	for i, doc := range l.list {
+		key := keys[i]
+		if !key.Incomplete() {
+			continue
+		}
+		if _, ok := doc.get().(entity.TextIdentifier); !ok {
+			continue
+		}
+
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		if id == "" {
+			continue
+		}
+		if assign {
+			key.StringID = id
+			doc.setKey(key)
+		} else {
+			keys[i] = types.NewKey(key.Kind, id, 0, key.Parent)
+			keys[i].Namespace = key.Namespace
+		}
+	}
+	return keys, nil
+}
+
 // Get returns the list's nth Doc.
 // it is created first if it doesn't already exist.
 func (l *DocList) Get(nth int) (ret *Doc) {
 	if nth < len(l.list) {
 		ret = l.list[nth]
 	} else {
-		ret, _ = newDocFromType(l.elemType)
+		ret, _ = newDocFromType(l.codecs, l.elemType)
 	}
 	return
 }
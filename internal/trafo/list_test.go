@@ -18,8 +18,9 @@ var _ = Describe("DocList", func() {
 	type InvalidModel struct{}
 
 	BeforeEach(func() {
-		CodecSet.AddMust(&InvalidModel{})
+		codecs.AddMust(&InvalidModel{})
 		kind = types.NewKind(ctx, "my-kind")
+		kind.Codecs = codecs
 
 		keys = make([]*types.Key, 4)
 		entities = make([]*fixture.EntityWithNumID, 4)
@@ -81,7 +82,7 @@ var _ = Describe("DocList", func() {
 	Context("writeable list", func() {
 
 		It("should create list from struct pointer", func() {
-			list, err := NewWriteableDocList(&(entities[0]), keys[0:1], false)
+			list, err := NewWriteableDocList(kind, &(entities[0]), keys[0:1], false)
 			Check(err, IsNil)
 			Check(list, NotNil)
 			Check(list.list, HasLen, 1)
@@ -90,7 +91,7 @@ var _ = Describe("DocList", func() {
 
 		It("should create list from slice", func() {
 			var entitySlice []*fixture.EntityWithNumID
-			list, err := NewWriteableDocList(&entitySlice, keys, true)
+			list, err := NewWriteableDocList(kind, &entitySlice, keys, true)
 
 			Check(err, IsNil)
 			Check(list, NotNil)
@@ -100,7 +101,7 @@ var _ = Describe("DocList", func() {
 
 		It("should create list from map of entities by string", func() {
 			var entityMap map[string]*fixture.EntityWithNumID
-			list, err := NewWriteableDocList(&entityMap, keys, true)
+			list, err := NewWriteableDocList(kind, &entityMap, keys, true)
 
 			Check(err, IsNil)
 			Check(list, NotNil)
@@ -110,7 +111,7 @@ var _ = Describe("DocList", func() {
 
 		It("should create list from map of entities by int64", func() {
 			var entityMap map[int64]*fixture.EntityWithNumID
-			list, err := NewWriteableDocList(&entityMap, keys, true)
+			list, err := NewWriteableDocList(kind, &entityMap, keys, true)
 
 			Check(err, IsNil)
 			Check(list, NotNil)
@@ -120,7 +121,7 @@ var _ = Describe("DocList", func() {
 
 		It("should create list from map of entities by Key pointer", func() {
 			var entityMap map[*types.Key]*fixture.EntityWithNumID
-			list, err := NewWriteableDocList(&entityMap, keys, true)
+			list, err := NewWriteableDocList(kind, &entityMap, keys, true)
 
 			Check(err, IsNil)
 			Check(list, NotNil)
@@ -130,7 +131,7 @@ var _ = Describe("DocList", func() {
 
 		It("should create list from map", func() {
 			var entityMap map[string]*fixture.EntityWithNumID
-			list, err := NewWriteableDocList(&entityMap, keys, true)
+			list, err := NewWriteableDocList(kind, &entityMap, keys, true)
 
 			Check(err, IsNil)
 			Check(list, NotNil)
@@ -142,19 +143,19 @@ var _ = Describe("DocList", func() {
 
 		It("should not create list from nil pointer", func() {
 			var entity *fixture.EntityWithNumID
-			list, err := NewWriteableDocList(entity, keys[0:1], false)
+			list, err := NewWriteableDocList(kind, entity, keys[0:1], false)
 			Check(list, IsNil)
 			Check(err, ErrorContains, `invalid value kind "ptr" (wanted non-nil pointer)`)
 		})
 
 		It("should not create list from non-pointer", func() {
-			list, err := NewWriteableDocList("invalid", keys[0:1], false)
+			list, err := NewWriteableDocList(kind, "invalid", keys[0:1], false)
 			Check(list, IsNil)
 			Check(err, ErrorContains, `invalid value kind "string" (wanted non-nil pointer)`)
 		})
 
 		//	It("should not create list from non-reference struct", func() {
-		//		list, err := NewWriteableDocList(entities[0], keys[0:1], false)
+		//		list, err := NewWriteableDocList(kind, entities[0], keys[0:1], false)
 		//		Check(list, IsNil)
 		//		Check(err, ErrorContains, `invalid value kind "ptr" (wanted non-nil pointer)`)
 		//	})
@@ -162,7 +163,7 @@ var _ = Describe("DocList", func() {
 		Context("single key", func() {
 
 			It("should not create list for single entity and multiple keys", func() {
-				list, err := NewWriteableDocList(&(entities[0]), keys, false)
+				list, err := NewWriteableDocList(kind, &(entities[0]), keys, false)
 				Check(list, IsNil)
 				Check(err, ErrorContains, `wanted exactly 1 key (got 4)`)
 			})
@@ -171,34 +172,34 @@ var _ = Describe("DocList", func() {
 		Context("multiple keys", func() {
 
 			It("should not create list with multiple keys from struct", func() {
-				list, err := NewWriteableDocList(entities[0], keys, true)
+				list, err := NewWriteableDocList(kind, entities[0], keys, true)
 				Check(list, IsNil)
 				Check(err, ErrorContains, `invalid value kind "struct" (wanted map or slice)`)
 			})
 
 			It("should not create list with multiple keys from struct pointer", func() {
-				list, err := NewWriteableDocList(&(entities[0]), keys, true)
+				list, err := NewWriteableDocList(kind, &(entities[0]), keys, true)
 				Check(list, IsNil)
 				Check(err, ErrorContains, `invalid value kind "ptr" (wanted map or slice)`)
 			})
 
 			It("should not create list from map with invalid key type", func() {
 				var entityMap map[bool]*fixture.EntityWithNumID
-				list, err := NewWriteableDocList(&entityMap, keys, true)
+				list, err := NewWriteableDocList(kind, &entityMap, keys, true)
 				Check(list, IsNil)
 				Check(err, ErrorContains, "invalid value key")
 			})
 
 			It("should not create list from slice of non-structs", func() {
 				var invalidMap []string
-				list, err := NewWriteableDocList(&invalidMap, keys, true)
+				list, err := NewWriteableDocList(kind, &invalidMap, keys, true)
 				Check(list, IsNil)
 				Check(err, ErrorContains, `invalid value element type "string" (wanted struct pointer)`)
 			})
 
 			It("should not create list from slice of non-struct pointers", func() {
 				var invalidMap []*string
-				list, err := NewWriteableDocList(&invalidMap, keys, true)
+				list, err := NewWriteableDocList(kind, &invalidMap, keys, true)
 				Check(list, IsNil)
 				Check(err, ErrorContains, `invalid value element type "*string" (wanted struct pointer)`)
 			})
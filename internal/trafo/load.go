@@ -1,7 +1,13 @@
 package trafo
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
+
 	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/structor"
 
 	ds "appengine/datastore"
 )
@@ -22,10 +28,12 @@ func (doc *Doc) Load(c <-chan ds.Property) error {
 		return err
 	}
 
-	//	c, err = doc.transformProperties(c)
-	//	if err != nil {
-	//		return err
-	//	}
+	// keys like *hrd.Key are not supported by datastore.LoadStruct
+	if keyFields := doc.keyFields(); len(keyFields) > 0 {
+		if c, err = loadKeyFields(c, reflect.Indirect(doc.srcVal), keyFields); err != nil {
+			return err
+		}
+	}
 
 	if err = ds.LoadStruct(dst, c); err != nil {
 		return err
@@ -39,23 +47,148 @@ func (doc *Doc) Load(c <-chan ds.Property) error {
 	return err
 }
 
-//func (doc *Doc) transformProperties(c <-chan ds.Property) (chan ds.Property, error) {
-//	var props []ds.Property
-//	for prop := range c {
-//		props = append(props, prop)
-//	}
-//	c2 := make(chan ds.Property, len(props))
-//	for _, prop := range props {
-//		prop2, err := doc.transformProperty(prop)
-//		if err != nil {
-//			return nil, err
-//		}
-//		c2 <- prop2
-//	}
-//	close(c2)
-//	return c2, nil
-//}
-//
-//func (doc *Doc) transformProperty(prop ds.Property) (ds.Property, error) {
-//	return prop, nil
-//}
+// keyFieldPath leads from an entity to a field that holds keys other
+// than datastore.Key, e.g. *hrd.Key, possibly in a slice of structs.
+type keyFieldPath []keyFieldStep
+
+// keyFieldStep is a field on a keyFieldPath.
+type keyFieldStep struct {
+	index int
+	slice bool // whether the field is a slice of structs
+}
+
+// field returns the field the path leads to in the passed-in entity,
+// which is in the struct at index i of a slice on the path.
+// The slice grows as needed.
+func (path keyFieldPath) field(v reflect.Value, i int) reflect.Value {
+	for _, step := range path {
+		v = v.Field(step.index)
+		if step.slice {
+			for v.Len() <= i {
+				v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+			}
+			v = v.Index(i)
+		}
+	}
+	return v
+}
+
+// keyFieldsCache contains the key fields of a codec's type
+// by the name of their property.
+var keyFieldsCache = struct {
+	sync.RWMutex
+	byCodec map[*structor.Codec]map[string]keyFieldPath
+}{byCodec: make(map[*structor.Codec]map[string]keyFieldPath)}
+
+// keyFields returns the fields of the entity type that hold keys other
+// than datastore.Key, e.g. *hrd.Key, by the name of their property.
+func (doc *Doc) keyFields() map[string]keyFieldPath {
+	keyFieldsCache.RLock()
+	ret, ok := keyFieldsCache.byCodec[doc.codec]
+	keyFieldsCache.RUnlock()
+	if ok {
+		return ret
+	}
+
+	ret = make(map[string]keyFieldPath)
+	collectKeyFields(doc.codecs, doc.codec, nil, "", ret)
+
+	keyFieldsCache.Lock()
+	keyFieldsCache.byCodec[doc.codec] = ret
+	keyFieldsCache.Unlock()
+	return ret
+}
+
+func collectKeyFields(codecs *structor.Set, codec *structor.Codec, path keyFieldPath, prefix string, ret map[string]keyFieldPath) {
+	for _, fCodec := range codec.Fields {
+		field := codec.Type.Field(fCodec.Index)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		name := fCodec.Attrs["label"].(string)
+		if name == "-" {
+			continue
+		}
+
+		fType := field.Type
+		step := keyFieldStep{index: fCodec.Index}
+		if fType.Kind() == reflect.Slice && fType.Elem().Kind() == reflect.Struct {
+			fType = fType.Elem()
+			step.slice = true
+		}
+		fPath := append(append(keyFieldPath{}, path...), step)
+
+		if isKeyFieldType(fType) {
+			if prefix != "" {
+				name = prefix + propertySeparator + name
+			}
+			ret[name] = fPath
+		} else if fType.Kind() == reflect.Struct && fType != typeOfTime && fType != typeOfGeoPoint {
+			if subCodec, err := getCodec(codecs, fType); err == nil {
+				collectKeyFields(codecs, subCodec, fPath, name, ret)
+			}
+		}
+	}
+}
+
+// loadKeyFields loads the key properties from the passed-in channel into
+// their fields of the entity. It returns a channel with the remaining
+// properties. Like datastore.LoadStruct, it loads the n-th property of
+// a name into the n-th struct of a slice.
+func loadKeyFields(c <-chan ds.Property, v reflect.Value, fields map[string]keyFieldPath) (<-chan ds.Property, error) {
+	var props []ds.Property
+	var err error
+	counts := make(map[string]int)
+	for prop := range c {
+		path, ok := fields[prop.Name]
+		if !ok {
+			props = append(props, prop)
+			continue
+		}
+		if err == nil {
+			err = loadKeyField(path.field(v, counts[prop.Name]), prop)
+		}
+		counts[prop.Name]++
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(chan ds.Property, len(props))
+	for _, prop := range props {
+		ret <- prop
+	}
+	close(ret)
+	return ret, nil
+}
+
+func loadKeyField(field reflect.Value, prop ds.Property) error {
+	dsKey, ok := prop.Value.(*ds.Key)
+	if !ok && prop.Value != nil {
+		return fmt.Errorf("cannot load %T into key property %q", prop.Value, prop.Name)
+	}
+
+	keyType := field.Type()
+	if field.Kind() == reflect.Slice {
+		keyType = keyType.Elem()
+	}
+
+	val := reflect.Zero(keyType)
+	if dsKey != nil {
+		key := types.ExportKey(types.ImportKey(dsKey))
+		if keyType.Implements(typeOfKeySetter) {
+			val = reflect.New(keyType.Elem())
+			val.Interface().(types.KeySetter).SetKey(key)
+		} else if val = reflect.ValueOf(key); !val.Type().AssignableTo(keyType) {
+			return fmt.Errorf("cannot load key property %q into %q", prop.Name, keyType)
+		}
+	}
+
+	if field.Kind() == reflect.Slice {
+		field.Set(reflect.Append(field, val))
+	} else {
+		field.Set(val)
+	}
+	return nil
+}
@@ -3,6 +3,10 @@ package trafo
 import (
 	"fmt"
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
 
 	ds "appengine/datastore"
 )
@@ -66,6 +70,137 @@ var _ = Describe("Doc: Load", func() {
 		Check(res.InnerModel2.Name, Equals, "her")
 	})
 
+	Context("key fields", func() {
+
+		var exportKey func(*types.Key) entity.Key
+
+		type MySub struct {
+			Name string
+			K    *loadKey
+		}
+
+		type MyModel struct {
+			Name string
+			K    *loadKey
+			KS   []*loadKey
+			NK   *loadKey
+			Sub  MySub
+			Subs []MySub
+		}
+
+		BeforeEach(func() {
+			exportKey = types.ExportKey
+			types.ExportKey = func(key *types.Key) entity.Key {
+				return &loadKey{key}
+			}
+		})
+
+		AfterEach(func() {
+			types.ExportKey = exportKey
+		})
+
+		It("should load key properties into key fields", func() {
+			dsKey1 := ds.NewKey(ctx, "kind", "", 1, nil)
+			dsKey2 := ds.NewKey(ctx, "kind", "two", 0, nil)
+
+			doc, c, err := load(&MyModel{}, []ds.Property{
+				{Name: "Name", Value: "name"},
+				{Name: "K", Value: dsKey1},
+				{Name: "KS", Value: dsKey1, Multiple: true},
+				{Name: "KS", Value: dsKey2, Multiple: true},
+				{Name: "NK", Value: (*ds.Key)(nil)},
+			})
+			Check(err, IsNil)
+			Check(c, IsClosed)
+
+			res := (doc.get()).(*MyModel)
+			Check(res.Name, Equals, "name")
+			Check(res.K.IntID(), EqualsNum, 1)
+			Check(res.KS, HasLen, 2)
+			Check(res.KS[0].IntID(), EqualsNum, 1)
+			Check(res.KS[1].StringID(), Equals, "two")
+			Check(res.NK, IsNil)
+		})
+
+		It("should load key properties into key fields of nested structs", func() {
+			dsKey1 := ds.NewKey(ctx, "kind", "", 1, nil)
+			dsKey2 := ds.NewKey(ctx, "kind", "two", 0, nil)
+
+			doc, c, err := load(&MyModel{}, []ds.Property{
+				{Name: "Sub.K", Value: dsKey1},
+				{Name: "Subs.K", Value: dsKey2, Multiple: true},
+				{Name: "Subs.K", Value: (*ds.Key)(nil), Multiple: true},
+				{Name: "Subs.K", Value: dsKey1, Multiple: true},
+			})
+			Check(err, IsNil)
+			Check(c, IsClosed)
+
+			res := (doc.get()).(*MyModel)
+			Check(res.Sub.K.IntID(), EqualsNum, 1)
+			Check(res.Subs, HasLen, 3)
+			Check(res.Subs[0].K.StringID(), Equals, "two")
+			Check(res.Subs[1].K, IsNil)
+			Check(res.Subs[2].K.IntID(), EqualsNum, 1)
+		})
+
+		It("should round-trip key fields", func() {
+			key1 := &loadKey{types.NewKey("kind", "", 1, nil)}
+			key2 := &loadKey{types.NewKey("kind", "two", 0, nil)}
+			src := &MyModel{
+				K: key1, KS: []*loadKey{key1, key2}, Sub: MySub{K: key2},
+				Subs: []MySub{{Name: "a", K: key2}, {Name: "b", K: key1}},
+			}
+
+			props, err := save(src)
+			Check(err, IsNil)
+			loadProps := make([]ds.Property, len(props))
+			for i, prop := range props {
+				loadProps[i] = *prop
+			}
+
+			doc, _, err := load(&MyModel{}, loadProps)
+			Check(err, IsNil)
+
+			res := (doc.get()).(*MyModel)
+			Check(res.K.Encode(), Equals, key1.Encode())
+			Check(res.KS, HasLen, 2)
+			Check(res.KS[1].Encode(), Equals, key2.Encode())
+			Check(res.Sub.K.Encode(), Equals, key2.Encode())
+			Check(res.Subs, HasLen, 2)
+			Check(res.Subs[0].K.Encode(), Equals, key2.Encode())
+			Check(res.Subs[1].K.Encode(), Equals, key1.Encode())
+		})
+
+		It("should cache the key fields of a codec", func() {
+			doc, _, err := load(&MyModel{}, nil)
+			Check(err, IsNil)
+
+			fields := doc.keyFields()
+			Check(fields, HasLen, 5)
+			Check(fields["Subs.K"], Equals, keyFieldPath{{index: 5, slice: true}, {index: 1}})
+
+			keyFieldsCache.RLock()
+			cached := keyFieldsCache.byCodec[doc.codec]
+			keyFieldsCache.RUnlock()
+			Check(cached, Equals, fields)
+		})
+
+		It("should return an error for a non-key property", func() {
+			_, c, err := load(&MyModel{}, []ds.Property{{Name: "K", Value: "invalid"}})
+			Check(err, ErrorContains, `cannot load string into key property "K"`)
+			Check(c, IsClosed)
+		})
+
+		It("should return an error for a key of another type", func() {
+			types.ExportKey = exportKey
+
+			dsKey := ds.NewKey(ctx, "kind", "", 1, nil)
+			_, c, err := load(&MyModel{}, []ds.Property{{Name: "K", Value: dsKey}})
+			Check(err, ErrorContains, `cannot load key property "K" into "*trafo.loadKey"`)
+			Check(c, IsClosed)
+		})
+	})
+
 	// ==== ERRORS
 
 	It("should return an error when loading fails", func() {
@@ -106,8 +241,8 @@ var _ = Describe("Doc: Load", func() {
 })
 
 func load(src interface{}, props []ds.Property) (*Doc, chan ds.Property, error) {
-	CodecSet.AddMust(src)
-	doc, err := newDocFromInst(src)
+	codecs.AddMust(src)
+	doc, err := newDocFromInst(codecs, src)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -123,3 +258,15 @@ func load(src interface{}, props []ds.Property) (*Doc, chan ds.Property, error)
 	err = doc.Load(c)
 	return doc, c, err
 }
+
+// loadKey is a key type to be loaded into an entity's key fields.
+type loadKey struct {
+	key *types.Key
+}
+
+func (k *loadKey) ToDSKey(ctx ae.Context) *ds.Key { return k.key.ToDSKey(ctx) }
+func (k *loadKey) Kind() string                   { return k.key.Kind }
+func (k *loadKey) StringID() string               { return k.key.StringID }
+func (k *loadKey) IntID() int64                   { return k.key.IntID }
+func (k *loadKey) Namespace() string              { return k.key.Namespace }
+func (k *loadKey) Encode() string                 { return k.key.Encode() }
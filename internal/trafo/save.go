@@ -76,7 +76,7 @@ func (doc *Doc) toProperties(ctx ae.Context, prefix string, tags []string, multi
 		// for slice fields (that aren't []byte), save each element
 		if fVal.Kind() == reflect.Slice && fVal.Type() != typeOfByteSlice {
 			for i := 0; i < fVal.Len(); i++ {
-				props, err = fieldToProps(ctx, prefix, name, aggrTags, true, fVal.Index(i))
+				props, err = doc.fieldToProps(ctx, prefix, name, aggrTags, true, fVal.Index(i))
 				if err != nil {
 					return
 				}
@@ -85,7 +85,7 @@ func (doc *Doc) toProperties(ctx ae.Context, prefix string, tags []string, multi
 			continue
 		}
 
-		props, err = fieldToProps(ctx, prefix, name, aggrTags, multi, fVal)
+		props, err = doc.fieldToProps(ctx, prefix, name, aggrTags, multi, fVal)
 		if err != nil {
 			return
 		}
@@ -95,7 +95,7 @@ func (doc *Doc) toProperties(ctx ae.Context, prefix string, tags []string, multi
 	return
 }
 
-func fieldToProps(ctx ae.Context, prefix, name string, tags []string, multi bool, v reflect.Value) (props []*ds.Property, err error) {
+func (doc *Doc) fieldToProps(ctx ae.Context, prefix, name string, tags []string, multi bool, v reflect.Value) (props []*ds.Property, err error) {
 
 	// process tags
 	indexed := false
@@ -127,7 +127,11 @@ func fieldToProps(ctx ae.Context, prefix, name string, tags []string, multi bool
 	case time.Time, ae.BlobKey, ae.GeoPoint, *ds.Key:
 		p.Value = x
 	case types.DSKeyConverter:
-		p.Value = x.ToDSKey(ctx)
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			p.Value = (*ds.Key)(nil)
+		} else {
+			p.Value = x.ToDSKey(ctx)
+		}
 	case []byte:
 		p.Value = x
 		p.NoIndex = true
@@ -146,7 +150,7 @@ func fieldToProps(ctx ae.Context, prefix, name string, tags []string, multi bool
 				return nil, fmt.Errorf("unsupported property %q (unaddressable)", name)
 			}
 
-			sub, err := newDocFromInst(v.Addr().Interface())
+			sub, err := newDocFromInst(doc.codecs, v.Addr().Interface())
 			if err != nil {
 				return nil, fmt.Errorf("unsupported property %q (%v)", name, err)
 			}
@@ -81,6 +81,29 @@ var _ = Describe("Doc Save", func() {
 			Check(*props[5], Equals, ds.Property{"GP", entity.GP, true, false})
 		})
 
+		It("should serialize key pointers", func() {
+			type MyModel struct {
+				K  *types.Key
+				KS []*types.Key
+				NK *types.Key
+			}
+
+			dsKey1 := ds.NewKey(ctx, "kind", "", 1, nil)
+			dsKey2 := ds.NewKey(ctx, "kind", "", 2, nil)
+			entity := &MyModel{
+				K:  types.ImportKey(dsKey1),
+				KS: []*types.Key{types.ImportKey(dsKey1), types.ImportKey(dsKey2)},
+			}
+			props, err := save(entity)
+			Check(err, IsNil)
+			Check(props, NotNil).And(HasLen, 4)
+
+			Check(*props[0], Equals, ds.Property{"K", dsKey1, true, false})
+			Check(*props[1], Equals, ds.Property{"KS", dsKey1, true, true})
+			Check(*props[2], Equals, ds.Property{"KS", dsKey2, true, true})
+			Check(*props[3], Equals, ds.Property{"NK", (*ds.Key)(nil), true, false})
+		})
+
 		It("should serialize arbitrary complex fields", func() {
 			type Pair struct {
 				Key string `datastore:"key"`
@@ -282,9 +305,9 @@ var _ = Describe("Doc Save", func() {
 })
 
 func save(src interface{}) ([]*ds.Property, error) {
-	CodecSet.AddMust(src)
+	codecs.AddMust(src)
 
-	doc, err := newDocFromInst(src)
+	doc, err := newDocFromInst(codecs, src)
 	if err != nil {
 		panic(err)
 	}
@@ -5,26 +5,24 @@ import (
 
 	. "github.com/101loops/bdd"
 	"github.com/101loops/hrd/entity/fixture"
+	"github.com/101loops/hrd/internal/memctx"
 
-	"appengine/aetest"
+	ae "appengine"
 )
 
 var (
-	ctx aetest.Context
+	ctx    ae.Context
+	codecs = NewCodecSet()
 )
 
 func TestSuite(t *testing.T) {
-	var err error
-	ctx, err = aetest.NewContext(nil)
-	if err != nil {
-		panic(err)
-	}
-	defer ctx.Close()
+	ctx = memctx.New()
 
-	CodecSet.AddMust(&fixture.EntityWithNumID{})
-	CodecSet.AddMust(&fixture.EntityWithTextID{})
-	CodecSet.AddMust(&fixture.EntityWithParentNumID{})
-	CodecSet.AddMust(&fixture.EntityWithParentTextID{})
+	codecs.AddMust(&fixture.EntityWithNumID{})
+	codecs.AddMust(&fixture.EntityWithTextID{})
+	codecs.AddMust(&fixture.EntityWithParentNumID{})
+	codecs.AddMust(&fixture.EntityWithParentTextID{})
+	codecs.AddMust(&fixture.EntityWithParentKey{})
 
 	RunSpecs(t, "HRD Trafo Suite")
 }
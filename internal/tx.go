@@ -1,15 +1,16 @@
 package internal
 
 import (
+	"github.com/101loops/hrd/internal/types"
+
 	ae "appengine"
-	ds "appengine/datastore"
 )
 
 // Transact runs a function in a transaction.
-func Transact(ctx ae.Context, crossGroup bool, f func(_ ae.Context) error) error {
-	return ds.RunInTransaction(ctx, func(ctx ae.Context) error {
-		var dsErr error
-		dsErr = f(ctx)
-		return dsErr
-	}, &ds.TransactionOptions{XG: crossGroup})
+// The context may notify a listener of the transaction's service calls.
+func (b *Backend) Transact(ctx ae.Context, crossGroup bool, f func(_ ae.Context) error) error {
+	run, done := b.local.transaction(types.BaseContext(ctx), f)
+	defer done()
+
+	return b.ds.RunInTransaction(ctx, run, crossGroup)
 }
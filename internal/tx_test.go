@@ -2,6 +2,7 @@ package internal
 
 import (
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
 
 	ae "appengine"
 )
@@ -11,20 +12,20 @@ var _ = Describe("Transact", func() {
 	It("should run operations inside a transaction", func() {
 		kind := randomKind()
 
-		Transact(ctx, false, func(ctx ae.Context) error {
-			key, err := Put(kind, &MyModel{}, false)
+		backend.Transact(ctx, false, func(ctx ae.Context) error {
+			key, err := backend.Put(kind, &MyModel{}, &types.Opts{})
 			Check(err, IsNil)
 			Check(key, NotNil)
 
 			var entity *MyModel
-			keys, err := Get(kind, key, &entity, false, false)
+			keys, err := backend.Get(kind, key, &entity, noCache, false)
 			Check(err, IsNil)
 			Check(keys[0].Synced, NotNil)
 
-			err = DeleteKeys(kind, keys...)
+			err = backend.Delete(kind, keys...)
 			Check(err, IsNil)
 
-			keys, err = Get(kind, key, &entity, false, false)
+			keys, err = backend.Get(kind, key, &entity, noCache, false)
 			Check(err, IsNil)
 			Check(keys[0].Synced, IsNil)
 
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"time"
+
+	"code.google.com/p/goprotobuf/proto"
+	"github.com/101loops/hrd/rpc"
+
+	ae "appengine"
+	"appengine_internal"
+)
+
+// listenContext is a context that notifies a listener of its service calls.
+type listenContext struct {
+	ae.Context
+	listener rpc.Listener
+}
+
+// ListenContext returns a context that notifies
+// the passed-in listener of every service call.
+func ListenContext(ctx ae.Context, listener rpc.Listener) ae.Context {
+	return &listenContext{BaseContext(ctx), listener}
+}
+
+// BaseContext returns the context that was passed to ListenContext,
+// or else the passed-in context.
+func BaseContext(ctx ae.Context) ae.Context {
+	if lc, ok := ctx.(*listenContext); ok {
+		return lc.Context
+	}
+	return ctx
+}
+
+func (c *listenContext) Call(service, method string, in, out appengine_internal.ProtoMessage, opts *appengine_internal.CallOptions) error {
+	start := time.Now()
+	err := c.Context.Call(service, method, in, out, opts)
+
+	c.listener.OnCall(c.Context, rpc.Call{
+		Service:     service,
+		Method:      method,
+		RequestSize: proto.Size(in),
+		Duration:    time.Since(start),
+		Err:         err,
+	})
+	return err
+}
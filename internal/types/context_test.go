@@ -0,0 +1,36 @@
+package types
+
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/rpc"
+
+	ae "appengine"
+	"appengine/memcache"
+)
+
+var _ = Describe("Context", func() {
+
+	It("should notify a listener of service calls", func() {
+		var calls []rpc.Call
+		listener := rpc.ListenerFunc(func(_ ae.Context, call rpc.Call) {
+			calls = append(calls, call)
+		})
+
+		memcache.Get(ListenContext(ctx, listener), "my-key")
+
+		Check(calls, HasLen, 1)
+		Check(calls[0].Service, Equals, "memcache")
+		Check(calls[0].Method, Equals, "Get")
+		Check(calls[0].RequestSize, IsGreaterThan, 0)
+		Check(calls[0].Err, IsNil)
+	})
+
+	It("should return the base context", func() {
+		listener := rpc.NewCounter()
+		listenCtx := ListenContext(ctx, listener)
+
+		Check(BaseContext(ctx), Equals, ctx)
+		Check(BaseContext(listenCtx), Equals, ctx)
+		Check(BaseContext(ListenContext(listenCtx, listener)), Equals, ctx)
+	})
+})
@@ -0,0 +1,244 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// The encoded form of a key is the datastore's: a URL-safe base64 encoding,
+// without padding, of a Reference protocol buffer:
+//
+//	message Reference {
+//	  required string app = 13;
+//	  optional string name_space = 20;
+//	  required Path path = 14;
+//	}
+//	message Path {
+//	  repeated group Element = 1 {
+//	    required string type = 2;
+//	    optional int64 id = 3;
+//	    optional string name = 4;
+//	  }
+//	}
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+
+	refApp       = 13
+	refPath      = 14
+	refNamespace = 20
+	pathElement  = 1
+	elemType     = 2
+	elemID       = 3
+	elemName     = 4
+)
+
+// Encode returns an opaque representation of the key, which is safe
+// to use in URLs and compatible with the datastore's encoded keys.
+// Since a Key has no app ID, it is encoded with an empty one.
+func (k *Key) Encode() string {
+	var path []byte
+	for _, elem := range k.Path() {
+		path = appendTag(path, pathElement, wireStartGroup)
+		path = appendString(path, elemType, elem.Kind)
+		if elem.IntID != 0 {
+			path = appendTag(path, elemID, wireVarint)
+			path = appendVarint(path, uint64(elem.IntID))
+		}
+		if elem.StringID != "" {
+			path = appendString(path, elemName, elem.StringID)
+		}
+		path = appendTag(path, pathElement, wireEndGroup)
+	}
+
+	var ref []byte
+	ref = appendString(ref, refApp, "")
+	ref = appendBytes(ref, refPath, path)
+	if ns := k.Root().Namespace; ns != "" {
+		ref = appendString(ref, refNamespace, ns)
+	}
+
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(ref), "=")
+}
+
+// DecodeKey decodes a key from the opaque representation returned by Encode,
+// or by the datastore. The app ID of a datastore key is ignored.
+func DecodeKey(encoded string) (*Key, error) {
+	if pad := len(encoded) % 4; pad != 0 {
+		encoded += strings.Repeat("=", 4-pad)
+	}
+	b, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoded key: %v", err)
+	}
+
+	var key *Key
+	var namespace string
+	d := &decoder{b: b}
+	for d.more() {
+		field, wire := d.tag()
+		switch {
+		case field == refPath && wire == wireBytes:
+			path := d.bytes()
+			if d.err != nil {
+				break
+			}
+			if key, err = decodePath(path); err != nil {
+				return nil, err
+			}
+		case field == refNamespace && wire == wireBytes:
+			namespace = string(d.bytes())
+		default:
+			d.skip(wire)
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("invalid encoded key: %v", d.err)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("invalid encoded key: missing path")
+	}
+
+	for k := key; k != nil; k = k.Parent {
+		k.Namespace = namespace
+	}
+	return key, nil
+}
+
+func decodePath(b []byte) (*Key, error) {
+	var key *Key
+	d := &decoder{b: b}
+	for d.more() {
+		field, wire := d.tag()
+		if field != pathElement || wire != wireStartGroup {
+			d.skip(wire)
+			continue
+		}
+
+		key = NewKey("", "", 0, key)
+		for d.err == nil {
+			field, wire = d.tag()
+			if field == pathElement && wire == wireEndGroup {
+				break
+			}
+			switch {
+			case field == elemType && wire == wireBytes:
+				key.Kind = string(d.bytes())
+			case field == elemID && wire == wireVarint:
+				key.IntID = int64(d.varint())
+			case field == elemName && wire == wireBytes:
+				key.StringID = string(d.bytes())
+			default:
+				d.skip(wire)
+			}
+		}
+		if d.err == nil && key.Kind == "" {
+			d.err = fmt.Errorf("missing kind")
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid encoded key: %v", d.err)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("invalid encoded key: empty path")
+	}
+	return key, nil
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return appendVarint(b, uint64(field<<3|wire))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	return appendBytes(b, field, []byte(v))
+}
+
+// decoder reads a protocol buffer. Once it failed, err is set
+// and all further reads return zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) more() bool {
+	return d.err == nil && len(d.b) > 0
+}
+
+func (d *decoder) varint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = fmt.Errorf("malformed varint")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) tag() (field int, wire int) {
+	v := d.varint()
+	return int(v >> 3), int(v & 7)
+}
+
+func (d *decoder) next(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.b)) {
+		d.err = fmt.Errorf("unexpected end")
+		return nil
+	}
+	ret := d.b[:n]
+	d.b = d.b[n:]
+	return ret
+}
+
+func (d *decoder) bytes() []byte {
+	return d.next(d.varint())
+}
+
+// skip skips the value of an unknown field.
+func (d *decoder) skip(wire int) {
+	switch wire {
+	case wireVarint:
+		d.varint()
+	case wireFixed64:
+		d.next(8)
+	case wireBytes:
+		d.bytes()
+	case wireFixed32:
+		d.next(4)
+	case wireStartGroup:
+		for d.err == nil {
+			_, w := d.tag()
+			if w == wireEndGroup {
+				return
+			}
+			d.skip(w)
+		}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unexpected wire type %d", wire)
+		}
+	}
+}
@@ -6,23 +6,33 @@ import (
 )
 
 // Iterator is the result of running a query.
-type Iterator struct {
+type Iterator interface {
+
+	// Cursor returns a cursor for the Iterator's current location.
+	Cursor() (string, error)
+
+	// Next returns the key of the next result. If the query is not keys-only,
+	// it also loads the entity stored for that key into a PropertyLoadSaver.
+	Next(pipeFunc func(ae.Context) ds.PropertyLoadSaver) (*ds.Key, error)
+}
+
+// dsIterator is an Iterator backed by the datastore.
+type dsIterator struct {
 	inner *ds.Iterator
 	ctx   ae.Context
 	query *Query
 }
 
 // NewIterator returns a new Iterator by executing the passed-in query.
-func NewIterator(ctx ae.Context, query *Query) *Iterator {
-	return &Iterator{
+func NewIterator(ctx ae.Context, query *Query) Iterator {
+	return &dsIterator{
 		inner: query.ToDSQuery(ctx).Run(ctx),
 		query: query,
 		ctx:   ctx,
 	}
 }
 
-// Cursor returns a cursor for the Iterator's current location.
-func (it *Iterator) Cursor() (string, error) {
+func (it *dsIterator) Cursor() (string, error) {
 	c, err := it.inner.Cursor()
 	if err != nil {
 		return "", err
@@ -30,9 +40,7 @@ func (it *Iterator) Cursor() (string, error) {
 	return c.String(), nil
 }
 
-// Next returns the key of the next result. If the query is not keys-only,
-// it also loads the entity stored for that key into a PropertyLoadSaver.
-func (it *Iterator) Next(pipeFunc func(ae.Context) ds.PropertyLoadSaver) (*ds.Key, error) {
+func (it *dsIterator) Next(pipeFunc func(ae.Context) ds.PropertyLoadSaver) (*ds.Key, error) {
 	pipe := pipeFunc(it.ctx)
 	return it.inner.Next(pipe)
 }
@@ -19,7 +19,7 @@ var _ = Describe("Iterator", func() {
 
 	It("should create a new iterator", func() {
 		it := NewIterator(ctx, qry)
-		Check(it.inner, NotNil)
+		Check(it.(*dsIterator).inner, NotNil)
 	})
 
 	It("should return next entity", func() {
@@ -16,6 +16,17 @@ type DSKeyConverter interface {
 	ToDSKey(ctx ae.Context) *ds.Key
 }
 
+// KeyHolder is an entity.Key that holds a Key, e.g. a *hrd.Key.
+type KeyHolder interface {
+	Inner() *Key
+}
+
+// KeySetter can be set from a key, e.g. a reference to an entity.
+// It allows a DSKeyConverter to be loaded from a key property.
+type KeySetter interface {
+	SetKey(key entity.Key)
+}
+
 // Key represents the identifier for an entity.
 type Key struct {
 	*KeyState
@@ -40,11 +51,29 @@ type KeyState struct {
 	Error error
 }
 
+// maxStringIDLength is the maximum length of a key's text ID in bytes.
+const maxStringIDLength = 500
+
+// InvalidKeyError is returned for a key that cannot be used with the datastore.
+type InvalidKeyError struct {
+	Key *Key
+	Err error
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid key %v: %v", e.Key, e.Err)
+}
+
 // NewKey returns a new Key.
+// It inherits the namespace of its parent, if any.
 func NewKey(kind, stringID string, intID int64, parent *Key) *Key {
-	return &Key{
+	key := &Key{
 		Kind: kind, StringID: stringID, IntID: intID, Parent: parent, KeyState: &KeyState{},
 	}
+	if parent != nil {
+		key.Namespace = parent.Namespace
+	}
+	return key
 }
 
 // ImportKey creates a Key from a datastore.Key.
@@ -66,6 +95,45 @@ func ImportKeys(keys ...*ds.Key) []*Key {
 	return ret
 }
 
+// Validate returns an *InvalidKeyError if the key or one of its ancestors
+// cannot be used with the datastore. An incomplete key is valid,
+// but not an incomplete parent.
+func (k *Key) Validate() error {
+	for key := k; key != nil; key = key.Parent {
+		if err := key.validateElem(); err != nil {
+			return &InvalidKeyError{Key: k, Err: err}
+		}
+	}
+	return nil
+}
+
+func (k *Key) validateElem() error {
+	if err := ValidateKind(k.Kind); err != nil {
+		return err
+	}
+	if err := ValidateNamespace(k.Namespace); err != nil {
+		return err
+	}
+	if k.IntID < 0 {
+		return fmt.Errorf("negative ID %d", k.IntID)
+	}
+	if k.IntID != 0 && k.StringID != "" {
+		return fmt.Errorf("both numeric ID %d and text ID %q", k.IntID, k.StringID)
+	}
+	if len(k.StringID) > maxStringIDLength {
+		return fmt.Errorf("text ID exceeds %d bytes", maxStringIDLength)
+	}
+	if parent := k.Parent; parent != nil {
+		if parent.Incomplete() {
+			return fmt.Errorf("incomplete parent %v", parent)
+		}
+		if parent.Namespace != k.Namespace {
+			return fmt.Errorf("namespace %q differs from namespace %q of parent %v", k.Namespace, parent.Namespace, parent)
+		}
+	}
+	return nil
+}
+
 // Incomplete returns whether the key does not refer to a stored entity.
 // In particular, whether the key has a zero StringID and a zero IntID.
 func (k *Key) Incomplete() bool {
@@ -73,14 +141,134 @@ func (k *Key) Incomplete() bool {
 }
 
 // ToDSKey converts the key to a datastore Key.
+// A key without parent is created in its own namespace,
+// otherwise the namespace is inherited from the parent.
 func (k *Key) ToDSKey(ctx ae.Context) *ds.Key {
 	var parentKey *ds.Key
 	if k.Parent != nil {
 		parentKey = k.Parent.ToDSKey(ctx)
+	} else {
+		ctx = WithNamespace(ctx, k.Namespace)
 	}
 	return ds.NewKey(ctx, k.Kind, k.StringID, k.IntID, parentKey)
 }
 
+// Root returns the key's top-most ancestor, or the key itself.
+func (k *Key) Root() *Key {
+	key := k
+	for key.Parent != nil {
+		key = key.Parent
+	}
+	return key
+}
+
+// Ancestors returns the key's ancestors, starting with its parent.
+func (k *Key) Ancestors() []*Key {
+	var ret []*Key
+	for key := k.Parent; key != nil; key = key.Parent {
+		ret = append(ret, key)
+	}
+	return ret
+}
+
+// Path returns the key and its ancestors, starting with the root.
+func (k *Key) Path() []*Key {
+	ancestors := k.Ancestors()
+	ret := make([]*Key, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		ret = append(ret, ancestors[i])
+	}
+	return append(ret, k)
+}
+
+// Equal returns whether the key refers to the same entity as the passed-in
+// key: whether their kinds, IDs, namespaces and parents are equal.
+func (k *Key) Equal(other *Key) bool {
+	for k != nil && other != nil {
+		if k.Kind != other.Kind || k.StringID != other.StringID ||
+			k.IntID != other.IntID || k.Namespace != other.Namespace {
+			return false
+		}
+		k, other = k.Parent, other.Parent
+	}
+	return k == nil && other == nil
+}
+
+// IsAncestorOf returns whether the key is an ancestor of the passed-in key.
+func (k *Key) IsAncestorOf(other *Key) bool {
+	for key := other.Parent; key != nil; key = key.Parent {
+		if k.Equal(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Less returns whether the key sorts before the passed-in key in the
+// datastore's order: by namespace and then by each element of their paths,
+// starting with the root, where an ancestor sorts before its descendants.
+// Elements are ordered by kind and then by ID, with numeric IDs sorting
+// before text IDs.
+func (k *Key) Less(other *Key) bool {
+	if ns1, ns2 := k.Root().Namespace, other.Root().Namespace; ns1 != ns2 {
+		return ns1 < ns2
+	}
+
+	path1, path2 := k.Path(), other.Path()
+	for i := 0; i < len(path1) && i < len(path2); i++ {
+		if c := compareKeyElems(path1[i], path2[i]); c != 0 {
+			return c < 0
+		}
+	}
+	return len(path1) < len(path2)
+}
+
+// compareKeyElems compares a single element of two key paths,
+// ignoring their parents.
+func compareKeyElems(k1, k2 *Key) int {
+	switch {
+	case k1.Kind < k2.Kind:
+		return -1
+	case k1.Kind > k2.Kind:
+		return 1
+	}
+
+	num1, num2 := k1.StringID == "", k2.StringID == ""
+	switch {
+	case num1 && !num2:
+		return -1
+	case !num1 && num2:
+		return 1
+	case num1:
+		switch {
+		case k1.IntID < k2.IntID:
+			return -1
+		case k1.IntID > k2.IntID:
+			return 1
+		}
+	default:
+		switch {
+		case k1.StringID < k2.StringID:
+			return -1
+		case k1.StringID > k2.StringID:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Copy returns a deep copy of the key and its ancestors, without the state
+// of the operation the key originates from. The copy of an incomplete key
+// is incomplete as well.
+func (k *Key) Copy() *Key {
+	if k == nil {
+		return nil
+	}
+	key := NewKey(k.Kind, k.StringID, k.IntID, k.Parent.Copy())
+	key.Namespace = k.Namespace
+	return key
+}
+
 func (k *Key) String() string {
 	return keyToString(k)
 }
@@ -107,26 +295,84 @@ func keyStringID(key *Key) (id string) {
 	return
 }
 
+// ExportKey converts a Key to the key type passed to entities, e.g. to an
+// entity.ParentKeyer. Package hrd replaces it to pass its own key type.
+var ExportKey = func(key *Key) entity.Key {
+	return entityKey{key}
+}
+
+// entityKey is a Key that implements entity.Key.
+type entityKey struct {
+	key *Key
+}
+
+func (k entityKey) Kind() string      { return k.key.Kind }
+func (k entityKey) StringID() string  { return k.key.StringID }
+func (k entityKey) IntID() int64      { return k.key.IntID }
+func (k entityKey) Namespace() string { return k.key.Namespace }
+func (k entityKey) Encode() string    { return k.key.Encode() }
+func (k entityKey) Inner() *Key       { return k.key }
+
 // GetEntityKey extracts a new Key from the given entity.
+// The key inherits the namespace of its parent, which must be the kind's.
+// A parent without a namespace of its own is in the kind's namespace.
 func GetEntityKey(kind *Kind, src interface{}) (*Key, error) {
 	var parentKey *Key
-	if parentIdent, ok := src.(entity.ParentNumIdentifier); ok {
-		kind, id := parentIdent.Parent()
-		parentKey = NewKey(kind, "", id, nil)
+	if parentKeyer, ok := src.(entity.ParentKeyer); ok {
+		var err error
+		if parentKey, err = getParentKey(parentKeyer); err != nil {
+			return nil, err
+		}
+	} else {
+		if parentIdent, ok := src.(entity.ParentNumIdentifier); ok {
+			kind, id := parentIdent.Parent()
+			parentKey = NewKey(kind, "", id, nil)
+		}
+		if parentIdent, ok := src.(entity.ParentTextIdentifier); ok {
+			kind, id := parentIdent.Parent()
+			parentKey = NewKey(kind, id, 0, nil)
+		}
+		if parentKey != nil {
+			parentKey.Namespace = kind.Namespace
+		}
 	}
-	if parentIdent, ok := src.(entity.ParentTextIdentifier); ok {
-		kind, id := parentIdent.Parent()
-		parentKey = NewKey(kind, id, 0, nil)
+	if parentKey != nil && parentKey.Namespace != kind.Namespace {
+		return nil, fmt.Errorf("namespace %q of parent key %v differs from namespace %q of kind %q",
+			parentKey.Namespace, parentKey, kind.Namespace, kind.Name)
 	}
 
+	var key *Key
 	if ident, ok := src.(entity.NumIdentifier); ok {
-		return NewKey(kind.Name, "", ident.ID(), parentKey), nil
+		key = NewKey(kind.Name, "", ident.ID(), parentKey)
+	} else if ident, ok := src.(entity.TextIdentifier); ok {
+		key = NewKey(kind.Name, ident.ID(), 0, parentKey)
+	} else {
+		return nil, fmt.Errorf("value type %q does not provide ID()", reflect.TypeOf(src))
 	}
-	if ident, ok := src.(entity.TextIdentifier); ok {
-		return NewKey(kind.Name, ident.ID(), 0, parentKey), nil
+
+	key.Namespace = kind.Namespace
+	return key, nil
+}
+
+// getParentKey returns a copy of the parent key of the given entity,
+// which may be nil. Other keys than a KeyHolder are copied by encoding.
+func getParentKey(src entity.ParentKeyer) (*Key, error) {
+	parent := src.ParentKey()
+	if parent == nil {
+		return nil, nil
+	}
+	if holder, ok := parent.(KeyHolder); ok {
+		return holder.Inner().Copy(), nil
+	}
+	if v := reflect.ValueOf(parent); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
 	}
 
-	return nil, fmt.Errorf("value type %q does not provide ID()", reflect.TypeOf(src))
+	key, err := DecodeKey(parent.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent key of %q: %v", reflect.TypeOf(src), err)
+	}
+	return key, nil
 }
 
 // GetEntitiesKeys extracts a sequence of Key from the given entities.
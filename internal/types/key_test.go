@@ -1,7 +1,11 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/entity"
 	"github.com/101loops/hrd/entity/fixture"
 
 	ds "appengine/datastore"
@@ -66,6 +70,14 @@ var _ = Describe("Key", func() {
 			ds.NewKey(ctx, "my-child", "", 42, ds.NewKey(ctx, "my-parent", "parent", 0, nil)))
 	})
 
+	It("should inherit the namespace of its parent", func() {
+		parent := NewKey("my-parent", "parent", 0, nil)
+		parent.Namespace = "my-ns"
+
+		Check(NewKey("my-kind", "", 42, parent).Namespace, Equals, "my-ns")
+		Check(NewKey("my-kind", "", 42, nil).Namespace, Equals, "")
+	})
+
 	Context("create key from a single entity", func() {
 
 		It("should return a new Key from numeric id", func() {
@@ -106,6 +118,39 @@ var _ = Describe("Key", func() {
 			Check(key, Equals, NewKey("my-kind", "abc", 0, NewKey("my-parent", "xyz", 0, nil)))
 		})
 
+		It("should return a new Key from a parent key", func() {
+			grandparent := NewKey("my-grandparent", "abc", 0, nil)
+			entity := fixture.EntityWithParentKey{}
+			entity.SetID(42)
+			entity.SetParentKey(ExportKey(NewKey("my-parent", "", 66, grandparent)))
+
+			key, err := GetEntityKey(kind, &entity)
+			Check(err, IsNil)
+			Check(key.String(), Equals, "Key{'my-kind', 42}[ParentKey{'my-parent', 66}[ParentKey{'my-grandparent', abc}]]")
+		})
+
+		It("should copy a parent key without encoding it", func() {
+			parent := NewKey("my-parent", "", 66, NewKey("my-grandparent", "abc", 0, nil))
+			entity := fixture.EntityWithParentKey{}
+			entity.SetID(42)
+			entity.SetParentKey(holderKey{key: parent})
+
+			key, err := GetEntityKey(kind, &entity)
+			Check(err, IsNil)
+			Check(key.Parent, Equals, parent)
+			Check(key.Parent == parent, IsFalse)
+			Check(key.Parent.Parent == parent.Parent, IsFalse)
+		})
+
+		It("should return a new Key without parent key", func() {
+			entity := fixture.EntityWithParentKey{}
+			entity.SetID(42)
+
+			key, err := GetEntityKey(kind, &entity)
+			Check(err, IsNil)
+			Check(key.Parent, IsNil)
+		})
+
 		It("should not create a Key from an invalid entity", func() {
 			entity := "invalid"
 			key, err := GetEntityKey(kind, &entity)
@@ -114,6 +159,54 @@ var _ = Describe("Key", func() {
 			Check(err, ErrorContains, `value type "*string" does not provide ID()`)
 		})
 
+		It("should return a new Key in the kind's namespace", func() {
+			entity := fixture.EntityWithParentNumID{}
+			entity.SetID(42)
+			entity.SetParent("my-parent", 66)
+
+			nsKind := NewKind(ctx, "my-kind")
+			nsKind.Namespace = "my-ns"
+
+			key, err := GetEntityKey(nsKind, &entity)
+			Check(err, IsNil)
+			Check(key.Namespace, Equals, "my-ns")
+			Check(key.Parent.Namespace, Equals, "my-ns")
+		})
+
+		It("should return a new Key in the namespace of its ancestors", func() {
+			grandparent := NewKey("my-grandparent", "abc", 0, nil)
+			grandparent.Namespace = "my-ns"
+			entity := fixture.EntityWithParentKey{}
+			entity.SetID(42)
+			entity.SetParentKey(ExportKey(NewKey("my-parent", "", 66, grandparent)))
+
+			nsKind := NewKind(ctx, "my-kind")
+			nsKind.Namespace = "my-ns"
+
+			key, err := GetEntityKey(nsKind, &entity)
+			Check(err, IsNil)
+			Check(key.Namespace, Equals, "my-ns")
+			Check(key.Parent.Namespace, Equals, "my-ns")
+			Check(key.Parent.Parent.Namespace, Equals, "my-ns")
+		})
+
+		It("should not create a Key with a parent of another namespace", func() {
+			parent := NewKey("my-parent", "", 66, NewKey("my-grandparent", "abc", 0, nil))
+			entity := fixture.EntityWithParentKey{}
+			entity.SetID(42)
+			entity.SetParentKey(ExportKey(parent))
+
+			nsKind := NewKind(ctx, "my-kind")
+			nsKind.Namespace = "my-ns"
+
+			key, err := GetEntityKey(nsKind, &entity)
+			Check(key, IsNil)
+			Check(err, ErrorContains, `namespace "" of parent key`)
+			Check(err, ErrorContains, `differs from namespace "my-ns" of kind "my-kind"`)
+			Check(parent.Namespace, Equals, "")
+			Check(parent.Parent.Namespace, Equals, "")
+		})
+
 		It("should not create a Key from an invalid entity collection", func() {
 			invalidEntities := "invalid"
 			key, err := GetEntitiesKeys(kind, &invalidEntities)
@@ -172,3 +265,182 @@ var _ = Describe("Key", func() {
 		})
 	})
 })
+
+var _ = Describe("Key Encoding", func() {
+
+	It("should encode a key", func() {
+		key := NewKey("k", "", 1, nil)
+		Check(key.Encode(), Equals, "agByBwsSAWsYAQw")
+	})
+
+	It("should encode a key with namespace and parent", func() {
+		parent := NewKey("p", "a", 0, nil)
+		parent.Namespace = "my-ns"
+		key := NewKey("c", "", 42, parent)
+
+		Check(key.Encode(), Equals, "agByDwsSAXAiAWEMCxIBYxgqDKIBBW15LW5z")
+	})
+
+	It("should decode a key", func() {
+		parent := NewKey("p", "a", 0, nil)
+		parent.Namespace = "my-ns"
+		key := NewKey("c", "", 42, parent)
+
+		decoded, err := DecodeKey(key.Encode())
+		Check(err, IsNil)
+		Check(decoded.Kind, Equals, "c")
+		Check(decoded.IntID, EqualsNum, 42)
+		Check(decoded.Namespace, Equals, "my-ns")
+		Check(decoded.Parent.Kind, Equals, "p")
+		Check(decoded.Parent.StringID, Equals, "a")
+		Check(decoded.Parent.Namespace, Equals, "my-ns")
+		Check(decoded.Parent.Parent, IsNil)
+	})
+
+	It("should decode a datastore key with an app ID", func() {
+		decoded, err := DecodeKey("agVzfmFwcHIPCxIBcCIBYQwLEgFjGCoMogEFbXktbnM")
+		Check(err, IsNil)
+		Check(decoded.String(), Equals, "Key{'c', 42}[ParentKey{'p', a}]")
+		Check(decoded.Namespace, Equals, "my-ns")
+	})
+
+	It("should round-trip with the datastore", func() {
+		dsKey := ds.NewKey(ctx, "my-kind", "", 42, ds.NewKey(ctx, "parent", "abc", 0, nil))
+
+		decoded, err := DecodeKey(dsKey.Encode())
+		Check(err, IsNil)
+		Check(decoded.ToDSKey(ctx).Equal(dsKey), IsTrue)
+	})
+
+	It("should not decode an invalid key", func() {
+		for _, encoded := range []string{"", "!", "agBy", "agByAgsM"} {
+			_, err := DecodeKey(encoded)
+			Check(err, ErrorContains, "invalid encoded key")
+		}
+	})
+})
+
+var _ = Describe("Key Hierarchy", func() {
+
+	var (
+		root, parent, child *Key
+	)
+
+	BeforeEach(func() {
+		root = NewKey("root", "a", 0, nil)
+		root.Namespace = "my-ns"
+		parent = NewKey("parent", "", 1, root)
+		child = NewKey("child", "", 2, parent)
+	})
+
+	It("should return the root, ancestors and path", func() {
+		Check(child.Root(), Equals, root)
+		Check(root.Root(), Equals, root)
+
+		Check(child.Ancestors(), Equals, []*Key{parent, root})
+		Check(root.Ancestors(), HasLen, 0)
+
+		Check(child.Path(), Equals, []*Key{root, parent, child})
+		Check(root.Path(), Equals, []*Key{root})
+	})
+
+	It("should compare keys", func() {
+		Check(child.Equal(child.Copy()), IsTrue)
+		Check(child.Equal(parent), IsFalse)
+		Check(child.Equal(NewKey("child", "", 2, nil)), IsFalse)
+		Check(child.Equal(NewKey("child", "", 2, NewKey("parent", "", 1, nil))), IsFalse)
+
+		Check(root.IsAncestorOf(child), IsTrue)
+		Check(parent.IsAncestorOf(child), IsTrue)
+		Check(child.IsAncestorOf(child), IsFalse)
+		Check(child.IsAncestorOf(root), IsFalse)
+	})
+
+	It("should sort keys in datastore order", func() {
+		ordered := []*Key{
+			NewKey("a", "", 1, nil),
+			NewKey("a", "", 2, nil),
+			NewKey("a", "", 1, NewKey("a", "", 2, nil)),
+			NewKey("a", "x", 0, nil),
+			NewKey("a", "y", 0, nil),
+			NewKey("b", "", 1, nil),
+			root,
+			parent,
+			child,
+		}
+
+		for i := range ordered {
+			for j := range ordered {
+				Check(ordered[i].Less(ordered[j]), Equals, i < j)
+			}
+		}
+	})
+
+	It("should copy a key", func() {
+		incomplete := NewKey("child", "", 0, parent)
+		incomplete.Error = fmt.Errorf("an error")
+
+		cp := incomplete.Copy()
+		Check(cp.Equal(incomplete), IsTrue)
+		Check(cp.Incomplete(), IsTrue)
+		Check(cp.Error, IsNil)
+		Check(cp.Parent == parent, IsFalse)
+		Check(cp.Root().Namespace, Equals, "my-ns")
+
+		Check((*Key)(nil).Copy(), IsNil)
+	})
+})
+
+var _ = Describe("Key Validation", func() {
+
+	It("should validate a kind name", func() {
+		Check(ValidateKind("my-kind"), IsNil)
+
+		Check(ValidateKind(""), ErrorContains, "empty kind name")
+		Check(ValidateKind("__kind__"), ErrorContains, `kind "__kind__" is reserved`)
+	})
+
+	It("should accept valid keys", func() {
+		parent := NewKey("parent", "a", 0, nil)
+		parent.Namespace = "my-ns"
+
+		Check(NewKey("kind", "", 1, nil).Validate(), IsNil)
+		Check(NewKey("kind", "a", 0, parent).Validate(), IsNil)
+		Check(NewKey("kind", "", 0, parent).Validate(), IsNil)
+		Check(NewKey("kind", strings.Repeat("a", 500), 0, nil).Validate(), IsNil)
+	})
+
+	It("should reject invalid keys", func() {
+		incompleteParent := NewKey("parent", "", 0, nil)
+		otherNsParent := NewKey("parent", "", 1, nil)
+		otherNsParent.Namespace = "other-ns"
+		otherNsKey := NewKey("kind", "", 1, otherNsParent)
+		otherNsKey.Namespace = "my-ns"
+
+		invalid := map[*Key]string{
+			NewKey("", "", 1, nil):                           "empty kind name",
+			NewKey("__kind__", "", 1, nil):                   `kind "__kind__" is reserved`,
+			NewKey("kind", "", -1, nil):                      "negative ID -1",
+			NewKey("kind", "a", 1, nil):                      `both numeric ID 1 and text ID "a"`,
+			NewKey("kind", strings.Repeat("a", 501), 0, nil): "text ID exceeds 500 bytes",
+			NewKey("kind", "", 1, incompleteParent):          "incomplete parent",
+			otherNsKey:                                       `namespace "my-ns" differs from namespace "other-ns" of parent`,
+			NewKey("kind", "", 1, NewKey("", "", 1, nil)):    "empty kind name",
+		}
+		for key, msg := range invalid {
+			err := key.Validate()
+			Check(err, ErrorContains, msg)
+			Check(err.(*InvalidKeyError).Key, Equals, key)
+		}
+	})
+})
+
+// holderKey is a KeyHolder that cannot be encoded.
+type holderKey struct {
+	entity.Key
+	key *Key
+}
+
+func (k holderKey) Inner() *Key {
+	return k.key
+}
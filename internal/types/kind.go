@@ -1,16 +1,84 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
+	"github.com/101loops/hrd/rpc"
+	"github.com/101loops/structor"
+
 	ae "appengine"
 )
 
 // Kind represents a kind of entity in the datastore.
 type Kind struct {
-	Context ae.Context
-	Name    string
+	Context   ae.Context
+	Name      string
+	Namespace string
+
+	// Codecs contains the codecs of the kind's entity types.
+	Codecs *structor.Set
+
+	// Cache is the global cache of the kind's entities, it may be nil.
+	Cache cache.Cache
+
+	// CachePolicy defines how the kind's entities are cached.
+	CachePolicy cache.Policy
+
+	// Logger logs the operations on the kind, it may be nil.
+	Logger logging.Logger
+
+	// Metrics receives events about the operations on the kind, it may be nil.
+	Metrics metrics.Sink
+
+	// Listener is notified of the service calls of the operations
+	// on the kind, it may be nil.
+	Listener rpc.Listener
+
+	// IDStrategy generates the IDs of entities with text IDs that are saved
+	// with an incomplete key, it may be nil.
+	IDStrategy IDStrategy
 }
 
-// NewKind creates a new kind.
+// IDStrategy generates a new text ID for an entity of a kind.
+// An empty ID leaves the entity's key incomplete.
+type IDStrategy interface {
+	NewID(ctx ae.Context, kind string) (string, error)
+}
+
+// ValidateKind returns an error if the passed-in kind name is invalid.
+// Names beginning with "__" are reserved by the datastore.
+func ValidateKind(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty kind name")
+	}
+	if strings.HasPrefix(name, "__") {
+		return fmt.Errorf("kind %q is reserved", name)
+	}
+	return nil
+}
+
+// NewKind creates a new kind in the default namespace.
 func NewKind(ctx ae.Context, name string) *Kind {
-	return &Kind{ctx, name}
+	return &Kind{Context: ctx, Name: name}
+}
+
+// Log logs a message about the kind with its Logger, if it has one.
+func (k *Kind) Log(level logging.Level, msg string, fields logging.Fields) {
+	if k.Logger != nil {
+		k.Logger.Log(k.Context, level, msg, fields)
+	}
+}
+
+// Record passes an event about an operation on the kind to its Metrics,
+// if it has any. The event's kind and namespace are set to the kind's.
+func (k *Kind) Record(event metrics.Event) {
+	if k.Metrics != nil {
+		event.Kind = k.Name
+		event.Namespace = k.Namespace
+		k.Metrics.Record(k.Context, event)
+	}
 }
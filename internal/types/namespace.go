@@ -0,0 +1,29 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+
+	ae "appengine"
+)
+
+var (
+	validNamespace = regexp.MustCompile(`^[0-9A-Za-z._-]{0,100}$`)
+)
+
+// ValidateNamespace returns an error if the passed-in namespace is invalid.
+func ValidateNamespace(namespace string) error {
+	if !validNamespace.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace %q", namespace)
+	}
+	return nil
+}
+
+// WithNamespace returns a context that operates in the passed-in namespace.
+// If the namespace is invalid, the passed-in context is returned.
+func WithNamespace(ctx ae.Context, namespace string) ae.Context {
+	if nsCtx, err := ae.Namespace(ctx, namespace); err == nil {
+		return nsCtx
+	}
+	return ctx
+}
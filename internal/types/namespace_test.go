@@ -0,0 +1,18 @@
+package types
+
+import (
+	"strings"
+
+	. "github.com/101loops/bdd"
+)
+
+var _ = Describe("Namespace", func() {
+
+	It("should validate a namespace", func() {
+		Check(ValidateNamespace(""), IsNil)
+		Check(ValidateNamespace("my-ns_1.0"), IsNil)
+
+		Check(ValidateNamespace("my ns"), ErrorContains, `invalid namespace "my ns"`)
+		Check(ValidateNamespace(strings.Repeat("a", 101)), HasOccurred)
+	})
+})
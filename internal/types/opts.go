@@ -6,8 +6,11 @@ type Opts struct {
 	// CompleteKeys is whether an entity's key must be set before writing.
 	CompleteKeys bool
 
-	// NoGlobalCache is whether memcache is used.
+	// NoGlobalCache is whether the global cache is not used.
 	NoGlobalCache bool
+
+	// NoLocalCache is whether the in-memory cache of a context is not used.
+	NoLocalCache bool
 }
 
 // DefaultOpts returns an object with default options.
@@ -1,6 +1,9 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+
 	ae "appengine"
 	ds "appengine/datastore"
 )
@@ -35,6 +38,7 @@ const (
 type Query struct {
 	kind string
 
+	Namespace  string
 	Ancestor   *Key
 	Filter     []Filter
 	Order      []Order
@@ -60,6 +64,11 @@ func NewQuery(kind string) (ret *Query) {
 	}
 }
 
+// Kind returns the name of the queried kind.
+func (q *Query) Kind() string {
+	return q.kind
+}
+
 // Clone creates a deep copy.
 func (q *Query) Clone() *Query {
 	ret := *q
@@ -76,6 +85,52 @@ func (q *Query) Clone() *Query {
 	return &ret
 }
 
+// String returns a description of the query for the log.
+func (q *Query) String() string {
+	parts := []string{fmt.Sprintf("KIND %q", q.kind)}
+	if q.Namespace != "" {
+		parts = append(parts, fmt.Sprintf("NAMESPACE %q", q.Namespace))
+	}
+	if q.Ancestor != nil {
+		parts = append(parts, fmt.Sprintf("ANCESTOR '%v'", q.Ancestor))
+	}
+	for _, f := range q.Filter {
+		parts = append(parts, fmt.Sprintf("FILTER '%v %v'", f.Filter, f.Value))
+	}
+	for _, o := range q.Order {
+		if o.Descending {
+			parts = append(parts, "ORDER DESC "+o.FieldName)
+		} else {
+			parts = append(parts, "ORDER ASC "+o.FieldName)
+		}
+	}
+	if len(q.Projection) > 0 {
+		parts = append(parts, fmt.Sprintf("PROJECT '%v'", strings.Join(q.Projection, "', '")))
+	}
+	if q.Limit >= 0 {
+		parts = append(parts, fmt.Sprintf("LIMIT %v", q.Limit))
+	}
+	if q.Offset != 0 {
+		parts = append(parts, fmt.Sprintf("OFFSET %v", q.Offset))
+	}
+	if q.Start != "" {
+		parts = append(parts, "START CURSOR")
+	}
+	if q.End != "" {
+		parts = append(parts, "END CURSOR")
+	}
+	if q.Eventual {
+		parts = append(parts, "EVENTUAL CONSISTENCY")
+	}
+	if q.Distinct {
+		parts = append(parts, "DISTINCT")
+	}
+	if q.TypeOf == KeysOnlyQuery {
+		parts = append(parts, "KEYS-ONLY")
+	}
+	return strings.Join(parts, " | ")
+}
+
 // ToDSQuery converts the query to a datastore Query.
 func (q *Query) ToDSQuery(ctx ae.Context) *ds.Query {
 	dsQry := ds.NewQuery(q.kind).Limit(q.Limit)
@@ -35,6 +35,17 @@ var _ = Describe("Query", func() {
 		Check(addrOf(qry.Projection), Not(Equals), addrOf(copy.Projection))
 	})
 
+	It("should describe itself", func() {
+		qry := NewQuery("my-kind")
+		Check(qry.String(), Equals, `KIND "my-kind"`)
+
+		qry.Filter = []Filter{Filter{"age >", 18}}
+		qry.Order = []Order{Order{"age", true}, Order{"name", false}}
+		qry.Limit = 10
+		qry.TypeOf = KeysOnlyQuery
+		Check(qry.String(), Equals, `KIND "my-kind" | FILTER 'age > 18' | ORDER DESC age | ORDER ASC name | LIMIT 10 | KEYS-ONLY`)
+	})
+
 	It("should convert to dastastore Query", func() {
 		qry := &Query{
 			kind:       "my-kind",
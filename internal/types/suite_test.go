@@ -4,21 +4,17 @@ import (
 	"testing"
 
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/memctx"
 
-	"appengine/aetest"
+	ae "appengine"
 )
 
 var (
-	ctx aetest.Context
+	ctx ae.Context
 )
 
 func TestSuite(t *testing.T) {
-	var err error
-	ctx, err = aetest.NewContext(nil)
-	if err != nil {
-		panic(err)
-	}
-	defer ctx.Close()
+	ctx = memctx.New()
 
 	RunSpecs(t, "HRD Types Suite")
 }
@@ -2,14 +2,17 @@ package internal
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
 
 	ae "appengine"
 	ds "appengine/datastore"
 )
 
-// LogDatastoreAction logs a datastore action.
+// LogDatastoreAction describes a datastore action for the log.
 func LogDatastoreAction(verb string, prop string, keys []*types.Key, kind string) string {
 	if len(keys) == 1 {
 		sKey := keys[0].String()
@@ -18,6 +21,34 @@ func LogDatastoreAction(verb string, prop string, keys []*types.Key, kind string
 	return fmt.Sprintf("%v %v items %v %q", verb, len(keys), prop, kind)
 }
 
+// reportAction logs a datastore action on a kind after it ran
+// and records it in the kind's metrics. The passed-in fields,
+// which may be nil, are added to the log message.
+func reportAction(kind *types.Kind, msg string, event metrics.Event, start time.Time, fields logging.Fields) {
+	event.Duration = time.Since(start)
+	kind.Record(event)
+
+	if fields == nil {
+		fields = make(logging.Fields)
+	}
+	fields["op"] = string(event.Op)
+	fields["kind"] = kind.Name
+	fields["duration"] = event.Duration
+	if event.Op == metrics.OpCount {
+		fields["count"] = event.BatchSize
+	} else {
+		fields["keys"] = event.BatchSize
+	}
+	if event.Op == metrics.OpGet {
+		fields["cache_hits"] = event.CacheHits
+		fields["cache_misses"] = event.CacheMisses
+	}
+	if event.Err != nil {
+		fields["error"] = event.Err
+	}
+	kind.Log(logging.Info, msg, fields)
+}
+
 func toDSKeys(ctx ae.Context, keys []*types.Key) []*ds.Key {
 	ret := make([]*ds.Key, len(keys))
 	for i, key := range keys {
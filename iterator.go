@@ -3,16 +3,34 @@ package hrd
 import "github.com/101loops/hrd/internal/types"
 
 // Iterator is the result of running a query.
+//
+// The query runs when the first results are loaded. Further loads
+// continue from the Iterator's cursor.
 type Iterator struct {
-	inner *types.Iterator
+	inner   types.Iterator // nil until the query ran
+	loaded  int
+	kind    *types.Kind
+	query   *Query
+	backend backend
+	err     error
 }
 
 func newIterator(qry *Query) *Iterator {
-	return &Iterator{types.NewIterator(qry.ctx, qry.inner)}
+	if qry.kind.err != nil {
+		return &Iterator{err: qry.kind.err}
+	}
+	kind := qry.kind.toInternal(qry.ctx, nil)
+	return &Iterator{kind: kind, query: qry, backend: qry.kind.store.run()}
 }
 
 // Cursor returns a cursor for the Iterator's current location.
 func (it *Iterator) Cursor() (string, error) {
+	if it.err != nil {
+		return "", it.err
+	}
+	if it.inner == nil {
+		return it.query.inner.Start, nil
+	}
 	return it.inner.Cursor()
 }
 
@@ -31,6 +49,39 @@ func (it *Iterator) GetAll(dsts interface{}) ([]*Key, error) {
 }
 
 func (it *Iterator) get(dsts interface{}, multi bool) ([]*Key, error) {
-	keys, err := dsIterate(it.inner, dsts, multi)
+	if it.err != nil {
+		return nil, it.err
+	}
+	qry, err := it.next()
+	if err != nil {
+		return nil, err
+	}
+
+	inner, keys, err := it.backend.Query(it.kind, qry, dsts, multi)
+	if inner != nil {
+		it.inner = inner
+	}
+	it.loaded += len(keys)
+	it.query.kind.bindRefs(it.query.opts, dsts)
 	return importKeys(keys), err
 }
+
+// next returns the query for the Iterator's next results: the query itself
+// at first, or else a query for the remaining results from its cursor.
+func (it *Iterator) next() (*types.Query, error) {
+	if it.inner == nil {
+		return it.query.inner, nil
+	}
+
+	cursor, err := it.inner.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	qry := it.query.inner.Clone()
+	qry.Start = cursor
+	qry.Offset = 0
+	if qry.Limit >= 0 {
+		qry.Limit -= it.loaded
+	}
+	return qry, nil
+}
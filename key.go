@@ -1,6 +1,11 @@
 package hrd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/101loops/hrd/entity"
 	"github.com/101loops/hrd/internal/types"
 
 	ae "appengine"
@@ -12,6 +17,17 @@ type Key struct {
 	inner *types.Key
 }
 
+var (
+	_ entity.Key      = (*Key)(nil)
+	_ types.KeyHolder = (*Key)(nil)
+)
+
+func init() {
+	types.ExportKey = func(key *types.Key) entity.Key {
+		return importKey(key)
+	}
+}
+
 func importKey(key *types.Key) *Key {
 	if key == nil {
 		return nil
@@ -27,20 +43,35 @@ func importKeys(keys []*types.Key) []*Key {
 	return ret
 }
 
-func newKey(kind string, stringID string, intID int64, parent *Key) *Key {
+// newKey creates a key in the kind's namespace,
+// unless it has a parent whose namespace it inherits.
+// The key is invalid if the parent's namespace differs from the
+// kind's, unless the latter is only known once resolved in a context.
+func newKey(kind *Kind, stringID string, intID int64, parent *Key) *Key {
 	var parentKey *types.Key
 	if parent != nil {
 		parentKey = parent.inner
 	}
-	return importKey(types.NewKey(kind, stringID, intID, parentKey))
+	key := types.NewKey(kind.name, stringID, intID, parentKey)
+	if parentKey == nil {
+		key.Namespace = kind.namespace
+	}
+	if err := key.Validate(); err != nil {
+		key.Error = importError(err)
+	} else if parentKey != nil && kind.resolved() && parentKey.Namespace != kind.namespace {
+		key.Error = &InvalidKeyError{Key: importKey(key), Err: fmt.Errorf(
+			"namespace %q of parent %v differs from namespace %q of kind %q",
+			parentKey.Namespace, parentKey, kind.namespace, kind.name)}
+	}
+	return importKey(key)
 }
 
 func newNumKey(kind *Kind, id int64, parent *Key) *Key {
-	return newKey(kind.name, "", id, parent)
+	return newKey(kind, "", id, parent)
 }
 
 func newTextKey(kind *Kind, id string, parent *Key) *Key {
-	return newKey(kind.name, id, 0, parent)
+	return newKey(kind, id, 0, parent)
 }
 
 // Kind returns the key's kind (also known as entity type).
@@ -69,6 +100,66 @@ func (k *Key) Namespace() string {
 	return k.inner.Namespace
 }
 
+// Root returns the key's top-most ancestor, or the key itself.
+func (k *Key) Root() *Key {
+	return importKey(k.inner.Root())
+}
+
+// Ancestors returns the key's ancestors, starting with its parent.
+func (k *Key) Ancestors() []*Key {
+	return importKeys(k.inner.Ancestors())
+}
+
+// Path returns the key and its ancestors, starting with the root.
+func (k *Key) Path() []*Key {
+	return importKeys(k.inner.Path())
+}
+
+// Equal returns whether the key refers to the same entity as the passed-in
+// key: whether their kinds, IDs, namespaces and parents are equal.
+// Two nil keys are equal.
+func (k *Key) Equal(other *Key) bool {
+	if k == nil || other == nil {
+		return k == other
+	}
+	return k.inner.Equal(other.inner)
+}
+
+// IsAncestorOf returns whether the key is an ancestor of the passed-in key,
+// i.e. whether the key is the parent of it or of one of its ancestors.
+// A nil key is neither an ancestor nor has any.
+func (k *Key) IsAncestorOf(other *Key) bool {
+	if k.Inner() == nil || other.Inner() == nil {
+		return false
+	}
+	return k.inner.IsAncestorOf(other.inner)
+}
+
+// Less returns whether the key sorts before the passed-in key
+// in the datastore's order. A nil key sorts before any other key.
+func (k *Key) Less(other *Key) bool {
+	if k.Inner() == nil || other.Inner() == nil {
+		return k.Inner() == nil && other.Inner() != nil
+	}
+	return k.inner.Less(other.inner)
+}
+
+// Copy returns a deep copy of the key and its ancestors, without the state
+// of the operation the key originates from (see Exists and Error).
+// The copy of an incomplete key is incomplete as well.
+func (k *Key) Copy() *Key {
+	return importKey(k.inner.Copy())
+}
+
+// Inner returns the package's internal representation of the key.
+// It is nil for a nil key.
+func (k *Key) Inner() *types.Key {
+	if k == nil {
+		return nil
+	}
+	return k.inner
+}
+
 // Exists is whether an entity with this key exists in the datastore.
 func (k *Key) Exists() bool {
 	if t := k.inner.Synced; t != nil {
@@ -77,7 +168,31 @@ func (k *Key) Exists() bool {
 	return false
 }
 
+// InvalidKeyError is returned for a key that cannot be used with the
+// datastore, e.g. because of a negative ID or a reserved kind name.
+type InvalidKeyError struct {
+
+	// Key is the invalid key.
+	Key *Key
+
+	// Err describes why the key is invalid.
+	Err error
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid key %v: %v", e.Key, e.Err)
+}
+
+// importError converts an internal error to its exported type, if any.
+func importError(err error) error {
+	if e, ok := err.(*types.InvalidKeyError); ok {
+		return &InvalidKeyError{Key: importKey(e.Key), Err: e.Err}
+	}
+	return err
+}
+
 // Error returns an error associated with the key.
+// For a key created invalid, it is an *InvalidKeyError.
 func (k *Key) Error() error {
 	return k.inner.Error
 }
@@ -97,6 +212,161 @@ func (k *Key) String() string {
 	return k.inner.String()
 }
 
+// Encode returns an opaque representation of the key, which is safe to use
+// in URLs. It is compatible with datastore.Key's Encode and DecodeKey,
+// but leaves out the app ID. A nil key is encoded as an empty string.
+func (k *Key) Encode() string {
+	if k.Inner() == nil {
+		return ""
+	}
+	return k.inner.Encode()
+}
+
+// DecodeKey decodes a key from the opaque representation returned by
+// Key.Encode or datastore.Key's Encode. It does not need a context.
+func DecodeKey(encoded string) (*Key, error) {
+	key, err := types.DecodeKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return importKey(key), nil
+}
+
+// jsonKey is the structured JSON form of a key.
+type jsonKey struct {
+	Kind      string   `json:"kind"`
+	ID        int64    `json:"id,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Parent    *jsonKey `json:"parent,omitempty"`
+}
+
+func toJSONKey(key *types.Key) *jsonKey {
+	if key == nil {
+		return nil
+	}
+	return &jsonKey{
+		Kind: key.Kind, ID: key.IntID, Name: key.StringID,
+		Namespace: key.Namespace, Parent: toJSONKey(key.Parent),
+	}
+}
+
+func (k *jsonKey) toKey() (*types.Key, error) {
+	if k == nil {
+		return nil, nil
+	}
+	if k.Kind == "" {
+		return nil, fmt.Errorf("invalid JSON key: missing kind")
+	}
+	parent, err := k.Parent.toKey()
+	if err != nil {
+		return nil, err
+	}
+	key := types.NewKey(k.Kind, k.Name, k.ID, parent)
+	key.Namespace = k.Namespace
+	return key, nil
+}
+
+// MarshalJSON implements json.Marshaler. A key is marshaled
+// to an object with its kind, id or name, namespace and parent,
+// e.g. {"kind":"book","id":42,"parent":{"kind":"shelf","name":"fiction"}}.
+// Use EncodedKey to marshal it to its compact encoded form instead.
+func (k *Key) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toJSONKey(k.inner))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It accepts the structured as well as the encoded form of a key.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return err
+		}
+		return k.UnmarshalText([]byte(encoded))
+	}
+
+	var jk jsonKey
+	if err := json.Unmarshal(data, &jk); err != nil {
+		return err
+	}
+	key, err := jk.toKey()
+	if err != nil {
+		return err
+	}
+	k.inner = key
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// A key is marshaled to its encoded form (see Encode).
+// It returns an error for a nil key.
+func (k *Key) MarshalText() ([]byte, error) {
+	if k.Inner() == nil {
+		return nil, fmt.Errorf("cannot marshal nil key")
+	}
+	return []byte(k.Encode()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It expects the encoded form of a key (see DecodeKey).
+func (k *Key) UnmarshalText(text []byte) error {
+	key, err := types.DecodeKey(string(text))
+	if err != nil {
+		return err
+	}
+	k.inner = key
+	return nil
+}
+
+// EncodedKey is a Key that is marshaled to JSON in its compact encoded
+// form (see Key.Encode), e.g. for use in URLs. It unmarshals both forms.
+type EncodedKey struct {
+	*Key
+}
+
+// MarshalJSON implements json.Marshaler.
+func (k EncodedKey) MarshalJSON() ([]byte, error) {
+	if k.Key == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(k.Encode())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (k *EncodedKey) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		k.Key = nil
+		return nil
+	}
+	key := new(Key)
+	if err := key.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	k.Key = key
+	return nil
+}
+
+// optionalKey returns the internal key of an optional key parameter.
+func optionalKey(key []*Key) *types.Key {
+	if len(key) == 0 || key[0] == nil {
+		return nil
+	}
+	return key[0].inner
+}
+
+// optionalKeys returns the internal parent key as a list to validate,
+// which is empty if there is none.
+func optionalKeys(key []*Key) []*types.Key {
+	if parent := optionalKey(key); parent != nil {
+		return []*types.Key{parent}
+	}
+	return nil
+}
+
 func toInternalKeys(keys []*Key) []*types.Key {
 	ret := make([]*types.Key, len(keys))
 	for i, k := range keys {
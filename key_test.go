@@ -1,9 +1,11 @@
 package hrd
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/entity"
 	"github.com/101loops/hrd/internal/types"
 	ds "appengine/datastore"
 )
@@ -85,4 +87,154 @@ var _ = Describe("Key", func() {
 		k := newTextKey(myKind, "abc", nil)
 		Check(k.String(), Equals, "Key{'my-kind', abc}")
 	})
+
+	It("should encode and decode", func() {
+		k1 := myKind.Namespace("my-ns").NewNumKey(42)
+		k2 := newTextKey(myKind, "abc", k1)
+
+		decoded, err := DecodeKey(k2.Encode())
+		Check(err, IsNil)
+		Check(decoded.String(), Equals, k2.String())
+		Check(decoded.Namespace(), Equals, "my-ns")
+		Check(decoded.Parent().IntID(), EqualsNum, 42)
+
+		_, err = DecodeKey("invalid")
+		Check(err, HasOccurred)
+	})
+
+	It("should navigate and compare the hierarchy", func() {
+		root := myKind.NewTextKey("abc")
+		parent := myKind.NewNumKey(1, root)
+		child := myKind.NewNumKey(2, parent)
+
+		Check(child.Root(), Equals, root)
+		Check(child.Ancestors(), Equals, []*Key{parent, root})
+		Check(child.Path(), Equals, []*Key{root, parent, child})
+
+		Check(child.Equal(child.Copy()), IsTrue)
+		Check(child.Equal(parent), IsFalse)
+		Check(child.Equal(nil), IsFalse)
+		Check((*Key)(nil).Equal(nil), IsTrue)
+
+		Check(root.IsAncestorOf(child), IsTrue)
+		Check(child.IsAncestorOf(root), IsFalse)
+
+		Check(parent.Less(child), IsTrue)
+		Check(child.Less(parent), IsFalse)
+		Check(myKind.NewNumKey(99).Less(root), IsTrue)
+	})
+
+	It("should handle nil keys", func() {
+		key := myKind.NewNumKey(1)
+		for _, nilKey := range []*Key{nil, &Key{}} {
+			Check(nilKey.IsAncestorOf(key), IsFalse)
+			Check(key.IsAncestorOf(nilKey), IsFalse)
+
+			Check(nilKey.Less(key), IsTrue)
+			Check(key.Less(nilKey), IsFalse)
+			Check(nilKey.Less(nil), IsFalse)
+
+			Check(nilKey.Encode(), Equals, "")
+			_, err := nilKey.MarshalText()
+			Check(err, ErrorContains, "nil key")
+		}
+	})
+
+	It("should be the parent key of an entity", func() {
+		parent := myKind.NewNumKey(2, myKind.NewTextKey("abc"))
+		entity := &entityWithParentKey{}
+		entity.SetID(1)
+		entity.SetParentKey(parent)
+		myStore.RegisterEntityMust(entity)
+
+		key, err := myKind.Save(ctx).Entity(entity)
+		Check(err, IsNil)
+		Check(key.Parent().Equal(parent), IsTrue)
+
+		var loaded *entityWithParentKey
+		_, err = myKind.Load(ctx).Key(key).GetOne(&loaded)
+		Check(err, IsNil)
+		Check(loaded.parent.Equal(parent), IsTrue)
+	})
+
+	Context("marshaling", func() {
+
+		var (
+			key *Key
+		)
+
+		BeforeEach(func() {
+			kind := myKind.Namespace("my-ns")
+			key = kind.NewNumKey(42, kind.NewTextKey("abc"))
+		})
+
+		It("should marshal to structured JSON", func() {
+			data, err := json.Marshal(key)
+			Check(err, IsNil)
+			Check(string(data), Equals, `{"kind":"my-kind","id":42,"namespace":"my-ns",`+
+				`"parent":{"kind":"my-kind","name":"abc","namespace":"my-ns"}}`)
+
+			var decoded *Key
+			err = json.Unmarshal(data, &decoded)
+			Check(err, IsNil)
+			Check(decoded.String(), Equals, key.String())
+			Check(decoded.Namespace(), Equals, "my-ns")
+		})
+
+		It("should marshal to encoded JSON", func() {
+			data, err := json.Marshal(EncodedKey{key})
+			Check(err, IsNil)
+			Check(string(data), Equals, `"`+key.Encode()+`"`)
+
+			var decoded EncodedKey
+			err = json.Unmarshal(data, &decoded)
+			Check(err, IsNil)
+			Check(decoded.String(), Equals, key.String())
+
+			var plain *Key
+			err = json.Unmarshal(data, &plain)
+			Check(err, IsNil)
+			Check(plain.String(), Equals, key.String())
+		})
+
+		It("should marshal nil keys", func() {
+			data, err := json.Marshal(struct {
+				Key     *Key
+				Encoded EncodedKey
+			}{})
+			Check(err, IsNil)
+			Check(string(data), Equals, `{"Key":null,"Encoded":null}`)
+		})
+
+		It("should marshal to text", func() {
+			text, err := key.MarshalText()
+			Check(err, IsNil)
+			Check(string(text), Equals, key.Encode())
+
+			decoded := new(Key)
+			err = decoded.UnmarshalText(text)
+			Check(err, IsNil)
+			Check(decoded.String(), Equals, key.String())
+		})
+
+		It("should not unmarshal an invalid key", func() {
+			var decoded *Key
+			Check(json.Unmarshal([]byte(`{"id":42}`), &decoded), ErrorContains, "missing kind")
+			Check(json.Unmarshal([]byte(`"invalid"`), &decoded), HasOccurred)
+			Check(new(Key).UnmarshalText([]byte("invalid")), HasOccurred)
+		})
+	})
 })
+
+type entityWithParentKey struct {
+	entity.NumID
+	parent *Key
+}
+
+func (mdl *entityWithParentKey) ParentKey() entity.Key {
+	return mdl.parent
+}
+
+func (mdl *entityWithParentKey) SetParentKey(key entity.Key) {
+	mdl.parent = key.(*Key)
+}
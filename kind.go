@@ -1,24 +1,59 @@
 package hrd
 
 import (
+	"fmt"
+
+	"github.com/101loops/hrd/cache"
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/trace"
 
 	ae "appengine"
 )
 
 // Kind represents a entity category in the datastore.
 type Kind struct {
-	store *Store
-	name  string
-	opts  *types.Opts
+	store     *Store
+	name      string
+	namespace string
+	opts      *types.Opts
+	policy    *cache.Policy
+
+	idStrategy IDStrategy
+
+	// err is an *InvalidKindError if the kind's name is invalid.
+	err error
+
+	// fixedNamespace is whether the namespace was set explicitly,
+	// so the store's namespace resolver does not apply.
+	fixedNamespace bool
 }
 
 func newKind(store *Store, name string) *Kind {
-	return &Kind{
-		store: store,
-		name:  name,
-		opts:  store.opts.Clone(),
+	kind := &Kind{
+		store:     store,
+		name:      name,
+		namespace: store.namespace,
+		opts:      store.opts.Clone(),
 	}
+	if err := types.ValidateKind(name); err != nil {
+		kind.err = &InvalidKindError{Name: name, Err: err}
+	}
+	return kind
+}
+
+// InvalidKindError is returned for an action on a kind with an invalid name,
+// e.g. an empty one or one that is reserved by the datastore.
+type InvalidKindError struct {
+
+	// Name is the name of the kind.
+	Name string
+
+	// Err describes why the name is invalid.
+	Err error
+}
+
+func (e *InvalidKindError) Error() string {
+	return fmt.Sprintf("invalid kind: %v", e.Err)
 }
 
 // Name returns the name of the kind.
@@ -26,6 +61,127 @@ func (k *Kind) Name() string {
 	return k.name
 }
 
+// CompleteKeys prevents saving an entity of the kind with an incomplete key.
+func (k *Kind) CompleteKeys() *Kind {
+	k.opts.CompleteKeys = true
+	return k
+}
+
+// NoGlobalCache prevents reading/writing entities of the kind
+// from/to the global cache.
+func (k *Kind) NoGlobalCache() *Kind {
+	k.opts.NoGlobalCache = true
+	return k
+}
+
+// NoLocalCache prevents reading/writing entities of the kind
+// from/to the in-memory cache of a context.
+func (k *Kind) NoLocalCache() *Kind {
+	k.opts.NoLocalCache = true
+	return k
+}
+
+// CachePolicy sets how the entities of the kind are cached.
+// It takes precedence over the policies declared for entity types
+// when registering them.
+func (k *Kind) CachePolicy(policy cache.Policy) *Kind {
+	k.policy = &policy
+	return k
+}
+
+// Namespace returns a derivative Kind that operates in the passed-in
+// namespace, regardless of the store's namespace resolver.
+// It panics if the namespace is invalid.
+func (k *Kind) Namespace(namespace string) *Kind {
+	if err := types.ValidateNamespace(namespace); err != nil {
+		panic(err)
+	}
+	return k.inNamespace(namespace)
+}
+
+// inNamespace returns a derivative Kind in the passed-in namespace,
+// which is not validated.
+func (k *Kind) inNamespace(namespace string) *Kind {
+	ret := *k
+	ret.opts = k.opts.Clone()
+	ret.namespace = namespace
+	ret.fixedNamespace = true
+	return &ret
+}
+
+// Global returns a derivative Kind that operates in the default
+// namespace, regardless of the store's namespace resolver.
+// It is meant for kinds that are shared by all tenants.
+func (k *Kind) Global() *Kind {
+	return k.Namespace("")
+}
+
+// related returns the store's Kind of the passed-in name and namespace
+// for entities related to the kind's, e.g. included or referenced ones.
+// It has its own cache policy and ID strategy, but operates with the
+// passed-in options of the calling action on the kind.
+func (k *Kind) related(name, namespace string, opts *types.Opts) *Kind {
+	ret := k.store.Kind(name).inNamespace(namespace)
+	ret.opts = opts.Clone()
+	return ret
+}
+
+// resolved returns whether the kind's namespace is known without
+// a context, i.e. the store's namespace resolver does not apply.
+func (k *Kind) resolved() bool {
+	return k.fixedNamespace || k.store.resolver == nil
+}
+
+// resolve returns the kind to operate on in the passed-in context:
+// a derivative Kind in the namespace resolved by the store, if it applies.
+// If the resolved namespace is invalid, the kind records an error.
+func (k *Kind) resolve(ctx ae.Context) *Kind {
+	if k.resolved() {
+		return k
+	}
+	namespace := k.store.resolver(ctx)
+	ret := k.inNamespace(namespace)
+	if err := types.ValidateNamespace(namespace); err != nil && ret.err == nil {
+		ret.err = fmt.Errorf("cannot resolve namespace: %v", err)
+	}
+	return ret
+}
+
+// toInternal returns the internal representation of the kind
+// for an action on the passed-in entities, which may be nil.
+func (k *Kind) toInternal(ctx ae.Context, entities interface{}) *types.Kind {
+	kind := types.NewKind(ctx, k.name)
+	kind.Namespace = k.namespace
+	kind.Codecs = k.store.codecs
+	kind.Cache = k.store.cache
+	kind.CachePolicy = k.cachePolicy(entities)
+	kind.Logger = k.store.logger
+	kind.Metrics = k.store.metrics
+	kind.Listener = k.store.listener
+	kind.IDStrategy = k.idStrategy
+	if k.idStrategy == DatastoreIDs {
+		kind.IDStrategy = k.allocatedIDs(kind)
+	}
+	return kind
+}
+
+// startSpan opens a span for an operation on the kind.
+func (k *Kind) startSpan(ctx ae.Context, name string) trace.Span {
+	span := k.store.startSpan(ctx, name)
+	span.SetAttribute("kind", k.name)
+	span.SetAttribute("namespace", k.namespace)
+	return span
+}
+
+// cachePolicy returns the cache policy of the kind or else the one
+// declared for the type of the passed-in entities, if any.
+func (k *Kind) cachePolicy(entities interface{}) cache.Policy {
+	if k.policy != nil {
+		return *k.policy
+	}
+	return k.store.policies.get(entities)
+}
+
 // Save returns a Saver action object.
 // It allows to save entities to the datastore.
 func (k *Kind) Save(ctx ae.Context) *Saver {
@@ -50,6 +206,43 @@ func (k *Kind) Query(ctx ae.Context) *Query {
 	return newQuery(ctx, k)
 }
 
+// AllocateIDs allocates n numeric IDs for the kind and returns them as
+// complete keys. The datastore does not assign these IDs automatically,
+// so they can be used to save new entities, e.g. to build the keys
+// of their children beforehand. It can also receive an optional parent key.
+func (k *Kind) AllocateIDs(ctx ae.Context, n int, parent ...*Key) (ret []*Key, err error) {
+	action := newActionContext(ctx, k)
+	span := action.kind.startSpan(ctx, "hrd.allocate")
+	defer func() {
+		finishSpan(span, ret, err)
+	}()
+
+	if err = action.validate(optionalKeys(parent)); err != nil {
+		return nil, err
+	}
+
+	keys, err := action.backend().AllocateIDs(action.Kind(), optionalKey(parent), n)
+	return importKeys(keys), err
+}
+
+// AllocateIDRange reserves the numeric IDs from start to end, inclusive,
+// for the kind, so the datastore does not assign them automatically.
+// It returns an error if any of them may already be in use.
+// It can also receive an optional parent key.
+func (k *Kind) AllocateIDRange(ctx ae.Context, start, end int64, parent ...*Key) (err error) {
+	action := newActionContext(ctx, k)
+	span := action.kind.startSpan(ctx, "hrd.allocate")
+	span.SetAttribute("range", fmt.Sprintf("%d-%d", start, end))
+	defer func() {
+		span.Finish(err)
+	}()
+
+	if err = action.validate(optionalKeys(parent)); err != nil {
+		return err
+	}
+	return action.backend().AllocateIDRange(action.Kind(), optionalKey(parent), start, end)
+}
+
 // NewNumKey returns a key for the passed kind and numeric ID.
 // It can also receive an optional parent key.
 func (k *Kind) NewNumKey(id int64, parent ...*Key) *Key {
@@ -60,6 +253,12 @@ func (k *Kind) NewNumKey(id int64, parent ...*Key) *Key {
 	return newNumKey(k, id, parentKey)
 }
 
+// NewRef returns a reference to the entity with the passed key,
+// bound to the kind.
+func (k *Kind) NewRef(key *Key) *Ref {
+	return newRef(k, key)
+}
+
 // NewNumKeys returns a sequence of key for the passed kind and
 // sequence of numeric ID.
 func (k *Kind) NewNumKeys(ids ...int64) []*Key {
@@ -1,6 +1,14 @@
 package hrd
 
-import . "github.com/101loops/bdd"
+import (
+	"time"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
 
 var _ = Describe("Kind", func() {
 
@@ -49,4 +57,283 @@ var _ = Describe("Kind", func() {
 		Check(key.Parent(), NotNil)
 		Check(key.Parent().StringID(), Equals, "xyz")
 	})
+
+	It("should be able to disable the local cache", func() {
+		kind := myStore.Kind("my-kind").NoLocalCache()
+
+		Check(kind.opts.NoLocalCache, IsTrue)
+		Check(kind.Load(ctx).opts.NoLocalCache, IsTrue)
+	})
+
+	It("should pass its options on to its actions", func() {
+		kind := myStore.Kind("my-kind").NoGlobalCache().CompleteKeys()
+
+		Check(kind.Load(ctx).opts.NoGlobalCache, IsTrue)
+		Check(kind.Save(ctx).opts.CompleteKeys, IsTrue)
+		Check(kind.Delete(ctx).opts.NoGlobalCache, IsTrue)
+		Check(kind.Query(ctx).opts.NoGlobalCache, IsTrue)
+		Check(kind.Query(ctx).opts.CompleteKeys, IsTrue)
+	})
+
+	It("should not pass its options on to existing actions", func() {
+		kind := myStore.Kind("my-kind")
+		qry := kind.Query(ctx)
+		kind.NoGlobalCache()
+
+		Check(qry.opts.NoGlobalCache, IsFalse)
+		Check(qry.NoLocalCache().opts.NoGlobalCache, IsFalse)
+	})
+
+	It("should use the cache policy declared for an entity type", func() {
+		store := NewStore(withBackend(myBackend))
+		policy := cache.Policy{Mode: cache.ReadOnly, TTL: time.Minute}
+		store.RegisterEntityMust(&MyModel{}, policy)
+		kind := store.Kind("my-kind")
+
+		Check(kind.toInternal(ctx, &MyModel{}).CachePolicy, Equals, policy)
+		Check(kind.toInternal(ctx, []*MyModel{}).CachePolicy, Equals, policy)
+		Check(kind.toInternal(ctx, nil).CachePolicy, Equals, cache.Policy{})
+	})
+
+	It("should prefer its own cache policy", func() {
+		store := NewStore(withBackend(myBackend))
+		store.RegisterEntityMust(&MyModel{}, cache.Policy{Mode: cache.ReadOnly})
+		policy := cache.Policy{Mode: cache.Never}
+		kind := store.Kind("my-kind").CachePolicy(policy)
+
+		Check(kind.toInternal(ctx, &MyModel{}).CachePolicy, Equals, policy)
+		Check(kind.Namespace("my-ns").toInternal(ctx, nil).CachePolicy, Equals, policy)
+	})
+
+	It("should create keys in its namespace", func() {
+		kind := myKind.Namespace("my-ns")
+
+		Check(kind.NewNumKey(42).Namespace(), Equals, "my-ns")
+		Check(kind.NewTextKeys("abc")[0].Namespace(), Equals, "my-ns")
+		Check(myKind.NewNumKey(42).Namespace(), Equals, "")
+	})
+
+	It("should inherit the namespace of a parent key", func() {
+		kind := myKind.Namespace("my-ns")
+		key := kind.NewNumKey(42, kind.NewNumKey(66))
+
+		Check(key.Namespace(), Equals, "my-ns")
+		Check(key.Error(), IsNil)
+	})
+
+	It("should record an error for a parent key of another namespace", func() {
+		parent := myKind.Namespace("my-ns").NewNumKey(66)
+		key := myKind.NewNumKey(42, parent)
+
+		err := key.Error()
+		Check(err, ErrorContains, `namespace "my-ns" of parent`)
+		Check(err, ErrorContains, `differs from namespace "" of kind "my-kind"`)
+		Check(err.(*InvalidKeyError).Key.IntID(), EqualsNum, 42)
+	})
+
+	It("should query in its namespace", func() {
+		qry := myKind.Namespace("my-ns").Query(ctx)
+
+		Check(qry.inner.Namespace, Equals, "my-ns")
+	})
+
+	It("should not accept an invalid namespace", func() {
+		Check(func() {
+			myKind.Namespace("invalid namespace!")
+		}, Panics)
+	})
+
+	Context("namespace resolver", func() {
+
+		var (
+			store *Store
+		)
+
+		BeforeEach(func() {
+			store = NewStore(withBackend(myBackend)).NamespaceResolver(func(c ae.Context) string {
+				Check(c, Equals, ctx)
+				return "tenant"
+			})
+		})
+
+		AfterEach(func() {
+			myBackend.delete = nil
+		})
+
+		It("should operate in the resolved namespace", func() {
+			kind := store.Kind("my-kind")
+
+			myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
+				Check(kind.Namespace, Equals, "tenant")
+				Check(keys[0].Namespace, Equals, "tenant")
+				return nil
+			}
+			Check(kind.Delete(ctx).ID(42), IsNil)
+
+			Check(kind.Query(ctx).inner.Namespace, Equals, "tenant")
+			Check(kind.NewNumKey(42).Namespace(), Equals, "")
+		})
+
+		It("should let keys inherit the namespace of a parent key", func() {
+			parent := store.Kind("my-kind").Namespace("tenant").NewNumKey(66)
+			key := store.Kind("my-kind").NewNumKey(42, parent)
+
+			Check(key.Namespace(), Equals, "tenant")
+			Check(key.Error(), IsNil)
+		})
+
+		It("should not apply to kinds with an explicit namespace", func() {
+			Check(store.Kind("my-kind").Namespace("my-ns").Query(ctx).inner.Namespace, Equals, "my-ns")
+			Check(store.Namespace("my-ns").Kind("my-kind").Query(ctx).inner.Namespace, Equals, "my-ns")
+		})
+
+		It("should not apply to global kinds", func() {
+			kind := store.Kind("my-kind").Global()
+
+			myBackend.delete = func(kind *types.Kind, keys ...*types.Key) error {
+				Check(kind.Namespace, Equals, "")
+				return nil
+			}
+			Check(kind.Delete(ctx).ID(42), IsNil)
+			Check(kind.Query(ctx).inner.Namespace, Equals, "")
+		})
+
+		It("should not accept an invalid namespace", func() {
+			store.NamespaceResolver(func(ae.Context) string {
+				return "invalid namespace!"
+			})
+			myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+				panic("unexpected call")
+			}
+			kind := store.Kind("my-kind")
+
+			err := kind.Delete(ctx).ID(42)
+			Check(err, ErrorContains, `cannot resolve namespace: invalid namespace "invalid namespace!"`)
+
+			_, err = kind.Query(ctx).GetCount()
+			Check(err, ErrorContains, "cannot resolve namespace")
+		})
+
+		It("should refuse keys outside of the resolved namespace", func() {
+			myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+				panic("unexpected call")
+			}
+			kind := store.Kind("my-kind")
+
+			err := kind.Delete(ctx).Key(kind.NewNumKey(42))
+			Check(err, ErrorContains, `namespace "" differs from namespace "tenant" of the action`)
+			_, ok := err.(*InvalidKeyError)
+			Check(ok, IsTrue)
+
+			var entity *MyModel
+			_, err = kind.Load(ctx).Key(kind.NewNumKey(42)).GetOne(&entity)
+			Check(err, ErrorContains, `differs from namespace "tenant"`)
+
+			myBackend.delete = nil
+			Check(kind.Delete(ctx).Key(kind.Namespace("tenant").NewNumKey(42)), IsNil)
+		})
+	})
+	Context("validation", func() {
+
+		var invalidKind *Kind
+
+		BeforeEach(func() {
+			invalidKind = myStore.Kind("__invalid")
+		})
+
+		AfterEach(func() {
+			myBackend.get = nil
+			myBackend.put = nil
+			myBackend.delete = nil
+			myBackend.count = nil
+			myBackend.allocate = nil
+		})
+
+		It("should record an error on an invalid key", func() {
+			Check(myKind.NewNumKey(1).Error(), IsNil)
+			Check(myKind.NewTextKey("a").Error(), IsNil)
+
+			err := myKind.NewNumKey(-1).Error()
+			Check(err, ErrorContains, "negative ID -1")
+			Check(err.(*InvalidKeyError).Key.IntID(), EqualsNum, -1)
+
+			Check(invalidKind.NewNumKey(1).Error(), ErrorContains, `kind "__invalid" is reserved`)
+			Check(myStore.Kind("").NewTextKey("a").Error(), ErrorContains, "empty kind name")
+		})
+
+		It("should refuse invalid keys", func() {
+			myBackend.get = func(_ *types.Kind, _ []*types.Key, _ interface{}, _ *types.Opts, _ bool) ([]*types.Key, error) {
+				panic("unexpected call")
+			}
+			myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+				panic("unexpected call")
+			}
+
+			var entity *MyModel
+			_, err := myKind.Load(ctx).ID(-1).GetOne(&entity)
+			Check(err, ErrorContains, "negative ID -1")
+			_, ok := err.(*InvalidKeyError)
+			Check(ok, IsTrue)
+
+			err = myKind.Delete(ctx).Key(myKind.NewNumKey(-1))
+			Check(err, ErrorContains, "negative ID -1")
+			_, ok = err.(*InvalidKeyError)
+			Check(ok, IsTrue)
+		})
+
+		It("should refuse an invalid kind", func() {
+			myBackend.put = func(_ *types.Kind, _ interface{}, _ *types.Opts) ([]*types.Key, error) {
+				panic("unexpected call")
+			}
+
+			_, err := invalidKind.Save(ctx).Entity(&MyModel{})
+			Check(err, ErrorContains, `invalid kind: kind "__invalid" is reserved`)
+			Check(err.(*InvalidKindError).Name, Equals, "__invalid")
+
+			var entity *MyModel
+			_, err = invalidKind.Load(ctx).ID(1).GetOne(&entity)
+			Check(err, ErrorContains, "invalid kind")
+
+			err = invalidKind.Delete(ctx).ID(1)
+			Check(err, ErrorContains, "invalid kind")
+		})
+
+		It("should refuse to query an invalid kind", func() {
+			myBackend.count = func(_ *types.Kind, _ *types.Query) (int, error) {
+				panic("unexpected call")
+			}
+
+			_, err := invalidKind.Query(ctx).GetCount()
+			Check(err, ErrorContains, "invalid kind")
+
+			var entities []*MyModel
+			_, _, err = invalidKind.Query(ctx).NoGlobalCache().NoLocalCache().GetAll(&entities)
+			Check(err, ErrorContains, "invalid kind")
+			_, _, err = invalidKind.Query(ctx).GetAll(&entities)
+			Check(err, ErrorContains, "invalid kind")
+			_, _, err = invalidKind.Query(ctx).GetKeys()
+			Check(err, ErrorContains, "invalid kind")
+
+			var entity *MyModel
+			_, err = invalidKind.Query(ctx).GetFirst(&entity)
+			Check(err, ErrorContains, "invalid kind")
+
+			_, err = invalidKind.Query(ctx).Run().Cursor()
+			Check(err, ErrorContains, "invalid kind")
+		})
+
+		It("should allocate IDs without a parent key", func() {
+			myBackend.allocate = func(_ *types.Kind, parent *types.Key, n int) ([]*types.Key, error) {
+				Check(parent, IsNil)
+				return make([]*types.Key, n), nil
+			}
+
+			keys, err := myKind.AllocateIDs(ctx, 2, nil)
+			Check(err, IsNil)
+			Check(keys, HasLen, 2)
+
+			_, err = invalidKind.AllocateIDs(ctx, 2, nil)
+			Check(err, ErrorContains, "invalid kind")
+		})
+	})
 })
@@ -1,11 +1,19 @@
 package hrd
 
-import ae "appengine"
+import (
+	"reflect"
+
+	ae "appengine"
+)
 
 // Loader can load entities from a kind.
 type Loader struct {
 	*actionContext
 	keys []*Key
+	refs []*Ref
+
+	// includes are the fields to load related entities into.
+	includes []string
 }
 
 // newLoader creates a new Loader for the passed kind.
@@ -14,13 +22,28 @@ func newLoader(ctx ae.Context, kind *Kind) *Loader {
 	return &Loader{actionContext: newActionContext(ctx, kind)}
 }
 
-// NoGlobalCache prevents reading/writing entities from/to memcache.
+// NoGlobalCache prevents reading/writing entities from/to the global cache.
 func (l *Loader) NoGlobalCache() *Loader {
 	l.opts = l.opts.Clone()
 	l.opts.NoGlobalCache = true
 	return l
 }
 
+// NoLocalCache prevents reading/writing entities from/to
+// the in-memory cache of the context.
+func (l *Loader) NoLocalCache() *Loader {
+	l.opts = l.opts.Clone()
+	l.opts.NoLocalCache = true
+	return l
+}
+
+// Include loads the entities related to the loaded entities into the
+// passed fields, in one batch per kind (see Query.Include).
+func (l *Loader) Include(fields ...string) *Loader {
+	l.includes = append(l.includes, fields...)
+	return l
+}
+
 // Key loads a single entity by key from the datastore.
 func (l *Loader) Key(key *Key) *SingleLoader {
 	l.keys = []*Key{key}
@@ -57,6 +80,26 @@ func (l *Loader) TextIDs(ids ...string) *MultiLoader {
 	return &MultiLoader{l}
 }
 
+// Refs loads the entities referenced by the passed entities, e.g. the
+// results of a query, at once. Only references to the loader's kind
+// and namespace are considered. Once loaded into a slice, the entities are assigned to
+// the references, so resolving them does not require another lookup.
+func (l *Loader) Refs(entities interface{}) *MultiLoader {
+	l.keys, l.refs = nil, nil
+	seen := make(map[string]bool)
+	walkRefs(reflect.ValueOf(entities), func(ref *Ref) {
+		if ref.key == nil || ref.key.Kind() != l.kind.name || ref.key.Namespace() != l.kind.namespace {
+			return
+		}
+		l.refs = append(l.refs, ref)
+		if encoded := ref.key.Encode(); !seen[encoded] {
+			seen[encoded] = true
+			l.keys = append(l.keys, ref.key)
+		}
+	})
+	return &MultiLoader{l}
+}
+
 // SingleLoader is a special Loader that allows to fetch exactly one entity
 // from the datastore.
 type SingleLoader struct {
@@ -72,9 +115,53 @@ func (l *SingleLoader) GetOne(dst interface{}) (*Key, error) {
 	return nil, err
 }
 
-func (l *Loader) get(dst interface{}, multi bool) ([]*Key, error) {
-	keys, err := dsGet(l.Kind(), toInternalKeys(l.keys), dst, !l.opts.NoGlobalCache, multi)
-	return importKeys(keys), err
+func (l *Loader) get(dst interface{}, multi bool) (ret []*Key, err error) {
+	span := l.kind.startSpan(l.ctx, "hrd.load")
+	defer func() {
+		finishSpan(span, l.keys, err)
+	}()
+
+	internalKeys := toInternalKeys(l.keys)
+	if err = l.validate(internalKeys); err != nil {
+		return nil, err
+	}
+
+	keys, err := l.backend().Get(l.kindOf(dst), internalKeys, dst, l.opts, multi)
+	ret = importKeys(keys)
+	l.kind.bindRefs(l.opts, dst)
+	if err == nil && l.refs != nil {
+		l.resolveRefs(ret, dst)
+	}
+	if err == nil && len(l.includes) > 0 {
+		err = l.kind.loadIncludes(l.ctx, l.opts, dst, l.includes)
+	}
+	return ret, err
+}
+
+// resolveRefs assigns the loaded entities to the references
+// of the loader, if they were loaded into a slice.
+func (l *Loader) resolveRefs(keys []*Key, dsts interface{}) {
+	dstsVal := reflect.Indirect(reflect.ValueOf(dsts))
+	if dstsVal.Kind() != reflect.Slice || dstsVal.Len() != len(keys) {
+		return
+	}
+
+	entities := make(map[string]reflect.Value, len(keys))
+	for i, key := range keys {
+		var entity reflect.Value // not found
+		if key.Exists() {
+			entity = reflect.ValueOf(dstsVal.Index(i).Interface())
+		}
+		entities[key.Encode()] = entity
+	}
+	for _, ref := range l.refs {
+		if entity, ok := entities[ref.key.Encode()]; ok {
+			if ref.kind == nil {
+				ref.kind = l.kind
+			}
+			ref.setEntity(entity)
+		}
+	}
 }
 
 // MultiLoader is a special Loader that allows to fetch multiple entities
@@ -2,29 +2,29 @@ package hrd
 
 import (
 	. "github.com/101loops/bdd"
-	"github.com/101loops/hrd/internal"
 	"github.com/101loops/hrd/internal/types"
 )
 
 var _ = Describe("Loader", func() {
 
 	BeforeEach(func() {
-		dsGet = func(_ *types.Kind, _ []*types.Key, _ interface{}, _ bool, _ bool) ([]*types.Key, error) {
+		myBackend.get = func(_ *types.Kind, _ []*types.Key, _ interface{}, _ *types.Opts, _ bool) ([]*types.Key, error) {
 			panic("unexpected call")
 		}
 	})
 
 	AfterEach(func() {
-		dsGet = internal.Get
+		myBackend.get = nil
 	})
 
 	It("should load an entity", func() {
 		entity := &MyModel{}
 
-		dsGet = func(kind *types.Kind, keys []*types.Key, dst interface{}, useGlobalCache bool, multi bool) ([]*types.Key, error) {
+		myBackend.get = func(kind *types.Kind, keys []*types.Key, dst interface{}, opts *types.Opts, multi bool) ([]*types.Key, error) {
 			Check(multi, IsFalse)
 			Check(dst, Equals, entity)
-			Check(useGlobalCache, IsTrue)
+			Check(opts.NoGlobalCache, IsFalse)
+			Check(opts.NoLocalCache, IsFalse)
 			Check(kind.Name, Equals, "my-kind")
 			Check(keys, Equals, toInternalKeys(myKind.NewNumKeys(42)))
 			return keys, nil
@@ -38,7 +38,7 @@ var _ = Describe("Loader", func() {
 	It("should load multiple entities", func() {
 		entities := []*MyModel{&MyModel{}, &MyModel{}}
 
-		dsGet = func(kind *types.Kind, keys []*types.Key, dsts interface{}, _ bool, multi bool) ([]*types.Key, error) {
+		myBackend.get = func(kind *types.Kind, keys []*types.Key, dsts interface{}, _ *types.Opts, multi bool) ([]*types.Key, error) {
 			Check(multi, IsTrue)
 			Check(dsts, Equals, entities)
 			Check(kind.Name, Equals, "my-kind")
@@ -52,14 +52,23 @@ var _ = Describe("Loader", func() {
 	})
 
 	It("should be able to skip the global cache", func() {
-		dsGet = func(_ *types.Kind, _ []*types.Key, _ interface{}, useGlobalCache bool, _ bool) ([]*types.Key, error) {
-			Check(useGlobalCache, IsFalse)
+		myBackend.get = func(_ *types.Kind, _ []*types.Key, _ interface{}, opts *types.Opts, _ bool) ([]*types.Key, error) {
+			Check(opts.NoGlobalCache, IsTrue)
 			return nil, nil
 		}
 
 		myKind.Load(ctx).NoGlobalCache().ID(42).GetOne(nil)
 	})
 
+	It("should be able to skip the local cache", func() {
+		myBackend.get = func(_ *types.Kind, _ []*types.Key, _ interface{}, opts *types.Opts, _ bool) ([]*types.Key, error) {
+			Check(opts.NoLocalCache, IsTrue)
+			return nil, nil
+		}
+
+		myKind.Load(ctx).NoLocalCache().ID(42).GetOne(nil)
+	})
+
 	Context("should create single-entity loader from", func() {
 		It("key", func() {
 			sl := myKind.Load(ctx).Key(myKind.NewNumKey(42))
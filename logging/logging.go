@@ -0,0 +1,106 @@
+// Package logging provides structured loggers for datastore operations.
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ae "appengine"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	// Debug is the level of detailed messages, e.g. about caching.
+	Debug Level = iota
+
+	// Info is the level of messages about datastore operations.
+	Info
+
+	// Warning is the level of messages about recoverable failures,
+	// e.g. of the global cache.
+	Warning
+
+	// Error is the level of messages about failed operations.
+	Error
+)
+
+var levelNames = map[Level]string{
+	Debug:   "debug",
+	Info:    "info",
+	Warning: "warning",
+	Error:   "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Fields are the structured data of a log message, e.g. the kind
+// or the number of keys of a datastore operation.
+type Fields map[string]interface{}
+
+// String returns the fields as space-separated "key=value" pairs,
+// sorted by key.
+func (f Fields) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%v=%v", name, f[name])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Logger logs messages about datastore operations.
+type Logger interface {
+
+	// Log logs a message with the passed-in level and fields.
+	Log(ctx ae.Context, level Level, msg string, fields Fields)
+}
+
+// contextLogger is a Logger that writes to the log of a context.
+type contextLogger struct {
+	min Level
+}
+
+// NewContextLogger returns a Logger that writes messages with at least
+// the passed-in level to the log of their context, followed by the fields.
+func NewContextLogger(min Level) Logger {
+	return &contextLogger{min}
+}
+
+func (l *contextLogger) Log(ctx ae.Context, level Level, msg string, fields Fields) {
+	if level < l.min {
+		return
+	}
+
+	if len(fields) > 0 {
+		msg = msg + " " + fields.String()
+	}
+
+	switch level {
+	case Debug:
+		ctx.Debugf("%s", msg)
+	case Info:
+		ctx.Infof("%s", msg)
+	case Warning:
+		ctx.Warningf("%s", msg)
+	default:
+		ctx.Errorf("%s", msg)
+	}
+}
+
+// discard is a Logger that discards all messages.
+type discard struct{}
+
+// Discard is a Logger that discards all messages.
+var Discard Logger = discard{}
+
+func (discard) Log(ae.Context, Level, string, Fields) {}
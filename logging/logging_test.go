@@ -0,0 +1,40 @@
+package logging
+
+import . "github.com/101loops/bdd"
+
+var _ = Describe("Logging", func() {
+
+	It("should format fields", func() {
+		Check(Fields{}.String(), Equals, "")
+		Check(Fields{"op": "get", "keys": 2}.String(), Equals, "keys=2 op=get")
+	})
+
+	It("should log to a context", func() {
+		ctx := newRecordingContext()
+		logger := NewContextLogger(Debug)
+
+		logger.Log(ctx, Debug, "a", nil)
+		logger.Log(ctx, Info, "b", Fields{"op": "get"})
+		logger.Log(ctx, Warning, "c", nil)
+		logger.Log(ctx, Error, "d", nil)
+
+		Check(ctx.msgs, Equals, []string{"debug: a", "info: b op=get", "warning: c", "error: d"})
+	})
+
+	It("should skip messages below its level", func() {
+		ctx := newRecordingContext()
+		logger := NewContextLogger(Warning)
+
+		logger.Log(ctx, Info, "a", nil)
+		logger.Log(ctx, Warning, "b", nil)
+
+		Check(ctx.msgs, Equals, []string{"warning: b"})
+	})
+
+	It("should discard messages", func() {
+		ctx := newRecordingContext()
+		Discard.Log(ctx, Error, "a", nil)
+
+		Check(ctx.msgs, HasLen, 0)
+	})
+})
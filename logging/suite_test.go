@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/101loops/bdd"
+
+	ae "appengine"
+)
+
+func TestSuite(t *testing.T) {
+	RunSpecs(t, "HRD Logging Suite")
+}
+
+// recordingContext is a context that records its log messages,
+// it does not support anything else.
+type recordingContext struct {
+	ae.Context
+	msgs []string
+}
+
+func newRecordingContext() *recordingContext {
+	return &recordingContext{}
+}
+
+func (c *recordingContext) record(level, format string, args ...interface{}) {
+	c.msgs = append(c.msgs, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *recordingContext) Debugf(format string, args ...interface{}) {
+	c.record("debug", format, args...)
+}
+
+func (c *recordingContext) Infof(format string, args ...interface{}) {
+	c.record("info", format, args...)
+}
+
+func (c *recordingContext) Warningf(format string, args ...interface{}) {
+	c.record("warning", format, args...)
+}
+
+func (c *recordingContext) Errorf(format string, args ...interface{}) {
+	c.record("error", format, args...)
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	ae "appengine"
+)
+
+// Stats are aggregated events of an operation on a kind.
+type Stats struct {
+	Kind string
+	Op   Op
+
+	// Count is the number of operations.
+	Count int
+
+	// Errors is the number of failed operations.
+	Errors int
+
+	// Entities is the sum of the operations' batch sizes.
+	Entities int
+
+	// TotalDuration is the sum of the operations' durations.
+	TotalDuration time.Duration
+
+	// MaxDuration is the longest duration of an operation.
+	MaxDuration time.Duration
+
+	CacheHits   int
+	CacheMisses int
+}
+
+// AvgDuration returns the average duration of an operation.
+func (s Stats) AvgDuration() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Count)
+}
+
+// HitRatio returns the ratio of entities loaded from a cache
+// to all entities looked up in a cache.
+func (s Stats) HitRatio() float64 {
+	total := s.CacheHits + s.CacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(total)
+}
+
+type statsKey struct {
+	kind string
+	op   Op
+}
+
+// Aggregator is a Sink that aggregates events in memory,
+// by kind and operation. It can be served as a debug page.
+type Aggregator struct {
+	mu    sync.Mutex
+	stats map[statsKey]*Stats
+	since time.Time
+}
+
+// NewAggregator returns a new Aggregator without any events.
+func NewAggregator() *Aggregator {
+	return &Aggregator{stats: make(map[statsKey]*Stats), since: time.Now()}
+}
+
+// Record aggregates an event.
+func (a *Aggregator) Record(_ ae.Context, event Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := statsKey{event.Kind, event.Op}
+	stats := a.stats[key]
+	if stats == nil {
+		stats = &Stats{Kind: event.Kind, Op: event.Op}
+		a.stats[key] = stats
+	}
+
+	stats.Count++
+	if event.Err != nil {
+		stats.Errors++
+	}
+	stats.Entities += event.BatchSize
+	stats.TotalDuration += event.Duration
+	if event.Duration > stats.MaxDuration {
+		stats.MaxDuration = event.Duration
+	}
+	stats.CacheHits += event.CacheHits
+	stats.CacheMisses += event.CacheMisses
+}
+
+// Stats returns the aggregated events, sorted by kind and operation.
+func (a *Aggregator) Stats() []Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ret := make([]Stats, 0, len(a.stats))
+	for _, stats := range a.stats {
+		ret = append(ret, *stats)
+	}
+	sort.Sort(byKindAndOp(ret))
+	return ret
+}
+
+// Reset removes all aggregated events.
+func (a *Aggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats = make(map[statsKey]*Stats)
+	a.since = time.Now()
+}
+
+// ServeHTTP writes the aggregated events as a plain text table.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	since := a.since
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "datastore operations since %v\n\n", since.Format(time.RFC3339))
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tOP\tCOUNT\tERRORS\tENTITIES\tAVG\tMAX\tCACHE HITS\tCACHE MISSES\tHIT RATIO")
+	for _, s := range a.Stats() {
+		fmt.Fprintf(tw, "%v\t%v\t%d\t%d\t%d\t%v\t%v\t%d\t%d\t%.2f\n", s.Kind, s.Op, s.Count, s.Errors,
+			s.Entities, s.AvgDuration(), s.MaxDuration, s.CacheHits, s.CacheMisses, s.HitRatio())
+	}
+	tw.Flush()
+}
+
+type byKindAndOp []Stats
+
+func (s byKindAndOp) Len() int      { return len(s) }
+func (s byKindAndOp) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byKindAndOp) Less(i, j int) bool {
+	if s[i].Kind != s[j].Kind {
+		return s[i].Kind < s[j].Kind
+	}
+	return s[i].Op < s[j].Op
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/101loops/bdd"
+)
+
+var _ = Describe("Aggregator", func() {
+
+	var (
+		agg *Aggregator
+	)
+
+	BeforeEach(func() {
+		agg = NewAggregator()
+		agg.Record(nil, Event{Op: OpGet, Kind: "b", BatchSize: 2, Duration: time.Second, CacheHits: 1, CacheMisses: 1})
+		agg.Record(nil, Event{Op: OpGet, Kind: "b", BatchSize: 1, Duration: 3 * time.Second, CacheHits: 1})
+		agg.Record(nil, Event{Op: OpPut, Kind: "b", BatchSize: 1, Err: fmt.Errorf("an error")})
+		agg.Record(nil, Event{Op: OpGet, Kind: "a", BatchSize: 1})
+	})
+
+	It("should aggregate events by kind and operation", func() {
+		stats := agg.Stats()
+
+		Check(stats, HasLen, 3)
+		Check(stats[0].Kind, Equals, "a")
+		Check(stats[1], Equals, Stats{
+			Kind: "b", Op: OpGet, Count: 2, Entities: 3, TotalDuration: 4 * time.Second,
+			MaxDuration: 3 * time.Second, CacheHits: 2, CacheMisses: 1,
+		})
+		Check(stats[2].Op, Equals, OpPut)
+		Check(stats[2].Errors, EqualsNum, 1)
+	})
+
+	It("should calculate averages", func() {
+		stats := agg.Stats()
+
+		Check(stats[1].AvgDuration(), Equals, 2*time.Second)
+		Check(stats[1].HitRatio(), EqualsNum, 2.0/3.0)
+		Check(stats[0].HitRatio(), EqualsNum, 0)
+	})
+
+	It("should be reset", func() {
+		agg.Reset()
+
+		Check(agg.Stats(), HasLen, 0)
+	})
+
+	It("should serve the aggregated events", func() {
+		w := httptest.NewRecorder()
+		agg.ServeHTTP(w, nil)
+
+		Check(w.Body.String(), Contains, "KIND")
+		Check(w.Body.String(), Contains, "0.67")
+	})
+})
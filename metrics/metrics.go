@@ -0,0 +1,73 @@
+// Package metrics provides sinks for events about datastore operations,
+// e.g. to monitor their latency or the effectiveness of caching.
+package metrics
+
+import (
+	"time"
+
+	ae "appengine"
+)
+
+// Op is the type of a datastore operation.
+type Op string
+
+const (
+	// OpGet loads entities by key.
+	OpGet Op = "get"
+
+	// OpPut saves entities.
+	OpPut Op = "put"
+
+	// OpDelete deletes entities by key.
+	OpDelete Op = "delete"
+
+	// OpQuery loads the results of a query.
+	OpQuery Op = "query"
+
+	// OpCount counts the results of a query.
+	OpCount Op = "count"
+
+	// OpTransaction runs a transaction.
+	OpTransaction Op = "transaction"
+
+	// OpAllocate allocates numeric IDs.
+	OpAllocate Op = "allocate"
+)
+
+// Event describes a datastore operation that ran.
+type Event struct {
+
+	// Op is the type of the operation.
+	Op Op
+
+	// Kind is the name of the operation's kind.
+	Kind string
+
+	// Namespace is the namespace of the operation's kind.
+	Namespace string
+
+	// BatchSize is the number of entities the operation affected
+	// or, for a count, the number of results.
+	BatchSize int
+
+	// Duration is how long the operation took.
+	Duration time.Duration
+
+	// CacheHits is the number of entities loaded from a cache.
+	CacheHits int
+
+	// CacheMisses is the number of entities loaded from the datastore
+	// after they were not found in a cache.
+	CacheMisses int
+
+	// Err is the error of the operation, if any.
+	Err error
+}
+
+// Sink receives events about datastore operations.
+// It must be safe for concurrent use.
+type Sink interface {
+
+	// Record receives an event about an operation that ran in the context.
+	Record(ctx ae.Context, event Event)
+}
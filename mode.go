@@ -0,0 +1,85 @@
+package hrd
+
+import (
+	"fmt"
+
+	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/internal/types"
+)
+
+// writeMode defines whether and how a Store writes to the datastore.
+type writeMode int
+
+const (
+	// readWrite writes to the datastore.
+	readWrite writeMode = iota
+
+	// readOnly refuses to write.
+	readOnly
+
+	// dryRun validates and logs writes and ID allocations,
+	// but never executes them.
+	dryRun
+)
+
+// ReadOnlyError is returned when saving or deleting entities
+// or allocating IDs with a read-only Store.
+type ReadOnlyError struct {
+
+	// Op is the type of the refused operation.
+	Op OpType
+
+	// Kind is the name of the kind that was written to.
+	Kind string
+}
+
+func (e *ReadOnlyError) Error() string {
+	what := "entities"
+	if e.Op == OpAllocate {
+		what = "IDs"
+	}
+	return fmt.Sprintf("cannot %v %v of %q: store is read-only", e.Op, what, e.Kind)
+}
+
+// readOnlyBackend is a backend that refuses to write.
+type readOnlyBackend struct {
+	backend
+}
+
+func (b *readOnlyBackend) Put(kind *types.Kind, _ interface{}, _ *types.Opts) ([]*types.Key, error) {
+	return nil, &ReadOnlyError{OpPut, kind.Name}
+}
+
+func (b *readOnlyBackend) Delete(kind *types.Kind, _ ...*types.Key) error {
+	return &ReadOnlyError{OpDelete, kind.Name}
+}
+
+func (b *readOnlyBackend) AllocateIDs(kind *types.Kind, _ *types.Key, _ int) ([]*types.Key, error) {
+	return nil, &ReadOnlyError{OpAllocate, kind.Name}
+}
+
+func (b *readOnlyBackend) AllocateIDRange(kind *types.Kind, _ *types.Key, _, _ int64) error {
+	return &ReadOnlyError{OpAllocate, kind.Name}
+}
+
+// dryRunBackend is a backend that validates, encodes
+// and logs writes, but never executes them.
+type dryRunBackend struct {
+	backend
+}
+
+func (b *dryRunBackend) Put(kind *types.Kind, src interface{}, opts *types.Opts) ([]*types.Key, error) {
+	return internal.DryRunPut(kind, src, opts.CompleteKeys)
+}
+
+func (b *dryRunBackend) Delete(kind *types.Kind, keys ...*types.Key) error {
+	return internal.DryRunDelete(kind, keys...)
+}
+
+func (b *dryRunBackend) AllocateIDs(kind *types.Kind, parent *types.Key, n int) ([]*types.Key, error) {
+	return internal.DryRunAllocateIDs(kind, parent, n)
+}
+
+func (b *dryRunBackend) AllocateIDRange(kind *types.Kind, parent *types.Key, start, end int64) error {
+	return internal.DryRunAllocateIDRange(kind, parent, start, end)
+}
@@ -0,0 +1,161 @@
+package hrd
+
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+
+	ae "appengine"
+)
+
+var _ = Describe("Write Mode", func() {
+
+	type MyEntity struct {
+		entity.NumID
+		Text string `datastore:"text"`
+	}
+
+	BeforeEach(func() {
+		myBackend.put = func(_ *types.Kind, _ interface{}, _ *types.Opts) ([]*types.Key, error) {
+			panic("unexpected call")
+		}
+		myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+			panic("unexpected call")
+		}
+		myBackend.allocate = func(_ *types.Kind, _ *types.Key, _ int) ([]*types.Key, error) {
+			panic("unexpected call")
+		}
+		myBackend.allocateRange = func(_ *types.Kind, _ *types.Key, _, _ int64) error {
+			panic("unexpected call")
+		}
+	})
+
+	AfterEach(func() {
+		myBackend.put = nil
+		myBackend.delete = nil
+		myBackend.transact = nil
+		myBackend.allocate = nil
+		myBackend.allocateRange = nil
+	})
+
+	Context("read-only", func() {
+
+		It("should refuse to save and delete", func() {
+			kind := NewStore(withBackend(myBackend)).ReadOnly().Kind("my-kind")
+
+			_, err := kind.Save(ctx).Entity(&MyModel{})
+			Check(err, Equals, &ReadOnlyError{OpPut, "my-kind"})
+			Check(err, ErrorContains, "store is read-only")
+
+			err = kind.Delete(ctx).ID(42)
+			Check(err, Equals, &ReadOnlyError{OpDelete, "my-kind"})
+		})
+
+		It("should refuse to allocate IDs", func() {
+			kind := NewStore(withBackend(myBackend)).ReadOnly().Kind("my-kind")
+
+			_, err := kind.AllocateIDs(ctx, 2)
+			Check(err, Equals, &ReadOnlyError{OpAllocate, "my-kind"})
+			Check(err, ErrorContains, `cannot allocate IDs of "my-kind"`)
+
+			err = kind.AllocateIDRange(ctx, 1, 10)
+			Check(err, Equals, &ReadOnlyError{OpAllocate, "my-kind"})
+		})
+
+		It("should apply to existing kinds", func() {
+			store := NewStore(withBackend(myBackend))
+			kind := store.Kind("my-kind")
+			store.ReadOnly()
+
+			err := kind.Delete(ctx).ID(42)
+			Check(err, Equals, &ReadOnlyError{OpDelete, "my-kind"})
+		})
+
+		It("should be shared with derivative stores", func() {
+			store := NewStore(withBackend(myBackend))
+			nsStore := store.Namespace("my-ns")
+			store.ReadOnly()
+
+			err := nsStore.Kind("my-kind").Delete(ctx).ID(42)
+			Check(err, Equals, &ReadOnlyError{OpDelete, "my-kind"})
+		})
+
+		It("should refuse to write inside a transaction", func() {
+			myBackend.transact = func(ctx ae.Context, _ bool, f func(ae.Context) error) error {
+				return f(ctx)
+			}
+
+			store := NewStore(withBackend(myBackend)).ReadOnly()
+			err := store.TX(ctx).Run(func(tx TX) error {
+				return store.Kind("my-kind").Delete(tx).ID(42)
+			})
+			Check(err, Equals, &ReadOnlyError{OpDelete, "my-kind"})
+		})
+	})
+
+	Context("dry run", func() {
+
+		It("should validate and log writes without executing them", func() {
+			logger := &msgLogger{}
+			store := NewStore(withBackend(myBackend), WithLogger(logger)).DryRun()
+			store.RegisterEntityMust(&MyEntity{})
+			kind := store.Kind("my-kind")
+
+			entity := &MyEntity{Text: "hello"}
+			entity.SetID(42)
+			key, err := kind.Save(ctx).Entity(entity)
+			Check(err, IsNil)
+			Check(key, Equals, kind.NewNumKey(42))
+
+			err = kind.Delete(ctx).ID(42)
+			Check(err, IsNil)
+
+			Check(logger.msgs, HasLen, 2)
+			Check(logger.msgs[0], Contains, "dry run: putting")
+			Check(logger.msgs[1], Contains, "dry run: deleting")
+		})
+
+		It("should log ID allocations without executing them", func() {
+			logger := &msgLogger{}
+			store := NewStore(withBackend(myBackend), WithLogger(logger)).DryRun()
+			store.RegisterEntityMust(&MyEntity{})
+			kind := store.Kind("my-kind")
+
+			keys, err := kind.AllocateIDs(ctx, 2)
+			Check(err, IsNil)
+			Check(keys, HasLen, 2)
+			Check(keys[0].Incomplete(), IsTrue)
+
+			err = kind.AllocateIDRange(ctx, 1, 10)
+			Check(err, IsNil)
+
+			entity := &MyEntity{}
+			keys, err = kind.Save(ctx).AssignIDs(entity)
+			Check(err, IsNil)
+			Check(keys[0].Incomplete(), IsTrue)
+			Check(entity.ID(), EqualsNum, 0)
+
+			Check(logger.msgs, HasLen, 3)
+			Check(logger.msgs[0], Contains, "dry run: allocating 2 IDs")
+			Check(logger.msgs[1], Contains, "dry run: allocating IDs 1 to 10")
+		})
+
+		It("should reject invalid entities", func() {
+			store := NewStore(withBackend(myBackend)).DryRun()
+			store.RegisterEntityMust(&MyEntity{})
+
+			_, err := store.Kind("my-kind").Save(ctx).CompleteKeys().Entity(&MyEntity{})
+			Check(err, ErrorContains, "is incomplete")
+		})
+	})
+})
+
+// msgLogger is a Logger that records the logged messages.
+type msgLogger struct {
+	msgs []string
+}
+
+func (l *msgLogger) Log(_ ae.Context, _ logging.Level, msg string, _ logging.Fields) {
+	l.msgs = append(l.msgs, msg)
+}
@@ -0,0 +1,50 @@
+package hrd
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/101loops/hrd/cache"
+)
+
+// cachePolicies contains the cache policies declared for entity types.
+type cachePolicies struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type]cache.Policy
+}
+
+func newCachePolicies() *cachePolicies {
+	return &cachePolicies{byType: make(map[reflect.Type]cache.Policy)}
+}
+
+// set declares the cache policy for the type of the passed-in entity.
+func (cp *cachePolicies) set(entity interface{}, policy cache.Policy) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.byType[entityType(entity)] = policy
+}
+
+// get returns the cache policy declared for the type of the passed-in
+// entities, or else the default policy.
+func (cp *cachePolicies) get(entities interface{}) cache.Policy {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+
+	return cp.byType[entityType(entities)]
+}
+
+// entityType returns the struct type of an entity, a pointer to one
+// or a slice of either. It returns nil for a nil value.
+func entityType(v interface{}) reflect.Type {
+	typ := reflect.TypeOf(v)
+	for typ != nil {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			typ = typ.Elem()
+		default:
+			return typ
+		}
+	}
+	return nil
+}
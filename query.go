@@ -2,6 +2,9 @@ package hrd
 
 import (
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
+	"github.com/101loops/hrd/trace"
 
 	ae "appengine"
 )
@@ -12,16 +15,22 @@ type Query struct {
 	ctx   ae.Context
 	kind  *Kind
 	opts  *types.Opts
+
+	// includes are the fields to load related entities into.
+	includes []string
 }
 
 // newQuery creates a new Query for the passed kind.
 // The kind's options are used as default options.
 func newQuery(ctx ae.Context, kind *Kind) (ret *Query) {
+	kind = kind.resolve(ctx)
+	inner := types.NewQuery(kind.name)
+	inner.Namespace = kind.namespace
 	return &Query{
-		inner: types.NewQuery(kind.name),
+		inner: inner,
 		ctx:   ctx,
 		kind:  kind,
-		opts:  types.DefaultOpts(),
+		opts:  kind.opts.Clone(),
 	}
 }
 
@@ -29,29 +38,31 @@ func (qry *Query) clone() *Query {
 	ret := *qry
 	ret.opts = qry.opts.Clone()
 	ret.inner = qry.inner.Clone()
-	//	if len(qry.log) > 0 {
-	//		ret.log = make([]string, len(qry.log))
-	//		copy(ret.log, qry.log)
-	//	}
+	ret.includes = append([]string(nil), qry.includes...)
 	return &ret
 }
 
-// NoGlobalCache prevents reading/writing entities from/to memcache.
+// NoGlobalCache prevents reading/writing entities from/to the global cache.
 func (qry *Query) NoGlobalCache() (ret *Query) {
 	ret = qry.clone()
 	ret.opts.NoGlobalCache = true
 	return
 }
 
+// NoLocalCache prevents reading/writing entities from/to
+// the in-memory cache of the context.
+func (qry *Query) NoLocalCache() (ret *Query) {
+	ret = qry.clone()
+	ret.opts.NoLocalCache = true
+	return
+}
+
 // Limit returns a derivative Query that has a limit on the number
 // of results returned. A negative value means unlimited.
 func (qry *Query) Limit(limit int) (ret *Query) {
 	ret = qry.clone()
-	if limit > 0 {
-		//ret.log("LIMIT %v", limit)
-	} else {
+	if limit <= 0 {
 		limit = -1
-		//ret.log("NO LIMIT")
 	}
 	ret.inner.Limit = limit
 	return
@@ -67,7 +78,6 @@ func (qry *Query) NoLimit() (ret *Query) {
 // The ancestor should not be nil.
 func (qry *Query) Ancestor(k *Key) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("ANCESTOR '%v'", k.String())
 	ret.inner.Ancestor = k.inner
 	return
 }
@@ -76,7 +86,6 @@ func (qry *Query) Ancestor(k *Key) (ret *Query) {
 // It cannot be used in a keys-only query.
 func (qry *Query) Project(fields ...string) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("PROJECT '%v'", strings.Join(fields, "', '"))
 	ret.inner.Projection = append([]string(nil), fields...)
 	ret.inner.TypeOf = types.ProjectQuery
 	return
@@ -86,7 +95,6 @@ func (qry *Query) Project(fields ...string) (ret *Query) {
 // consistent results. It only has an effect on ancestor queries.
 func (qry *Query) EventualConsistency() (ret *Query) {
 	ret = qry.clone()
-	//ret.log("EVENTUAL CONSISTENCY")
 	ret.inner.Eventual = true
 	return
 }
@@ -94,7 +102,6 @@ func (qry *Query) EventualConsistency() (ret *Query) {
 // Start returns a derivative Query with the passed start point.
 func (qry *Query) Start(c string) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("START CURSOR")
 	ret.inner.Start = c
 	return
 }
@@ -102,7 +109,6 @@ func (qry *Query) Start(c string) (ret *Query) {
 // End returns a derivative Query with the passed end point.
 func (qry *Query) End(c string) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("END CURSOR")
 	ret.inner.End = c
 	return
 }
@@ -111,7 +117,6 @@ func (qry *Query) End(c string) (ret *Query) {
 // to skip over before returning results. A negative value is invalid.
 func (qry *Query) Offset(off int) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("OFFSET %v", off)
 	ret.inner.Offset = off
 	return
 }
@@ -120,7 +125,6 @@ func (qry *Query) Offset(off int) (ret *Query) {
 // Orders are applied in the order they are added.
 func (qry *Query) OrderAsc(s string) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("ORDER ASC %v", s)
 	ret.inner.Order = append(ret.inner.Order, types.Order{FieldName: s, Descending: false})
 	return
 }
@@ -129,7 +133,6 @@ func (qry *Query) OrderAsc(s string) (ret *Query) {
 // Orders are applied in the order they are added.
 func (qry *Query) OrderDesc(s string) (ret *Query) {
 	ret = qry.clone()
-	//ret.log("ORDER DESC %v", s)
 	ret.inner.Order = append(ret.inner.Order, types.Order{FieldName: s, Descending: true})
 	return
 }
@@ -151,30 +154,54 @@ func (qry *Query) Distinct() (ret *Query) {
 func (qry *Query) Filter(q string, val interface{}) (ret *Query) {
 	ret = qry.clone()
 	ret.inner.Filter = append(ret.inner.Filter, types.Filter{Filter: q, Value: val})
-	//ret.log("FILTER '%v %v'", q, val)
+	return
+}
+
+// Include loads the entities related to the results into the passed fields.
+// Each field is tagged with the name of the field holding the keys of the
+// related entities (*Key, []*Key, *Ref or []*Ref), e.g.
+//
+//	AuthorKey *hrd.Key `datastore:"author"`
+//	Author    *User    `datastore:"-" hrd:"AuthorKey"`
+//
+// The related entities are loaded in one batch per kind.
+func (qry *Query) Include(fields ...string) (ret *Query) {
+	ret = qry.clone()
+	ret.includes = append(ret.includes, fields...)
 	return
 }
 
 // GetCount returns the number of results for the query.
-func (qry *Query) GetCount() (int, error) {
-	//qry.log("COUNT")
-	//qry.ctx.Infof(qry.getLog())
+func (qry *Query) GetCount() (count int, err error) {
+	span := qry.startSpan("hrd.count")
+	defer func() {
+		span.SetAttribute("count", count)
+		span.Finish(err)
+	}()
 
-	return dsCount(qry.ctx, qry.inner)
+	if qry.kind.err != nil {
+		return 0, qry.kind.err
+	}
+	return qry.kind.store.run().Count(qry.kind.toInternal(qry.ctx, nil), qry.inner)
 }
 
 // GetKeys executes the query as keys-only: No entities are retrieved, just their keys.
-func (qry *Query) GetKeys() ([]*Key, string, error) {
+func (qry *Query) GetKeys() (keys []*Key, cursor string, err error) {
 	keysQry := qry.clone()
-	//keysQry.log("KEYS-ONLY")
 	keysQry.inner.TypeOf = types.KeysOnlyQuery
 
+	span := keysQry.startSpan("hrd.query")
+	span.SetAttribute("path", "keys-only")
+	defer func() {
+		finishSpan(span, keys, err)
+	}()
+
 	it := keysQry.Run()
-	keys, err := it.GetAll(nil)
+	keys, err = it.GetAll(nil)
 	if err != nil {
 		return nil, "", err
 	}
-	cursor, err := it.Cursor()
+	cursor, err = it.Cursor()
 	return keys, cursor, err
 }
 
@@ -185,12 +212,29 @@ func (qry *Query) GetKeys() ([]*Key, string, error) {
 // and then the keys are used to lookup the local and global cache as well
 // as the datastore eventually. For a warm cache this usually is
 // faster and cheaper than the regular query.
-func (qry *Query) GetAll(dsts interface{}) ([]*Key, string, error) {
-	useHybridQry := qry.inner.Limit != 1 && qry.inner.TypeOf == types.FullQuery && !qry.opts.NoGlobalCache
+func (qry *Query) GetAll(dsts interface{}) (keys []*Key, cursor string, err error) {
+	span := qry.startSpan("hrd.query")
+	defer func() {
+		finishSpan(span, keys, err)
+	}()
+
+	useCache := (!qry.opts.NoGlobalCache || !qry.opts.NoLocalCache) && qry.kind.cachePolicy(dsts).Enabled()
+	useHybridQry := qry.inner.Limit != 1 && qry.inner.TypeOf == types.FullQuery && useCache
 	if useHybridQry {
-		return qry.getAllByHybrid(dsts)
+		span.SetAttribute("path", "hybrid")
+		keys, cursor, err = qry.getAllByHybrid(dsts)
+	} else {
+		span.SetAttribute("path", "direct")
+		keys, cursor, err = qry.getAllDirect(dsts)
 	}
 
+	if err == nil && len(qry.includes) > 0 {
+		err = qry.kind.loadIncludes(qry.ctx, qry.opts, dsts, qry.includes)
+	}
+	return
+}
+
+func (qry *Query) getAllDirect(dsts interface{}) ([]*Key, string, error) {
 	it := qry.Run()
 	keys, err := it.GetAll(dsts)
 	if err != nil {
@@ -203,27 +247,47 @@ func (qry *Query) GetAll(dsts interface{}) ([]*Key, string, error) {
 func (qry *Query) getAllByHybrid(dsts interface{}) ([]*Key, string, error) {
 	keys, cursor, err := qry.GetKeys()
 	if err == nil && len(keys) > 0 {
-		keys, err = newLoader(qry.ctx, qry.kind).Keys(keys).GetAll(dsts)
+		loader := newLoader(qry.ctx, qry.kind)
+		loader.opts = qry.opts.Clone()
+		keys, err = loader.Keys(keys).GetAll(dsts)
 	}
 	return keys, cursor, err
 }
 
 // GetFirst executes the query and writes the result's first entity
 // to the passed destination.
-func (qry *Query) GetFirst(dst interface{}) (*Key, error) {
-	return qry.Run().GetOne(dst)
+func (qry *Query) GetFirst(dst interface{}) (key *Key, err error) {
+	span := qry.startSpan("hrd.query")
+	span.SetAttribute("path", "direct")
+	defer func() {
+		span.SetAttribute("found", key != nil)
+		span.Finish(err)
+	}()
+
+	key, err = qry.Run().GetOne(dst)
+	if err == nil && key != nil && len(qry.includes) > 0 {
+		err = qry.kind.loadIncludes(qry.ctx, qry.opts, dst, qry.includes)
+	}
+	return
 }
 
-// Run executes the query and returns an Iterator.
+// Run returns an Iterator for the query.
+// The query executes once the Iterator loads its results.
 func (qry *Query) Run() *Iterator {
-	//qry.ctx.Infof(qry.getLog())
+	qry.log("running query", logging.Fields{"op": string(metrics.OpQuery)})
 	return newIterator(qry)
 }
 
-//func (qry *Query) log(s string, values ...interface{}) {
-//	qry.log = append(qry.log, fmt.Sprintf(s, values...))
-//}
-//
-//func (qry *Query) getLog() string {
-//	return fmt.Sprintf("running query \"%v\"", strings.Join(qry.log, " | "))
-//}
+// startSpan opens a span for the query, including its description.
+func (qry *Query) startSpan(name string) trace.Span {
+	span := qry.kind.startSpan(qry.ctx, name)
+	span.SetAttribute("query", qry.inner.String())
+	return span
+}
+
+// log logs a message about the query, including its description.
+func (qry *Query) log(msg string, fields logging.Fields) {
+	fields["kind"] = qry.kind.name
+	fields["query"] = qry.inner
+	qry.kind.toInternal(qry.ctx, nil).Log(logging.Info, msg, fields)
+}
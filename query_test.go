@@ -3,10 +3,7 @@ package hrd
 import (
 	"fmt"
 	. "github.com/101loops/bdd"
-	"github.com/101loops/hrd/internal"
 	"github.com/101loops/hrd/internal/types"
-
-	ae "appengine"
 )
 
 var _ = Describe("Query", func() {
@@ -17,21 +14,21 @@ var _ = Describe("Query", func() {
 
 	BeforeEach(func() {
 		query = myKind.Query(ctx)
-		dsCount = func(_ ae.Context, _ *types.Query) (int, error) {
+		myBackend.count = func(_ *types.Kind, _ *types.Query) (int, error) {
 			panic("unexpected call")
 		}
-		dsIterate = func(_ *types.Iterator, _ interface{}, _ bool) ([]*types.Key, error) {
+		myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
 			panic("unexpected call")
 		}
-		dsGet = func(_ *types.Kind, _ []*types.Key, _ interface{}, _ bool, _ bool) ([]*types.Key, error) {
+		myBackend.get = func(_ *types.Kind, _ []*types.Key, _ interface{}, _ *types.Opts, _ bool) ([]*types.Key, error) {
 			panic("unexpected call")
 		}
 	})
 
 	AfterEach(func() {
-		dsGet = internal.Get
-		dsCount = internal.Count
-		dsIterate = internal.Iterate
+		myBackend.get = nil
+		myBackend.count = nil
+		myBackend.query = nil
 	})
 
 	Context("building the query", func() {
@@ -148,7 +145,8 @@ var _ = Describe("Query", func() {
 		Context("count", func() {
 
 			It("should return the result's size", func() {
-				dsCount = func(_ ae.Context, _ *types.Query) (int, error) {
+				myBackend.count = func(kind *types.Kind, _ *types.Query) (int, error) {
+					Check(kind.Name, Equals, myKind.name)
 					return 42, nil
 				}
 				c, err := query.GetCount()
@@ -157,7 +155,7 @@ var _ = Describe("Query", func() {
 			})
 
 			It("should return an error when the operation fails", func() {
-				dsCount = func(_ ae.Context, _ *types.Query) (int, error) {
+				myBackend.count = func(_ *types.Kind, _ *types.Query) (int, error) {
 					return 0, fmt.Errorf("an error")
 				}
 				c, err := query.GetCount()
@@ -169,9 +167,9 @@ var _ = Describe("Query", func() {
 		Context("keys", func() {
 
 			It("should return the result's keys", func() {
-				dsIterate = func(_ *types.Iterator, _ interface{}, multi bool) ([]*types.Key, error) {
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, multi bool) (types.Iterator, []*types.Key, error) {
 					Check(multi, IsTrue)
-					return retKeys, nil
+					return nil, retKeys, nil
 				}
 				keys, _, err := query.GetKeys()
 				Check(err, IsNil)
@@ -179,8 +177,8 @@ var _ = Describe("Query", func() {
 			})
 
 			It("should return an error when the operation fails", func() {
-				dsIterate = func(_ *types.Iterator, _ interface{}, _ bool) ([]*types.Key, error) {
-					return nil, fmt.Errorf("an error")
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+					return nil, nil, fmt.Errorf("an error")
 				}
 
 				_, _, err := query.GetKeys()
@@ -193,9 +191,9 @@ var _ = Describe("Query", func() {
 			var entity MyModel
 
 			It("should return the first result", func() {
-				dsIterate = func(_ *types.Iterator, _ interface{}, multi bool) ([]*types.Key, error) {
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, multi bool) (types.Iterator, []*types.Key, error) {
 					Check(multi, IsFalse)
-					return retKeys[0:1], nil
+					return nil, retKeys[0:1], nil
 				}
 				key, err := query.GetFirst(&entity)
 				Check(err, IsNil)
@@ -203,8 +201,8 @@ var _ = Describe("Query", func() {
 			})
 
 			It("should return nil when there is no result", func() {
-				dsIterate = func(_ *types.Iterator, _ interface{}, _ bool) ([]*types.Key, error) {
-					return []*types.Key{}, nil
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+					return nil, []*types.Key{}, nil
 				}
 				key, err := query.GetFirst(&entity)
 				Check(err, IsNil)
@@ -217,15 +215,15 @@ var _ = Describe("Query", func() {
 			var entities []*MyModel
 
 			It("should use hybrid query by default", func() {
-				dsIterate = func(_ *types.Iterator, _ interface{}, multi bool) ([]*types.Key, error) {
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, multi bool) (types.Iterator, []*types.Key, error) {
 					Check(multi, IsTrue)
-					return retKeys, nil
+					return nil, retKeys, nil
 				}
 
-				dsGet = func(kind *types.Kind, keys []*types.Key, _ interface{}, useGlobalCache bool, multi bool) ([]*types.Key, error) {
+				myBackend.get = func(kind *types.Kind, keys []*types.Key, _ interface{}, opts *types.Opts, multi bool) ([]*types.Key, error) {
 					Check(kind.Name, Equals, myKind.name)
 					Check(keys, Equals, retKeys)
-					Check(useGlobalCache, IsTrue)
+					Check(opts.NoGlobalCache, IsFalse)
 					Check(multi, IsTrue)
 					return retKeys, nil
 				}
@@ -237,9 +235,9 @@ var _ = Describe("Query", func() {
 
 			It("should run the iterator otherwise", func() {
 				fetchWithIterator := func(q *Query) {
-					dsIterate = func(_ *types.Iterator, _ interface{}, multi bool) ([]*types.Key, error) {
+					myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, multi bool) (types.Iterator, []*types.Key, error) {
 						Check(multi, IsTrue)
-						return retKeys, nil
+						return nil, retKeys, nil
 					}
 
 					keys, _, err := q.GetAll(&entities)
@@ -249,12 +247,27 @@ var _ = Describe("Query", func() {
 
 				fetchWithIterator(query.Limit(1))
 				fetchWithIterator(query.Project("a"))
-				fetchWithIterator(query.NoGlobalCache())
+				fetchWithIterator(query.NoGlobalCache().NoLocalCache())
+			})
+
+			It("should pass the query's options to the hybrid query", func() {
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+					return nil, retKeys, nil
+				}
+
+				myBackend.get = func(_ *types.Kind, _ []*types.Key, _ interface{}, opts *types.Opts, _ bool) ([]*types.Key, error) {
+					Check(opts.NoGlobalCache, IsTrue)
+					Check(opts.NoLocalCache, IsFalse)
+					return retKeys, nil
+				}
+
+				_, _, err := query.NoGlobalCache().GetAll(&entities)
+				Check(err, IsNil)
 			})
 
 			It("should return an error when the query fails", func() {
-				dsIterate = func(_ *types.Iterator, _ interface{}, _ bool) ([]*types.Key, error) {
-					return nil, fmt.Errorf("an error")
+				myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+					return nil, nil, fmt.Errorf("an error")
 				}
 
 				_, _, err := query.NoGlobalCache().GetAll(&entities)
@@ -0,0 +1,222 @@
+package hrd
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+	ds "appengine/datastore"
+)
+
+// Ref is a reference to an entity of a kind. As an entity field
+// it is saved as the key of the referenced entity.
+//
+// A Ref is bound to the kind of the referenced entity, either when it is
+// created by Kind.NewRef or when it is loaded by a store, which allows
+// to resolve it to the entity.
+type Ref struct {
+	key  *Key
+	kind *Kind
+
+	// resolved is whether the referenced entity was loaded already.
+	resolved bool
+	entity   reflect.Value
+}
+
+var (
+	_ types.DSKeyConverter = (*Ref)(nil)
+	_ types.KeySetter      = (*Ref)(nil)
+
+	typeOfRef = reflect.TypeOf(&Ref{})
+)
+
+func newRef(kind *Kind, key *Key) *Ref {
+	return &Ref{key: key, kind: kind}
+}
+
+// Key returns the key of the referenced entity.
+func (r *Ref) Key() *Key {
+	return r.key
+}
+
+// Kind returns the kind the reference is bound to, which may be nil.
+func (r *Ref) Kind() *Kind {
+	return r.kind
+}
+
+// SetKey makes the reference point to the entity with the passed key.
+func (r *Ref) SetKey(key entity.Key) {
+	*r = Ref{}
+	if k, ok := key.(*Key); ok {
+		r.key = k
+	} else if key != nil {
+		r.key, _ = DecodeKey(key.Encode())
+	}
+}
+
+// IsResolved returns whether the referenced entity was loaded already.
+func (r *Ref) IsResolved() bool {
+	return r.resolved
+}
+
+// Resolve loads the referenced entity into the passed destination.
+// If the reference was resolved before, the entity is not loaded again.
+// It returns datastore.ErrNoSuchEntity if the entity does not exist.
+func (r *Ref) Resolve(ctx ae.Context, dst interface{}) error {
+	if r.key == nil {
+		return fmt.Errorf("cannot resolve reference without key")
+	}
+
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr || dstVal.IsNil() {
+		return fmt.Errorf("invalid value kind %q (wanted non-nil pointer)", dstVal.Kind())
+	}
+
+	if r.resolved {
+		return r.assign(dstVal.Elem())
+	}
+
+	if r.kind == nil {
+		return fmt.Errorf("reference to %v is not bound to a kind", r.key)
+	}
+
+	key, err := r.kind.Load(ctx).Key(r.key).GetOne(dst)
+	if err != nil {
+		return err
+	}
+	if !key.Exists() {
+		r.setEntity(reflect.Value{})
+		return r.assign(dstVal.Elem())
+	}
+	r.setEntity(reflect.ValueOf(dstVal.Elem().Interface()))
+	return nil
+}
+
+// ToDSKey returns the datastore.Key of the referenced entity.
+func (r *Ref) ToDSKey(ctx ae.Context) *ds.Key {
+	if r.key == nil {
+		return nil
+	}
+	return r.key.ToDSKey(ctx)
+}
+
+func (r *Ref) String() string {
+	return fmt.Sprintf("Ref{%v}", r.key)
+}
+
+func (r *Ref) setEntity(entity reflect.Value) {
+	r.resolved = true
+	r.entity = entity
+}
+
+func (r *Ref) assign(dst reflect.Value) error {
+	if !r.entity.IsValid() || isNil(r.entity) {
+		dst.Set(reflect.Zero(dst.Type()))
+		return ds.ErrNoSuchEntity
+	}
+	if !r.entity.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("cannot resolve reference of type %q into %q", r.entity.Type(), dst.Type())
+	}
+	dst.Set(r.entity)
+	return nil
+}
+
+// bindRefs binds the unbound references of the passed entities to
+// the store's kind of the referenced entity, with the passed-in options
+// of the calling action on the kind (see Kind.related).
+func (k *Kind) bindRefs(opts *types.Opts, entities interface{}) {
+	walkRefs(reflect.ValueOf(entities), func(ref *Ref) {
+		if ref.kind == nil && ref.key != nil {
+			ref.kind = k.related(ref.key.Kind(), ref.key.Namespace(), opts)
+		}
+	})
+}
+
+// walkRefs calls the function for every non-nil reference in the value.
+func walkRefs(v reflect.Value, f func(*Ref)) {
+	if !v.IsValid() || !hasRefs(v.Type()) {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if v.Type() == typeOfRef {
+			f(v.Interface().(*Ref))
+			return
+		}
+		walkRefs(v.Elem(), f)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkRefs(v.Index(i), f)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			walkRefs(v.MapIndex(k), f)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				walkRefs(v.Field(i), f)
+			}
+		}
+	}
+}
+
+var (
+	refTypes      = make(map[reflect.Type]bool)
+	refTypesMutex sync.RWMutex
+)
+
+// hasRefs returns whether values of the type can contain references.
+func hasRefs(typ reflect.Type) bool {
+	refTypesMutex.RLock()
+	ret, ok := refTypes[typ]
+	refTypesMutex.RUnlock()
+	if ok {
+		return ret
+	}
+
+	refTypesMutex.Lock()
+	defer refTypesMutex.Unlock()
+	return hasRefsLocked(typ)
+}
+
+func hasRefsLocked(typ reflect.Type) bool {
+	if ret, ok := refTypes[typ]; ok {
+		return ret
+	}
+	refTypes[typ] = false // prevents endless recursion
+
+	ret := false
+	switch typ.Kind() {
+	case reflect.Interface:
+		ret = typeOfRef.Implements(typ)
+	case reflect.Ptr:
+		ret = typ == typeOfRef || hasRefsLocked(typ.Elem())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		ret = hasRefsLocked(typ.Elem())
+	case reflect.Struct:
+		for i := 0; i < typ.NumField() && !ret; i++ {
+			field := typ.Field(i)
+			ret = field.PkgPath == "" && hasRefsLocked(field.Type)
+		}
+	}
+
+	refTypes[typ] = ret
+	return ret
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package hrd
+
+import (
+	"time"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/entity"
+
+	ds "appengine/datastore"
+)
+
+type refModel struct {
+	entity.NumID
+
+	Ref  *Ref
+	Refs []*Ref
+}
+
+var _ = Describe("Ref", func() {
+
+	BeforeEach(func() {
+		myStore.RegisterEntityMust(&refModel{})
+	})
+
+	It("should create a reference bound to a kind", func() {
+		key := myKind.NewNumKey(42)
+		ref := myKind.NewRef(key)
+
+		Check(ref.Key(), Equals, key)
+		Check(ref.Kind(), Equals, myKind)
+		Check(ref.IsResolved(), IsFalse)
+		Check(ref.String(), Equals, "Ref{Key{'my-kind', 42}}")
+	})
+
+	It("should convert to a datastore key", func() {
+		ref := myKind.NewRef(myKind.NewNumKey(42))
+		Check(ref.ToDSKey(ctx).IntID(), EqualsNum, 42)
+		Check((&Ref{}).ToDSKey(ctx), IsNil)
+	})
+
+	It("should set the key", func() {
+		ref := myKind.NewRef(myKind.NewNumKey(1))
+
+		key := myKind.NewNumKey(2)
+		ref.SetKey(key)
+		Check(ref.Key(), Equals, key)
+		Check(ref.Kind(), IsNil)
+	})
+
+	It("should bind loaded references to the kind of their key", func() {
+		src := &refModel{
+			Ref:  myKind.NewRef(myKind.Namespace("my-ns").NewNumKey(1)),
+			Refs: []*Ref{myKind.NewRef(myKind.NewNumKey(2))},
+		}
+		src.SetID(1)
+		_, err := myKind.Save(ctx).Entity(src)
+		Check(err, IsNil)
+
+		var dst *refModel
+		_, err = myKind.Load(ctx).ID(1).GetOne(&dst)
+		Check(err, IsNil)
+
+		Check(dst.Ref.Key().Equal(src.Ref.Key()), IsTrue)
+		Check(dst.Ref.Kind().Name(), Equals, "my-kind")
+		Check(dst.Ref.Kind().namespace, Equals, "my-ns")
+		Check(dst.Refs, HasLen, 1)
+		Check(dst.Refs[0].Kind().Name(), Equals, "my-kind")
+	})
+
+	It("should bind loaded references with the options of the caller, but their own policy", func() {
+		ownPolicy := cache.Policy{Mode: cache.WriteThrough, TTL: time.Hour}
+		store := NewStore(withBackend(myBackend))
+		store.RegisterEntityMust(&refModel{}, ownPolicy)
+
+		policy := cache.Policy{Mode: cache.ReadOnly, TTL: time.Minute}
+		kind := store.Kind("my-kind").NoGlobalCache().CachePolicy(policy).IDStrategy(UUIDs)
+
+		src := &refModel{Refs: []*Ref{myKind.NewRef(myKind.NewNumKey(2))}}
+		src.SetID(1)
+		_, err := kind.Save(ctx).Entity(src)
+		Check(err, IsNil)
+
+		var dst *refModel
+		_, err = kind.Load(ctx).NoLocalCache().ID(1).GetOne(&dst)
+		Check(err, IsNil)
+
+		Check(dst.Refs, HasLen, 1)
+		bound := dst.Refs[0].Kind()
+		Check(bound.Name(), Equals, "my-kind")
+		Check(bound.opts.NoGlobalCache, IsTrue)
+		Check(bound.opts.NoLocalCache, IsTrue)
+		Check(bound.cachePolicy(&refModel{}), Equals, ownPolicy)
+		Check(bound.idStrategy, IsNil)
+	})
+
+	It("should resolve a reference", func() {
+		target := &refModel{}
+		target.SetID(10)
+		_, err := myKind.Save(ctx).Entity(target)
+		Check(err, IsNil)
+
+		ref := myKind.NewRef(myKind.NewNumKey(10))
+
+		var dst *refModel
+		err = ref.Resolve(ctx, &dst)
+		Check(err, IsNil)
+		Check(dst.ID(), EqualsNum, 10)
+		Check(ref.IsResolved(), IsTrue)
+	})
+
+	It("should load referenced entities at once", func() {
+		targets := []*refModel{{}, {}}
+		targets[0].SetID(20)
+		targets[1].SetID(21)
+		_, err := myKind.Save(ctx).Entities(targets)
+		Check(err, IsNil)
+
+		srcs := []*refModel{
+			{Ref: myKind.NewRef(myKind.NewNumKey(20))},
+			{Ref: myKind.NewRef(myKind.NewNumKey(21)), Refs: []*Ref{myKind.NewRef(myKind.NewNumKey(20))}},
+			{Ref: myStore.Kind("other-kind").NewRef(myStore.Kind("other-kind").NewNumKey(20))},
+			{Ref: myKind.NewRef(myKind.Namespace("my-ns").NewNumKey(20))},
+		}
+
+		var loaded []*refModel
+		keys, err := myKind.Load(ctx).Refs(srcs).GetAll(&loaded)
+		Check(err, IsNil)
+		Check(keys, HasLen, 2)
+
+		Check(srcs[0].Ref.IsResolved(), IsTrue)
+		Check(srcs[1].Ref.IsResolved(), IsTrue)
+		Check(srcs[1].Refs[0].IsResolved(), IsTrue)
+		Check(srcs[2].Ref.IsResolved(), IsFalse)
+		Check(srcs[3].Ref.IsResolved(), IsFalse)
+
+		var dst *refModel
+		err = srcs[1].Ref.Resolve(ctx, &dst)
+		Check(err, IsNil)
+		Check(dst, Equals, loaded[1])
+	})
+
+	// ==== ERRORS
+
+	It("should not resolve a missing entity", func() {
+		ref := myKind.NewRef(myKind.NewNumKey(666))
+
+		var dst *refModel
+		err := ref.Resolve(ctx, &dst)
+		Check(err, Equals, ds.ErrNoSuchEntity)
+		Check(dst, IsNil)
+	})
+
+	It("should not resolve a reference without key", func() {
+		var dst *refModel
+		err := (&Ref{}).Resolve(ctx, &dst)
+		Check(err, ErrorContains, "cannot resolve reference without key")
+	})
+
+	It("should not resolve an unbound reference", func() {
+		ref := &Ref{}
+		ref.SetKey(myKind.NewNumKey(1))
+
+		var dst *refModel
+		err := ref.Resolve(ctx, &dst)
+		Check(err, ErrorContains, "reference to Key{'my-kind', 1} is not bound to a kind")
+	})
+
+	It("should not resolve into an invalid destination", func() {
+		var dst *refModel
+		err := myKind.NewRef(myKind.NewNumKey(1)).Resolve(ctx, dst)
+		Check(err, ErrorContains, `invalid value kind "ptr" (wanted non-nil pointer)`)
+	})
+})
@@ -0,0 +1,96 @@
+// Package rpc provides listeners for the App Engine service calls,
+// e.g. to the datastore or memcache, made on behalf of hrd operations.
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	ae "appengine"
+)
+
+// Call describes a service call that was made.
+type Call struct {
+
+	// Service is the name of the called service, e.g. "datastore_v3".
+	Service string
+
+	// Method is the name of the called method, e.g. "Get".
+	Method string
+
+	// RequestSize is the size of the encoded request in bytes.
+	RequestSize int
+
+	// Duration is how long the call took.
+	Duration time.Duration
+
+	// Err is the error of the call, if any.
+	Err error
+}
+
+// Listener is notified of service calls.
+// It must be safe for concurrent use.
+type Listener interface {
+
+	// OnCall is notified of a call made in the passed-in context.
+	OnCall(ctx ae.Context, call Call)
+}
+
+// ListenerFunc is a function that is a Listener.
+type ListenerFunc func(ctx ae.Context, call Call)
+
+// OnCall calls the function.
+func (f ListenerFunc) OnCall(ctx ae.Context, call Call) {
+	f(ctx, call)
+}
+
+// Counter is a Listener that counts the calls by service and method.
+type Counter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// NewCounter returns a new Counter without any calls.
+func NewCounter() *Counter {
+	return &Counter{calls: make(map[string]int)}
+}
+
+// OnCall counts a call.
+func (c *Counter) OnCall(_ ae.Context, call Call) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls[call.Service+"."+call.Method]++
+}
+
+// Calls returns the number of calls by "service.method".
+func (c *Counter) Calls() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make(map[string]int, len(c.calls))
+	for name, n := range c.calls {
+		ret[name] = n
+	}
+	return ret
+}
+
+// Total returns the number of all calls.
+func (c *Counter) Total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int
+	for _, n := range c.calls {
+		total += n
+	}
+	return total
+}
+
+// Reset removes all counted calls.
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls = make(map[string]int)
+}
@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	. "github.com/101loops/bdd"
+
+	ae "appengine"
+)
+
+var _ = Describe("Counter", func() {
+
+	It("should count calls by service and method", func() {
+		counter := NewCounter()
+		counter.OnCall(nil, Call{Service: "datastore_v3", Method: "Get"})
+		counter.OnCall(nil, Call{Service: "datastore_v3", Method: "Get"})
+		counter.OnCall(nil, Call{Service: "memcache", Method: "Get"})
+
+		Check(counter.Total(), EqualsNum, 3)
+		Check(counter.Calls(), Equals, map[string]int{"datastore_v3.Get": 2, "memcache.Get": 1})
+
+		counter.Reset()
+		Check(counter.Total(), EqualsNum, 0)
+	})
+
+	It("should adapt a function", func() {
+		var calls []Call
+		var listener Listener = ListenerFunc(func(_ ae.Context, call Call) {
+			calls = append(calls, call)
+		})
+
+		listener.OnCall(nil, Call{Service: "memcache"})
+		Check(calls, HasLen, 1)
+	})
+})
@@ -0,0 +1,11 @@
+package rpc
+
+import (
+	"testing"
+
+	. "github.com/101loops/bdd"
+)
+
+func TestSuite(t *testing.T) {
+	RunSpecs(t, "HRD RPC Suite")
+}
@@ -1,6 +1,14 @@
 package hrd
 
-import ae "appengine"
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/101loops/hrd/entity"
+	"github.com/101loops/hrd/internal/types"
+
+	ae "appengine"
+)
 
 // Saver can save entities to the datastore.
 type Saver struct {
@@ -38,7 +46,99 @@ func (s *Saver) Entities(srcs interface{}) ([]*Key, error) {
 	return s.put(srcs)
 }
 
-func (s *Saver) put(src interface{}) ([]*Key, error) {
-	keys, err := dsPut(s.Kind(), src, s.opts.CompleteKeys)
-	return importKeys(keys), err
+// AssignIDs allocates numeric IDs for the passed entities with an incomplete
+// key and assigns them, without saving the entities. This way their keys
+// are known before a batch put. It returns the keys of all entities,
+// which must implement entity.NumIdentifier if their key is incomplete.
+func (s *Saver) AssignIDs(srcs interface{}) (ret []*Key, err error) {
+	span := s.kind.startSpan(s.ctx, "hrd.allocate")
+	defer func() {
+		finishSpan(span, ret, err)
+	}()
+
+	if err = s.validate(nil); err != nil {
+		return nil, err
+	}
+
+	kind := s.kindOf(srcs)
+	entities := entityList(srcs)
+
+	keys := make([]*types.Key, len(entities))
+	var groups []string
+	incomplete := make(map[string][]int)
+	for i, src := range entities {
+		key, err := types.GetEntityKey(kind, src)
+		if err != nil {
+			return nil, err
+		}
+		if err = key.Validate(); err != nil {
+			return nil, importError(err)
+		}
+		keys[i] = key
+		if !key.Incomplete() {
+			continue
+		}
+		if _, ok := src.(entity.NumIdentifier); !ok {
+			return nil, fmt.Errorf("value type %q does not provide SetID(int64)", reflect.TypeOf(src))
+		}
+
+		// IDs are allocated per parent key
+		var group string
+		if key.Parent != nil {
+			group = key.Parent.Encode()
+		}
+		if _, ok := incomplete[group]; !ok {
+			groups = append(groups, group)
+		}
+		incomplete[group] = append(incomplete[group], i)
+	}
+
+	for _, group := range groups {
+		indices := incomplete[group]
+		allocated, err := s.backend().AllocateIDs(kind, keys[indices[0]].Parent, len(indices))
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range indices {
+			entities[i].(entity.NumIdentifier).SetID(allocated[j].IntID)
+			keys[i] = allocated[j]
+		}
+	}
+
+	return importKeys(keys), nil
+}
+
+// entityList returns the entities of the passed slice or map,
+// or the passed entity itself.
+func entityList(src interface{}) []interface{} {
+	srcVal := reflect.Indirect(reflect.ValueOf(src))
+	switch srcVal.Kind() {
+	case reflect.Slice:
+		ret := make([]interface{}, srcVal.Len())
+		for i := range ret {
+			ret[i] = srcVal.Index(i).Interface()
+		}
+		return ret
+	case reflect.Map:
+		ret := make([]interface{}, 0, srcVal.Len())
+		for _, key := range srcVal.MapKeys() {
+			ret = append(ret, srcVal.MapIndex(key).Interface())
+		}
+		return ret
+	}
+	return []interface{}{src}
+}
+
+func (s *Saver) put(src interface{}) (ret []*Key, err error) {
+	span := s.kind.startSpan(s.ctx, "hrd.save")
+	defer func() {
+		finishSpan(span, ret, err)
+	}()
+
+	if err = s.validate(nil); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.backend().Put(s.kindOf(src), src, s.opts)
+	return importKeys(keys), importError(err)
 }
@@ -2,28 +2,27 @@ package hrd
 
 import (
 	. "github.com/101loops/bdd"
-	"github.com/101loops/hrd/internal"
 	"github.com/101loops/hrd/internal/types"
 )
 
 var _ = Describe("Saver", func() {
 
 	BeforeEach(func() {
-		dsPut = func(_ *types.Kind, _ interface{}, _ bool) ([]*types.Key, error) {
+		myBackend.put = func(_ *types.Kind, _ interface{}, _ *types.Opts) ([]*types.Key, error) {
 			panic("unexpected call")
 		}
 	})
 
 	AfterEach(func() {
-		dsPut = internal.Put
+		myBackend.put = nil
 	})
 
 	It("should save an entity", func() {
 		entity := &MyModel{}
 
-		dsPut = func(kind *types.Kind, src interface{}, completeKeys bool) ([]*types.Key, error) {
+		myBackend.put = func(kind *types.Kind, src interface{}, opts *types.Opts) ([]*types.Key, error) {
 			// TODO
-			Check(completeKeys, IsFalse)
+			Check(opts.CompleteKeys, IsFalse)
 			Check(kind.Name, Equals, "my-kind")
 			return toInternalKeys(myKind.NewNumKeys(42)), nil
 		}
@@ -36,9 +35,9 @@ var _ = Describe("Saver", func() {
 	It("should save multiple entities", func() {
 		entities := []*MyModel{&MyModel{}, &MyModel{}}
 
-		dsPut = func(kind *types.Kind, src interface{}, completeKeys bool) ([]*types.Key, error) {
+		myBackend.put = func(kind *types.Kind, src interface{}, opts *types.Opts) ([]*types.Key, error) {
 			// TODO
-			Check(completeKeys, IsFalse)
+			Check(opts.CompleteKeys, IsFalse)
 			Check(kind.Name, Equals, "my-kind")
 			return toInternalKeys(myKind.NewNumKeys(1, 2)), nil
 		}
@@ -49,8 +48,8 @@ var _ = Describe("Saver", func() {
 	})
 
 	It("should be able to require complete keys", func() {
-		dsPut = func(_ *types.Kind, _ interface{}, completeKeys bool) ([]*types.Key, error) {
-			Check(completeKeys, IsTrue)
+		myBackend.put = func(_ *types.Kind, _ interface{}, opts *types.Opts) ([]*types.Key, error) {
+			Check(opts.CompleteKeys, IsTrue)
 			return nil, nil
 		}
 
@@ -1,58 +1,215 @@
 package hrd
 
 import (
+	"fmt"
 	"time"
 
-	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/cache"
 	"github.com/101loops/hrd/internal/trafo"
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/logging"
+	"github.com/101loops/hrd/metrics"
+	"github.com/101loops/hrd/rpc"
+	"github.com/101loops/hrd/trace"
+	"github.com/101loops/structor"
 
 	ae "appengine"
 )
 
-// datastore operations, makes it easy to stub out during testing
-var (
-	dsGet        = internal.Get
-	dsPut        = internal.Put
-	dsCount      = internal.Count
-	dsDelete     = internal.Delete
-	dsIterate    = internal.Iterate
-	dsTransact   = internal.Transact
-	dsDeleteKeys = internal.DeleteKeys
-)
-
 // Store represents the App Engine datastore.
 // Usually there should only be one per application.
+//
+// Options are inherited from Store to Kind to action: a Kind starts
+// with the options of its Store and an action (Loader, Saver, Deleter,
+// Query) with the options of its Kind. Since they are copied on creation,
+// changing the options of a Store or Kind does not affect the kinds or
+// actions that were created before. A Transactor is the exception: it has
+// no options, the actions inside a transaction use those of their kinds.
+//
+// Unlike options, the write mode (see ReadOnly and DryRun) and the namespace
+// resolver apply to the store's existing kinds. The write mode is shared
+// with the store's derivatives, like its registered entities.
 type Store struct {
-	opts      *types.Opts
-	createdAt time.Time
+	opts         *types.Opts
+	codecs       *structor.Set
+	policies     *cachePolicies
+	backend      backend
+	cache        cache.Cache
+	namespace    string
+	resolver     func(ae.Context) string
+	logger       logging.Logger
+	metrics      metrics.Sink
+	listener     rpc.Listener
+	tracer       trace.Tracer
+	interceptors []Interceptor
+	mode         *writeMode
+	createdAt    time.Time
+}
+
+// StoreOption configures a Store on creation.
+type StoreOption func(*Store)
+
+// withBackend makes the store execute its datastore operations
+// with the passed-in backend instead of the App Engine datastore.
+func withBackend(b backend) StoreOption {
+	return func(s *Store) {
+		s.backend = b
+	}
+}
+
+// WithCache makes the store use the passed-in Cache as global cache
+// instead of memcache. A nil Cache disables the global cache.
+func WithCache(c cache.Cache) StoreOption {
+	return func(s *Store) {
+		s.cache = c
+	}
+}
+
+// WithLogger makes the store log its operations with the passed-in Logger
+// instead of writing them to the log of their context. A nil Logger, or
+// logging.Discard, silences the store.
+func WithLogger(logger logging.Logger) StoreOption {
+	return func(s *Store) {
+		s.logger = logger
+	}
+}
+
+// WithMetrics makes the store pass events about its operations
+// to the passed-in Sink, e.g. a metrics.Aggregator.
+func WithMetrics(sink metrics.Sink) StoreOption {
+	return func(s *Store) {
+		s.metrics = sink
+	}
+}
+
+// WithListener makes the store notify the passed-in Listener of every
+// service call, e.g. to the datastore or memcache, made on behalf of
+// its operations and transactions.
+func WithListener(listener rpc.Listener) StoreOption {
+	return func(s *Store) {
+		s.listener = listener
+	}
+}
+
+// WithTracer makes the store open a span with the passed-in Tracer
+// for every operation. A nil Tracer disables tracing.
+func WithTracer(tracer trace.Tracer) StoreOption {
+	return func(s *Store) {
+		s.tracer = tracer
+	}
 }
 
 // NewStore creates a new store.
-func NewStore() *Store {
+// By default it uses the App Engine datastore and memcache,
+// and logs its operations to the log of their context.
+func NewStore(options ...StoreOption) *Store {
 	store := &Store{
 		createdAt: time.Now(),
 		opts:      types.DefaultOpts(),
+		codecs:    trafo.NewCodecSet(),
+		policies:  newCachePolicies(),
+		mode:      new(writeMode),
+		backend:   defaultBackend(),
+		cache:     cache.NewMemcache(),
+		logger:    logging.NewContextLogger(logging.Info),
+		tracer:    trace.Noop,
+	}
+	for _, opt := range options {
+		opt(store)
 	}
 	return store
 }
 
-// NoGlobalCache prevents reading/writing entities from/to memcache.
+// CompleteKeys prevents saving an entity with an incomplete key.
+func (s *Store) CompleteKeys() *Store {
+	s.opts.CompleteKeys = true
+	return s
+}
+
+// NoGlobalCache prevents reading/writing entities from/to the global cache.
 func (s *Store) NoGlobalCache() *Store {
 	s.opts.NoGlobalCache = true
 	return s
 }
 
+// NoLocalCache prevents reading/writing entities from/to
+// the in-memory cache of a context.
+func (s *Store) NoLocalCache() *Store {
+	s.opts.NoLocalCache = true
+	return s
+}
+
+// ReadOnly makes the store refuse to save or delete entities and to allocate
+// IDs, also inside transactions, by returning a *ReadOnlyError.
+// Loading and querying entities is not affected.
+func (s *Store) ReadOnly() *Store {
+	*s.mode = readOnly
+	return s
+}
+
+// DryRun makes the store validate, encode and log the entities to save
+// and the keys to delete, but never write them to the datastore or caches.
+// Saved entities keep their keys, but the returned keys include the IDs
+// generated by a kind's ID strategy, so they show what would be saved.
+// IDs are not allocated though: allocated keys are incomplete, and so are
+// the keys of a kind with DatastoreIDs.
+func (s *Store) DryRun() *Store {
+	*s.mode = dryRun
+	return s
+}
+
+// Namespace returns a derivative Store that operates in the passed-in
+// namespace, without the store's namespace resolver.
+// It panics if the namespace is invalid.
+func (s *Store) Namespace(namespace string) *Store {
+	if err := types.ValidateNamespace(namespace); err != nil {
+		panic(err)
+	}
+	ret := *s
+	ret.opts = s.opts.Clone()
+	ret.namespace = namespace
+	ret.resolver = nil
+	return &ret
+}
+
+// NamespaceResolver makes the store derive the namespace of every kind
+// operation from its context, e.g. from the tenant of a request.
+// It does not apply to kinds with an explicit namespace, such as global
+// kinds which operate in the default namespace (see Kind.Global).
+// Since keys created by a Kind have no context, they are not affected,
+// and actions refuse keys outside of the resolved namespace. Use keys
+// created by an action, e.g. Loader.ID, or by a Kind with an explicit
+// namespace instead.
+// An operation returns an error if the resolved namespace is invalid.
+func (s *Store) NamespaceResolver(resolver func(ae.Context) string) *Store {
+	s.resolver = resolver
+	return s
+}
+
 // RegisterEntity prepares the passed-in struct type for the datastore.
 // It returns an error if the type is invalid.
-func (s *Store) RegisterEntity(entity interface{}) error {
-	return trafo.CodecSet.Add(entity)
+// The type is only known to this store and its derivatives.
+// It can also receive an optional cache policy for entities of the type,
+// which applies to all kinds without their own policy.
+func (s *Store) RegisterEntity(entity interface{}, policy ...cache.Policy) error {
+	if err := s.codecs.Add(entity); err != nil {
+		return err
+	}
+	if len(policy) > 0 {
+		s.policies.set(entity, policy[0])
+	}
+	return nil
 }
 
 // RegisterEntityMust prepares the passed-in struct type for the datastore.
 // It panics if the type is invalid.
-func (s *Store) RegisterEntityMust(entity interface{}) {
-	trafo.CodecSet.AddMust(entity)
+// The type is only known to this store and its derivatives.
+// It can also receive an optional cache policy for entities of the type,
+// which applies to all kinds without their own policy.
+func (s *Store) RegisterEntityMust(entity interface{}, policy ...cache.Policy) {
+	if err := s.RegisterEntity(entity, policy...); err != nil {
+		panic(err)
+	}
 }
 
 // Kind returns a kind for the passed name.
@@ -65,6 +222,43 @@ func (s *Store) TX(ctx ae.Context) *Transactor {
 	return newTransactor(s, ctx)
 }
 
+// Cache returns the store's global Cache, which may be nil.
+func (s *Store) Cache() cache.Cache {
+	return s.cache
+}
+
+// run returns the backend to execute the store's operations,
+// which applies the store's write mode and runs them through
+// the store's interceptors.
+func (s *Store) run() backend {
+	backend := s.backend
+	switch *s.mode {
+	case readOnly:
+		backend = &readOnlyBackend{backend}
+	case dryRun:
+		backend = &dryRunBackend{backend}
+	}
+
+	if len(s.interceptors) == 0 {
+		return backend
+	}
+	return &interceptedBackend{backend, s.interceptors}
+}
+
+// startSpan opens a span with the store's tracer.
+func (s *Store) startSpan(ctx ae.Context, name string) trace.Span {
+	if s.tracer == nil {
+		return trace.Noop.StartSpan(ctx, name)
+	}
+	return s.tracer.StartSpan(ctx, name)
+}
+
+// finishSpan closes a span of an operation on the passed-in keys.
+func finishSpan(span trace.Span, keys []*Key, err error) {
+	span.SetAttribute("keys", len(keys))
+	span.Finish(err)
+}
+
 // CreatedAt returns the time the store was created.
 func (s *Store) CreatedAt() time.Time {
 	return s.createdAt
@@ -77,9 +271,38 @@ type actionContext struct {
 }
 
 func newActionContext(ctx ae.Context, kind *Kind) *actionContext {
+	kind = kind.resolve(ctx)
 	return &actionContext{ctx, kind, kind.opts.Clone()}
 }
 
 func (sa *actionContext) Kind() *types.Kind {
-	return types.NewKind(sa.ctx, sa.kind.name)
+	return sa.kind.toInternal(sa.ctx, nil)
+}
+
+// kindOf returns the internal kind for an action on the passed-in entities.
+func (sa *actionContext) kindOf(entities interface{}) *types.Kind {
+	return sa.kind.toInternal(sa.ctx, entities)
+}
+
+// validate returns an error if the action's kind or one of the keys,
+// which must be non-nil, is invalid or not in the kind's namespace.
+func (sa *actionContext) validate(keys []*types.Key) error {
+	if sa.kind.err != nil {
+		return sa.kind.err
+	}
+	for _, key := range keys {
+		if err := key.Validate(); err != nil {
+			return importError(err)
+		}
+		if key.Namespace != sa.kind.namespace {
+			return &InvalidKeyError{Key: importKey(key), Err: fmt.Errorf(
+				"namespace %q differs from namespace %q of the action",
+				key.Namespace, sa.kind.namespace)}
+		}
+	}
+	return nil
+}
+
+func (sa *actionContext) backend() backend {
+	return sa.kind.store.run()
 }
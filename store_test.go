@@ -1,15 +1,85 @@
 package hrd
 
-import . "github.com/101loops/bdd"
+import (
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/rpc"
+
+	ae "appengine"
+)
 
 var _ = Describe("Store", func() {
 
 	It("should initialize and be configurable", func() {
-		Check(myStore.opts.NoGlobalCache, IsFalse)
-		Check(myStore.CreatedAt(), Not(IsZero))
+		store := NewStore()
+		Check(store.opts.NoGlobalCache, IsFalse)
+		Check(store.CreatedAt(), Not(IsZero))
+
+		store.NoGlobalCache()
+		Check(store.opts.NoGlobalCache, IsTrue)
+	})
+
+	It("should pass its options on to new kinds", func() {
+		store := NewStore()
+		kind := store.Kind("old-kind")
+		store.NoGlobalCache().CompleteKeys()
+
+		newKind := store.Kind("new-kind")
+		Check(newKind.opts.NoGlobalCache, IsTrue)
+		Check(newKind.opts.CompleteKeys, IsTrue)
+		Check(kind.opts.NoGlobalCache, IsFalse)
+		Check(kind.opts.CompleteKeys, IsFalse)
+	})
+
+	It("should be able to disable the local cache", func() {
+		store := NewStore()
+		Check(store.opts.NoLocalCache, IsFalse)
+
+		store.NoLocalCache()
+		Check(store.opts.NoLocalCache, IsTrue)
+		Check(store.Kind("new-kind").opts.NoLocalCache, IsTrue)
+	})
+
+	It("should use the datastore backend by default", func() {
+		Check(NewStore().backend, Equals, internal.NewBackend(internal.AppEngine{}))
+	})
+
+	It("should use memcache as global cache by default", func() {
+		Check(NewStore().Cache(), Equals, cache.NewMemcache())
+	})
+
+	It("should use a custom global cache", func() {
+		lru := cache.NewLRU(10)
+		Check(NewStore(WithCache(lru)).Cache(), Equals, lru)
+		Check(NewStore(WithCache(lru)).Kind("my-kind").toInternal(ctx, nil).Cache, Equals, lru)
+		Check(NewStore(WithCache(nil)).Cache(), IsNil)
+	})
+
+	It("should pass its listener on to kinds", func() {
+		counter := rpc.NewCounter()
+
+		Check(NewStore(WithListener(counter)).Kind("my-kind").toInternal(ctx, nil).Listener, Equals, counter)
+		Check(NewStore().Kind("my-kind").toInternal(ctx, nil).Listener, IsNil)
+	})
+
+	It("should use a custom backend", func() {
+		backend := &mockBackend{}
+		Check(NewStore(withBackend(backend)).backend, Equals, backend)
+	})
+
+	It("should use a custom datastore", func() {
+		d := &countDatastore{count: 3}
+		kind := NewStore(WithDatastore(d)).Kind("my-kind").Namespace("my-ns")
+		parent := kind.NewNumKey(1)
 
-		myStore.NoGlobalCache()
-		Check(myStore.opts.NoGlobalCache, IsTrue)
+		count, err := kind.Query(ctx).Ancestor(parent).Filter("a >", 1).OrderDesc("b").Limit(5).GetCount()
+		Check(err, IsNil)
+		Check(count, EqualsNum, 3)
+		Check(d.qry, Equals, &DatastoreQuery{
+			Kind: "my-kind", Namespace: "my-ns", Ancestor: parent.inner.ToDSKey(ctx),
+			Filters: []DatastoreFilter{{"a >", 1}}, Orders: []DatastoreOrder{{"b", true}}, Limit: 5,
+		})
 	})
 
 	It("should create a kind", func() {
@@ -20,6 +90,19 @@ var _ = Describe("Store", func() {
 		Check(newKind.Name(), Equals, "new-kind")
 	})
 
+	It("should create a derivative store for a namespace", func() {
+		nsStore := myStore.Namespace("my-ns")
+
+		Check(nsStore, Not(Equals), myStore)
+		Check(nsStore.backend, Equals, myStore.backend)
+		Check(nsStore.Kind("new-kind").namespace, Equals, "my-ns")
+		Check(myStore.Kind("new-kind").namespace, Equals, "")
+
+		Check(func() {
+			myStore.Namespace("invalid namespace!")
+		}, Panics)
+	})
+
 	It("should register a new entity", func() {
 		type MyModel1 struct{}
 		err := myStore.RegisterEntity(&MyModel1{})
@@ -35,4 +118,31 @@ var _ = Describe("Store", func() {
 			myStore.RegisterEntityMust("invalid-entity")
 		}, Panics)
 	})
+
+	It("should not share registered entities with other stores", func() {
+		type MyModel struct{}
+		store1, store2 := NewStore(), NewStore()
+		store1.RegisterEntityMust(&MyModel{})
+
+		_, err := store1.codecs.Get(&MyModel{})
+		Check(err, IsNil)
+
+		_, err = store2.codecs.Get(&MyModel{})
+		Check(err, HasOccurred)
+
+		_, err = store1.Namespace("my-ns").codecs.Get(&MyModel{})
+		Check(err, IsNil)
+	})
 })
+
+// countDatastore is a Datastore that records the query it counts.
+type countDatastore struct {
+	Datastore
+	qry   *DatastoreQuery
+	count int
+}
+
+func (d *countDatastore) Count(_ ae.Context, qry *DatastoreQuery) (int, error) {
+	d.qry = qry
+	return d.count, nil
+}
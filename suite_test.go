@@ -3,28 +3,101 @@ package hrd
 import (
 	"testing"
 	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/cache"
+	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/internal/memory"
+	"github.com/101loops/hrd/internal/types"
 
-	"appengine/aetest"
+	ae "appengine"
 )
 
 var (
-	ctx     aetest.Context
-	myKind  *Kind
-	myStore *Store
+	ctx       ae.Context
+	myKind    *Kind
+	myStore   *Store
+	myBackend *mockBackend
 )
 
 type MyModel struct{}
 
 func TestSuite(t *testing.T) {
-	var err error
-	ctx, err = aetest.NewContext(nil)
-	if err != nil {
-		panic(err)
-	}
-	defer ctx.Close()
+	ctx = memory.NewContext()
 
-	myStore = NewStore()
+	myBackend = &mockBackend{backend: internal.NewBackend(memory.NewDatastore())}
+	myStore = NewStore(withBackend(myBackend), WithCache(cache.NewLRU(100)))
 	myKind = myStore.Kind("my-kind")
 
 	RunSpecs(t, "HRD API Suite")
 }
+
+// mockBackend is a backend that allows to stub out single operations.
+// Any operation that is not stubbed is passed on to the embedded backend.
+type mockBackend struct {
+	backend
+
+	get      func(*types.Kind, []*types.Key, interface{}, *types.Opts, bool) ([]*types.Key, error)
+	put      func(*types.Kind, interface{}, *types.Opts) ([]*types.Key, error)
+	delete   func(*types.Kind, ...*types.Key) error
+	count    func(*types.Kind, *types.Query) (int, error)
+	query    func(*types.Kind, *types.Query, interface{}, bool) (types.Iterator, []*types.Key, error)
+	transact func(ae.Context, bool, func(ae.Context) error) error
+
+	allocate      func(*types.Kind, *types.Key, int) ([]*types.Key, error)
+	allocateRange func(*types.Kind, *types.Key, int64, int64) error
+}
+
+func (b *mockBackend) Get(kind *types.Kind, keys []*types.Key, dst interface{}, opts *types.Opts, multi bool) ([]*types.Key, error) {
+	if b.get != nil {
+		return b.get(kind, keys, dst, opts, multi)
+	}
+	return b.backend.Get(kind, keys, dst, opts, multi)
+}
+
+func (b *mockBackend) Put(kind *types.Kind, src interface{}, opts *types.Opts) ([]*types.Key, error) {
+	if b.put != nil {
+		return b.put(kind, src, opts)
+	}
+	return b.backend.Put(kind, src, opts)
+}
+
+func (b *mockBackend) Delete(kind *types.Kind, keys ...*types.Key) error {
+	if b.delete != nil {
+		return b.delete(kind, keys...)
+	}
+	return b.backend.Delete(kind, keys...)
+}
+
+func (b *mockBackend) Count(kind *types.Kind, qry *types.Query) (int, error) {
+	if b.count != nil {
+		return b.count(kind, qry)
+	}
+	return b.backend.Count(kind, qry)
+}
+
+func (b *mockBackend) Query(kind *types.Kind, qry *types.Query, dsts interface{}, multi bool) (types.Iterator, []*types.Key, error) {
+	if b.query != nil {
+		return b.query(kind, qry, dsts, multi)
+	}
+	return b.backend.Query(kind, qry, dsts, multi)
+}
+
+func (b *mockBackend) Transact(ctx ae.Context, crossGroup bool, f func(ae.Context) error) error {
+	if b.transact != nil {
+		return b.transact(ctx, crossGroup, f)
+	}
+	return b.backend.Transact(ctx, crossGroup, f)
+}
+
+func (b *mockBackend) AllocateIDs(kind *types.Kind, parent *types.Key, n int) ([]*types.Key, error) {
+	if b.allocate != nil {
+		return b.allocate(kind, parent, n)
+	}
+	return b.backend.AllocateIDs(kind, parent, n)
+}
+
+func (b *mockBackend) AllocateIDRange(kind *types.Kind, parent *types.Key, start, end int64) error {
+	if b.allocateRange != nil {
+		return b.allocateRange(kind, parent, start, end)
+	}
+	return b.backend.AllocateIDRange(kind, parent, start, end)
+}
@@ -0,0 +1,127 @@
+// Package trace provides tracers that open a span for every operation
+// of a store, so request traces show where datastore time goes.
+package trace
+
+import (
+	"sync"
+	"time"
+
+	ae "appengine"
+)
+
+// Tracer opens spans.
+// It must be safe for concurrent use.
+type Tracer interface {
+
+	// StartSpan opens a span with the passed-in name in the context.
+	StartSpan(ctx ae.Context, name string) Span
+}
+
+// Span is a traced operation.
+type Span interface {
+
+	// SetAttribute sets an attribute of the span, e.g. the kind.
+	SetAttribute(key string, value interface{})
+
+	// Finish closes the span with the passed-in error, which may be nil.
+	Finish(err error)
+}
+
+// noop is a Tracer whose spans do nothing.
+type noop struct{}
+
+// Noop is a Tracer whose spans do nothing.
+var Noop Tracer = noop{}
+
+func (noop) StartSpan(ae.Context, string) Span {
+	return noop{}
+}
+
+func (noop) SetAttribute(string, interface{}) {}
+
+func (noop) Finish(error) {}
+
+// RecordedSpan is a span opened by a Recorder.
+type RecordedSpan struct {
+	Name       string
+	Attributes map[string]interface{}
+	Start      time.Time
+
+	// Duration is how long the span was open, once it is finished.
+	Duration time.Duration
+
+	// Finished is whether the span is finished.
+	Finished bool
+
+	// Err is the error the span was finished with.
+	Err error
+}
+
+// Recorder is a Tracer that records its spans in memory, e.g. for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder returns a new Recorder without any spans.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// StartSpan opens and records a span.
+func (r *Recorder) StartSpan(_ ae.Context, name string) Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	span := &RecordedSpan{Name: name, Attributes: make(map[string]interface{}), Start: time.Now()}
+	r.spans = append(r.spans, span)
+	return &recorderSpan{r, span}
+}
+
+// Spans returns copies of the recorded spans, in the order they were opened.
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ret := make([]RecordedSpan, len(r.spans))
+	for i, span := range r.spans {
+		ret[i] = *span
+		ret[i].Attributes = make(map[string]interface{}, len(span.Attributes))
+		for key, value := range span.Attributes {
+			ret[i].Attributes[key] = value
+		}
+	}
+	return ret
+}
+
+// Reset removes all recorded spans.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = nil
+}
+
+type recorderSpan struct {
+	recorder *Recorder
+	span     *RecordedSpan
+}
+
+func (s *recorderSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Attributes[key] = value
+}
+
+func (s *recorderSpan) Finish(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	if s.span.Finished {
+		return
+	}
+	s.span.Duration = time.Since(s.span.Start)
+	s.span.Finished = true
+	s.span.Err = err
+}
@@ -0,0 +1,37 @@
+package trace
+
+import (
+	"fmt"
+
+	. "github.com/101loops/bdd"
+)
+
+var _ = Describe("Tracer", func() {
+
+	It("should record spans", func() {
+		recorder := NewRecorder()
+
+		span := recorder.StartSpan(nil, "my-span")
+		span.SetAttribute("kind", "my-kind")
+		Check(recorder.Spans()[0].Finished, IsFalse)
+
+		span.Finish(fmt.Errorf("an error"))
+		span.Finish(nil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 1)
+		Check(spans[0].Name, Equals, "my-span")
+		Check(spans[0].Attributes, Equals, map[string]interface{}{"kind": "my-kind"})
+		Check(spans[0].Finished, IsTrue)
+		Check(spans[0].Err, ErrorContains, "an error")
+
+		recorder.Reset()
+		Check(recorder.Spans(), HasLen, 0)
+	})
+
+	It("should ignore spans of a no-op tracer", func() {
+		span := Noop.StartSpan(nil, "my-span")
+		span.SetAttribute("kind", "my-kind")
+		span.Finish(nil)
+	})
+})
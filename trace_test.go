@@ -0,0 +1,139 @@
+package hrd
+
+import (
+	"fmt"
+
+	. "github.com/101loops/bdd"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/trace"
+
+	ae "appengine"
+)
+
+var _ = Describe("Tracing", func() {
+
+	var (
+		recorder *trace.Recorder
+		kind     *Kind
+	)
+
+	BeforeEach(func() {
+		recorder = trace.NewRecorder()
+		kind = NewStore(withBackend(myBackend), WithTracer(recorder)).Kind("my-kind")
+	})
+
+	AfterEach(func() {
+		myBackend.get = nil
+		myBackend.put = nil
+		myBackend.delete = nil
+		myBackend.count = nil
+		myBackend.query = nil
+		myBackend.transact = nil
+	})
+
+	It("should trace a load", func() {
+		myBackend.get = func(_ *types.Kind, keys []*types.Key, _ interface{}, _ *types.Opts, _ bool) ([]*types.Key, error) {
+			return keys, nil
+		}
+
+		var entities []*MyModel
+		_, err := kind.Namespace("my-ns").Load(ctx).IDs(1, 2).GetAll(&entities)
+		Check(err, IsNil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 1)
+		Check(spans[0].Name, Equals, "hrd.load")
+		Check(spans[0].Attributes, Equals, map[string]interface{}{"kind": "my-kind", "namespace": "my-ns", "keys": 2})
+		Check(spans[0].Finished, IsTrue)
+	})
+
+	It("should trace a save", func() {
+		myBackend.put = func(_ *types.Kind, _ interface{}, _ *types.Opts) ([]*types.Key, error) {
+			return toInternalKeys(myKind.NewNumKeys(1, 2, 3)), nil
+		}
+
+		_, err := kind.Save(ctx).Entities([]*MyModel{{}, {}, {}})
+		Check(err, IsNil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 1)
+		Check(spans[0].Name, Equals, "hrd.save")
+		Check(spans[0].Attributes["keys"], Equals, 3)
+	})
+
+	It("should trace a failed delete", func() {
+		myBackend.delete = func(_ *types.Kind, _ ...*types.Key) error {
+			return fmt.Errorf("an error")
+		}
+
+		err := kind.Delete(ctx).ID(42)
+		Check(err, NotNil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 1)
+		Check(spans[0].Name, Equals, "hrd.delete")
+		Check(spans[0].Attributes["keys"], Equals, 1)
+		Check(spans[0].Err, ErrorContains, "an error")
+	})
+
+	It("should trace a count", func() {
+		myBackend.count = func(_ *types.Kind, _ *types.Query) (int, error) {
+			return 3, nil
+		}
+
+		_, err := kind.Query(ctx).Filter("a >", 1).GetCount()
+		Check(err, IsNil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 1)
+		Check(spans[0].Name, Equals, "hrd.count")
+		Check(spans[0].Attributes["count"], Equals, 3)
+		Check(spans[0].Attributes["query"], Contains, "FILTER 'a > 1'")
+	})
+
+	It("should trace the path of a query", func() {
+		// without results, the hybrid query does not load any entities
+		myBackend.query = func(_ *types.Kind, _ *types.Query, _ interface{}, _ bool) (types.Iterator, []*types.Key, error) {
+			return nil, nil, nil
+		}
+
+		var entities []*MyModel
+		_, _, err := kind.Query(ctx).GetAll(&entities)
+		Check(err, IsNil)
+
+		_, _, err = kind.Query(ctx).NoGlobalCache().NoLocalCache().GetAll(&entities)
+		Check(err, IsNil)
+
+		_, _, err = kind.Query(ctx).GetKeys()
+		Check(err, IsNil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 4)
+		Check(spans[0].Name, Equals, "hrd.query")
+		Check(spans[0].Attributes["path"], Equals, "hybrid")
+		Check(spans[1].Attributes["path"], Equals, "keys-only") // nested in the hybrid query
+		Check(spans[2].Attributes["path"], Equals, "direct")
+		Check(spans[3].Attributes["path"], Equals, "keys-only")
+	})
+
+	It("should trace a transaction", func() {
+		myBackend.transact = func(ctx ae.Context, _ bool, f func(ae.Context) error) error {
+			return f(ctx)
+		}
+
+		err := kind.store.TX(ctx).XG().Run(func(_ TX) error {
+			return nil
+		})
+		Check(err, IsNil)
+
+		spans := recorder.Spans()
+		Check(spans, HasLen, 1)
+		Check(spans[0].Name, Equals, "hrd.transaction")
+		Check(spans[0].Attributes["xg"], Equals, true)
+	})
+
+	It("should not trace without a tracer", func() {
+		store := NewStore(withBackend(myBackend), WithTracer(nil))
+		Check(store.Kind("my-kind").Delete(ctx).ID(42), IsNil)
+	})
+})
@@ -2,6 +2,7 @@ package hrd
 
 import (
 	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/rpc"
 
 	ae "appengine"
 )
@@ -10,7 +11,9 @@ import (
 // By default it does not handle multiple entity groups.
 type Transactor struct {
 	ctx        ae.Context
-	opts       *types.Opts
+	backend    backend
+	listener   rpc.Listener
+	store      *Store
 	crossGroup bool
 }
 
@@ -21,7 +24,7 @@ type TX interface {
 }
 
 func newTransactor(s *Store, ctx ae.Context) *Transactor {
-	return &Transactor{ctx: ctx, opts: s.opts.Clone()}
+	return &Transactor{ctx: ctx, backend: s.run(), listener: s.listener, store: s}
 }
 
 // XG defines whether the transaction can cross multiple entity groups.
@@ -36,8 +39,18 @@ func (tx *Transactor) XG(enable ...bool) *Transactor {
 }
 
 // Run executes a function in a transaction.
-func (tx *Transactor) Run(f func(_ TX) error) error {
-	return dsTransact(tx.ctx, tx.crossGroup, func(ctx ae.Context) error {
+func (tx *Transactor) Run(f func(_ TX) error) (err error) {
+	span := tx.store.startSpan(tx.ctx, "hrd.transaction")
+	span.SetAttribute("xg", tx.crossGroup)
+	defer func() {
+		span.Finish(err)
+	}()
+
+	ctx := tx.ctx
+	if tx.listener != nil {
+		ctx = types.ListenContext(ctx, tx.listener)
+	}
+	return tx.backend.Transact(ctx, tx.crossGroup, func(ctx ae.Context) error {
 		return f(ctx)
 	})
 }
@@ -3,7 +3,8 @@ package hrd
 import (
 	"fmt"
 	. "github.com/101loops/bdd"
-	"github.com/101loops/hrd/internal"
+	"github.com/101loops/hrd/internal/types"
+	"github.com/101loops/hrd/rpc"
 
 	ae "appengine"
 )
@@ -22,7 +23,8 @@ var _ = Describe("Transactor", func() {
 func dsTransactTests(crossGroup bool) {
 
 	AfterEach(func() {
-		dsTransact = internal.Transact
+		myBackend.get = nil
+		myBackend.transact = nil
 	})
 
 	It("should run a transaction", func() {
@@ -31,7 +33,7 @@ func dsTransactTests(crossGroup bool) {
 		tx := myStore.TX(ctx).XG(crossGroup)
 		Check(tx.crossGroup, Equals, crossGroup)
 
-		dsTransact = func(ctx ae.Context, xg bool, f func(_ ae.Context) error) error {
+		myBackend.transact = func(ctx ae.Context, xg bool, f func(_ ae.Context) error) error {
 			Check(xg, Equals, crossGroup)
 			return f(ctx)
 		}
@@ -42,4 +44,39 @@ func dsTransactTests(crossGroup bool) {
 		})
 		Check(err, ErrorContains, "tx error")
 	})
+
+	It("should run a transaction with a listening context", func() {
+		store := NewStore(withBackend(myBackend), WithListener(rpc.NewCounter()))
+
+		myBackend.transact = func(txCtx ae.Context, _ bool, f func(_ ae.Context) error) error {
+			Check(txCtx, Not(Equals), ctx)
+			Check(types.BaseContext(txCtx), Equals, ctx)
+			return nil
+		}
+
+		err := store.TX(ctx).XG(crossGroup).Run(func(tx TX) error {
+			return nil
+		})
+		Check(err, IsNil)
+	})
+
+	It("should run actions with the options of their kinds", func() {
+		myBackend.transact = func(ctx ae.Context, _ bool, f func(_ ae.Context) error) error {
+			return f(ctx)
+		}
+		myBackend.get = func(_ *types.Kind, keys []*types.Key, _ interface{}, opts *types.Opts, _ bool) ([]*types.Key, error) {
+			Check(opts.NoGlobalCache, IsTrue)
+			Check(opts.NoLocalCache, IsFalse)
+			return keys, nil
+		}
+
+		store := NewStore(withBackend(myBackend))
+		kind := store.Kind("my-kind").NoGlobalCache()
+		err := store.NoLocalCache().TX(ctx).XG(crossGroup).Run(func(tx TX) error {
+			var entity *MyModel
+			_, err := kind.Load(tx).ID(42).GetOne(&entity)
+			return err
+		})
+		Check(err, IsNil)
+	})
 }